@@ -20,11 +20,15 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ghodss/yaml"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
+	"istio.io/pkg/env"
 	"istio.io/pkg/log"
 
 	"istio.io/istio/istioctl/pkg/util/handlers"
@@ -36,18 +40,86 @@ import (
 
 const (
 	jsonOutput    = "json"
+	yamlOutput    = "yaml"
 	summaryOutput = "short"
+	wideOutput    = "wide"
+	// jsonPathOutputPrefix prefixes the template of the JSONPath output format, like -o jsonpath={.[*].name}
+	jsonPathOutputPrefix = "jsonpath="
 )
 
 var (
 	fqdn, direction, subset string
 	port                    int
 
-	address, listenerType string
-
-	routeName string
-
-	clusterName, status string
+	// nameContains selects the listeners, clusters, routes or endpoints with a resource name containing the value
+	nameContains string
+
+	// summaryProxyConfig prints the summary rather than the full dump for the json, yaml and jsonpath output formats
+	summaryProxyConfig bool
+
+	listenerName, listenerFilterName, listenerType, listenerPort   string
+	address, addressRegex, cidr, sni, tlsMode, listenerSortBy      string
+	verboseProxyConfig, listenerChains, expandInbound, skipVirtual bool
+	internalListeners, skipInternal                                bool
+
+	listenerOrigin       string
+	listenerLastUpdated  bool
+	listenerCount        bool
+	listenerConflicts    bool
+	listenerHTTPSettings bool
+	listenerBootstrap    bool
+	listenerColumns      []string
+	listenerUpdatedSince time.Duration
+	listenerLimit        int
+	listenerOffset       int
+	listenerStrict       bool
+
+	routeName              string
+	routeVirtualHostDomain string
+	routeVirtualHosts      bool
+	routeWeights           bool
+	routeTimeouts          bool
+	routeRetries           bool
+	routeMatches           bool
+	routeMethod, routePath string
+	routeIncludeCatchAll   bool
+	routeHeaders           bool
+	routeWithHeaders       bool
+
+	clusterType            string
+	clusterLbPolicy        string
+	clusterDestinationRule string
+	clusterNameOnly        bool
+	clusterLastUpdated     bool
+	clusterSortBy          string
+	clustersFile           string
+	clusterCheckReferences bool
+	clusterUnreferenced    bool
+	clusterPriorities      bool
+	clusterTLS             bool
+	clusterProtocolOptions bool
+	clusterOutlier         bool
+	clusterCircuitBreakers bool
+	clusterHealthChecks    bool
+	clusterDNS             bool
+	fqdnRegex              bool
+	fqdnExact              bool
+
+	nodeMetadata          bool
+	nodeMetadataKeyPrefix string
+
+	clusterName, status  string
+	secretName           string
+	secretExpiringWithin time.Duration
+	secretVerifyChain    bool
+	endpointLocality     string
+
+	extensionConfigName string
+
+	// colorOutput colors the output written to a terminal, see shouldColor
+	colorOutput bool
+
+	noColorEnvVar = env.RegisterStringVar("NO_COLOR", "", "Disables the colors of the proxy-config output when set")
 )
 
 // Level is an enumeration of all supported log levels.
@@ -146,11 +218,16 @@ var (
 )
 
 func setupPodConfigdumpWriter(podName, podNamespace string, out io.Writer) (*configdump.ConfigWriter, error) {
+	return setupPodConfigdumpPathWriter(podName, podNamespace, "config_dump", out)
+}
+
+// setupPodConfigdumpPathWriter reads the config dump of the Envoy of the pod from the admin path, such as
+// config_dump?include_eds for a config dump with the EDS section
+func setupPodConfigdumpPathWriter(podName, podNamespace, path string, out io.Writer) (*configdump.ConfigWriter, error) {
 	kubeClient, err := envoyClientFactory(kubeconfig, configContext)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create k8s client: %v", err)
 	}
-	path := "config_dump"
 	debug, err := kubeClient.EnvoyDo(podName, podNamespace, "GET", path, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute command on %s.%s sidecar: %v", podName, podNamespace, err)
@@ -158,6 +235,42 @@ func setupPodConfigdumpWriter(podName, podNamespace string, out io.Writer) (*con
 	return setupConfigdumpEnvoyConfigWriter(debug, out)
 }
 
+// parseListenerPort sets the port filter from a single port, a comma separated list of ports such as
+// 80,443,15443, or an inclusive range of ports such as 8000-9000
+func parseListenerPort(value string, filter *configdump.ListenerFilter) error {
+	if value == "" {
+		return nil
+	}
+	if i := strings.Index(value, "-"); i >= 0 {
+		min, err := strconv.ParseUint(value[:i], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid port range %q: %v", value, err)
+		}
+		max, err := strconv.ParseUint(value[i+1:], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid port range %q: %v", value, err)
+		}
+		filter.PortRange = &configdump.PortRange{Min: uint32(min), Max: uint32(max)}
+		return nil
+	}
+	if strings.Contains(value, ",") {
+		for _, p := range strings.Split(value, ",") {
+			port, err := strconv.ParseUint(p, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid port list %q: %v", value, err)
+			}
+			filter.Ports = append(filter.Ports, uint32(port))
+		}
+		return nil
+	}
+	port, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid port %q: %v", value, err)
+	}
+	filter.Port = uint32(port)
+	return nil
+}
+
 func setupFileConfigdumpWriter(filename string, out io.Writer) (*configdump.ConfigWriter, error) {
 	file := os.Stdin
 	if filename != "-" {
@@ -179,8 +292,29 @@ func setupFileConfigdumpWriter(filename string, out io.Writer) (*configdump.Conf
 	return setupConfigdumpEnvoyConfigWriter(data, out)
 }
 
+// setupDiffConfigdumpWriter reads a config dump compared by proxy-config diff from the file named by the argument,
+// or else from the Envoy of the pod it names, with the EDS section so the endpoints of EDS clusters are compared
+func setupDiffConfigdumpWriter(arg string, out io.Writer) (*configdump.ConfigWriter, error) {
+	if _, err := os.Stat(arg); err == nil || arg == "-" {
+		return setupFileConfigdumpWriter(arg, out)
+	}
+	podName, ns := handlers.InferPodInfo(arg, handlers.HandleNamespace(namespace, defaultNamespace))
+	return setupPodConfigdumpPathWriter(podName, ns, "config_dump?include_eds", out)
+}
+
+// shouldColor returns true if the output is colored: when --color is set, the output is a terminal and NO_COLOR is
+// not set, so output piped to other tools or files is left as it is
+func shouldColor(out io.Writer) bool {
+	if !colorOutput || noColorEnvVar.Get() != "" {
+		return false
+	}
+	file, ok := out.(*os.File)
+	return ok && isatty.IsTerminal(file.Fd())
+}
+
 func setupConfigdumpEnvoyConfigWriter(debug []byte, out io.Writer) (*configdump.ConfigWriter, error) {
-	cw := &configdump.ConfigWriter{Stdout: out}
+	color := shouldColor(out)
+	cw := &configdump.ConfigWriter{Stdout: out, Color: color, Highlight: color}
 	err := cw.Prime(debug)
 	if err != nil {
 		return nil, err
@@ -237,6 +371,21 @@ func setupPodClustersWriter(podName, podNamespace string, out io.Writer) (*clust
 	return setupClustersEnvoyConfigWriter(debug, out)
 }
 
+// setupEndpointMetadata reads the metadata of the endpoints of the pod from the load assignments of its config dump,
+// including those of the EDS section
+func setupEndpointMetadata(cw *clusters.ConfigWriter, podName, podNamespace string, out io.Writer) error {
+	configWriter, err := setupPodConfigdumpPathWriter(podName, podNamespace, "config_dump?include_eds", out)
+	if err != nil {
+		return err
+	}
+	loadAssignments, err := configWriter.GetClusterLoadAssignments(configdump.ClusterFilter{})
+	if err != nil {
+		return err
+	}
+	cw.SetEndpointMetadata(loadAssignments)
+	return nil
+}
+
 func setupFileClustersWriter(filename string, out io.Writer) (*clusters.ConfigWriter, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -254,6 +403,40 @@ func setupFileClustersWriter(filename string, out io.Writer) (*clusters.ConfigWr
 	return setupClustersEnvoyConfigWriter(data, out)
 }
 
+// setupJSONPathOutput sets up the config writer to print through the template of a jsonpath=<template> output format,
+// returning false for the other output formats
+func setupJSONPathOutput(cw *configdump.ConfigWriter, outputFormat string) bool {
+	if !strings.HasPrefix(outputFormat, jsonPathOutputPrefix) {
+		return false
+	}
+	cw.OutputFormat = configdump.JSONPath
+	cw.JSONPathTemplate = strings.TrimPrefix(outputFormat, jsonPathOutputPrefix)
+	return true
+}
+
+// primePodClusters loads the clusters admin output of the pod into the config writer, so the cluster summary counts
+// the endpoints of EDS clusters
+func primePodClusters(cw *configdump.ConfigWriter, podName, podNamespace string) error {
+	kubeClient, err := envoyClientFactory(kubeconfig, configContext)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	path := "clusters?format=json"
+	debug, err := kubeClient.EnvoyDo(podName, podNamespace, "GET", path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to execute command on Envoy: %v", err)
+	}
+	return cw.PrimeClusters(debug)
+}
+
+func primeFileClusters(cw *configdump.ConfigWriter, filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return cw.PrimeClusters(data)
+}
+
 // TODO(fisherxu): migrate this to config dump when implemented in Envoy
 // Issue to track -> https://github.com/envoyproxy/envoy/issues/3362
 func setupClustersEnvoyConfigWriter(debug []byte, out io.Writer) (*clusters.ConfigWriter, error) {
@@ -274,11 +457,14 @@ func proxyConfig() *cobra.Command {
 		Short: "Retrieve information about proxy configuration from Envoy [kube only]",
 		Long:  `A group of commands used to retrieve information about proxy configuration from the Envoy config dump`,
 		Example: `  # Retrieve information about proxy configuration from an Envoy instance.
-  istioctl proxy-config <clusters|listeners|routes|endpoints|bootstrap> <pod-name[.namespace]>`,
+  istioctl proxy-config <clusters|listeners|routes|endpoints|bootstrap|ecds> <pod-name[.namespace]>`,
 		Aliases: []string{"pc"},
 	}
 
-	configCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|short")
+	configCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short|wide|jsonpath=<template>")
+	configCmd.PersistentFlags().BoolVar(&colorOutput, "color", false,
+		"Color the output written to a terminal, unless NO_COLOR is set: the keys, strings and numbers of json and yaml "+
+			"output, the added and removed lines of diffs and expired certificates")
 
 	clusterConfigCmd := &cobra.Command{
 		Use:   "cluster [<pod-name[.namespace]>]",
@@ -293,9 +479,67 @@ func proxyConfig() *cobra.Command {
   # Retrieve full cluster dump for clusters that are inbound with a FQDN of details.default.svc.cluster.local.
   istioctl proxy-config clusters <pod-name[.namespace]> --fqdn details.default.svc.cluster.local --direction inbound -o json
 
+  # Retrieve cluster summary for the services of the prod namespace.
+  istioctl proxy-config clusters <pod-name[.namespace]> --fqdn '.*\.prod\.svc.*' --fqdn-regex
+
+  # Retrieve cluster summary for the DNS resolved clusters, such as those of ServiceEntries with DNS resolution.
+  istioctl proxy-config clusters <pod-name[.namespace]> --type STRICT_DNS
+
+  # Retrieve cluster summary for the reviews services of all namespaces, leaving out services like reviews-v2.
+  istioctl proxy-config clusters <pod-name[.namespace]> --fqdn reviews --fqdn-exact
+
+  # Retrieve cluster summary for the clusters of the foo service of the bar namespace, whatever their port and subset.
+  istioctl proxy-config clusters <pod-name[.namespace]> --name-contains foo.bar.svc
+
+  # Retrieve cluster summary for the clusters shaped by the DestinationRule reviews of the default namespace.
+  istioctl proxy-config clusters <pod-name[.namespace]> --destination-rule default/reviews --verbose
+
+  # Retrieve cluster summary with how long ago each cluster was updated, to spot clusters stuck warming.
+  istioctl proxy-config clusters <pod-name[.namespace]> --last-updated
+
+  # Check that the clusters the routes and TCP proxies send traffic to exist, listing unused clusters too.
+  istioctl proxy-config clusters <pod-name[.namespace]> --check-references --show-unreferenced
+
+  # Retrieve the endpoint priorities of the reviews clusters, to audit locality failover.
+  istioctl proxy-config clusters <pod-name[.namespace]> --fqdn reviews --priorities
+
+  # Retrieve the upstream TLS settings of the reviews clusters, to debug TLS handshake errors.
+  istioctl proxy-config clusters <pod-name[.namespace]> --fqdn reviews --tls
+
+  # Retrieve the upstream HTTP protocol and connection pool options of the inbound clusters.
+  istioctl proxy-config clusters <pod-name[.namespace]> --direction inbound --protocol-options
+
+  # Retrieve the circuit breaker thresholds and outlier detection of the clusters shaped by a DestinationRule.
+  istioctl proxy-config clusters <pod-name[.namespace]> --destination-rule default/reviews --circuit-breakers
+
+  # Retrieve the outlier detection, active health checks or DNS settings of the clusters.
+  istioctl proxy-config clusters <pod-name[.namespace]> --outlier-detection
+  istioctl proxy-config clusters <pod-name[.namespace]> --health-checks
+  istioctl proxy-config clusters <pod-name[.namespace]> --type STRICT_DNS --dns
+
+  # Retrieve the names of the inbound clusters, one per line.
+  istioctl proxy-config clusters <pod-name[.namespace]> --direction inbound --name-only
+
+  # Retrieve full cluster dump for the clusters with port 9080 as YAML.
+  istioctl proxy-config clusters <pod-name[.namespace]> --port 9080 -o yaml
+
+  # Retrieve cluster summary for clusters with port 9080 as JSON, with the endpoint counts of EDS clusters.
+  istioctl proxy-config clusters <pod-name[.namespace]> --port 9080 --summary -o json
+
+  # Retrieve the connect timeout of each cluster with a JSONPath template, like kubectl -o jsonpath.
+  istioctl proxy-config clusters <pod-name[.namespace]> -o jsonpath='{range .[*]}{.name}{"\t"}{.connectTimeout}{"\n"}{end}'
+
   # Retrieve cluster summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config clusters --file envoy-config.json
+
+  # Retrieve cluster summary without using Kubernetes API, counting the endpoints of EDS clusters
+  ssh <user@hostname> 'curl localhost:15000/clusters?format=json' > envoy-clusters.json
+  istioctl proxy-config clusters --file envoy-config.json --clusters-file envoy-clusters.json
+
+  # Retrieve cluster summary without using Kubernetes API, counting the endpoints of EDS clusters in the config dump
+  ssh <user@hostname> 'curl localhost:15000/config_dump?include_eds' > envoy-config.json
+  istioctl proxy-config clusters --file envoy-config.json
 `,
 		Aliases: []string{"clusters", "c"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -308,25 +552,89 @@ func proxyConfig() *cobra.Command {
 		RunE: func(c *cobra.Command, args []string) error {
 			var configWriter *configdump.ConfigWriter
 			var err error
+			primeEndpoints := (outputFormat == summaryOutput || summaryProxyConfig || clusterPriorities) && !clusterNameOnly
 			if len(args) == 1 {
 				podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
 				configWriter, err = setupPodConfigdumpWriter(podName, ns, c.OutOrStdout())
+				if err == nil && primeEndpoints {
+					// The endpoint counts are best-effort, the summary shows ? for the EDS clusters without them
+					if primeErr := primePodClusters(configWriter, podName, ns); primeErr != nil {
+						fmt.Fprintf(c.ErrOrStderr(), "Warning: unable to count the endpoints of EDS clusters: %v\n", primeErr)
+					}
+				}
 			} else {
 				configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout())
+				if err == nil && clustersFile != "" {
+					err = primeFileClusters(configWriter, clustersFile)
+				}
 			}
 			if err != nil {
 				return err
 			}
 			filter := configdump.ClusterFilter{
-				FQDN:      host.Name(fqdn),
-				Port:      port,
-				Subset:    subset,
-				Direction: model.TrafficDirection(direction),
+				FQDN:            host.Name(fqdn),
+				FQDNRegex:       fqdnRegex,
+				FQDNExact:       fqdnExact,
+				NameContains:    nameContains,
+				Port:            port,
+				Subset:          subset,
+				Direction:       model.TrafficDirection(direction),
+				Type:            clusterType,
+				LbPolicy:        clusterLbPolicy,
+				TLSMode:         tlsMode,
+				DestinationRule: clusterDestinationRule,
+				Verbose:         verboseProxyConfig,
+				LastUpdated:     clusterLastUpdated,
+				SortBy:          clusterSortBy,
+			}
+			if clusterNameOnly {
+				return configWriter.PrintClusterNames(filter)
+			}
+			if clusterCheckReferences {
+				switch outputFormat {
+				case jsonOutput:
+					configWriter.OutputFormat = configdump.JSON
+				case yamlOutput:
+					configWriter.OutputFormat = configdump.YAML
+				}
+				return configWriter.CheckClusterReferences(clusterUnreferenced)
+			}
+			switch {
+			case clusterPriorities:
+				return configWriter.PrintClusterEndpointPriorities(filter)
+			case clusterTLS:
+				return configWriter.PrintClusterTLS(filter)
+			case clusterProtocolOptions:
+				return configWriter.PrintClusterProtocolOptions(filter)
+			case clusterCircuitBreakers:
+				return configWriter.PrintClusterCircuitBreakers(filter)
+			case clusterOutlier:
+				return configWriter.PrintClusterOutlierDetection(filter)
+			case clusterHealthChecks:
+				return configWriter.PrintClusterHealthChecks(filter)
+			case clusterDNS:
+				return configWriter.PrintClusterDNS(filter)
+			}
+			if setupJSONPathOutput(configWriter, outputFormat) {
+				if summaryProxyConfig {
+					return configWriter.PrintClusterSummary(filter)
+				}
+				return configWriter.PrintClusterDump(filter)
 			}
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintClusterSummary(filter)
 			case jsonOutput:
+				if summaryProxyConfig {
+					configWriter.OutputFormat = configdump.JSON
+					return configWriter.PrintClusterSummary(filter)
+				}
+				return configWriter.PrintClusterDump(filter)
+			case yamlOutput:
+				configWriter.OutputFormat = configdump.YAML
+				if summaryProxyConfig {
+					return configWriter.PrintClusterSummary(filter)
+				}
 				return configWriter.PrintClusterDump(filter)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
@@ -334,12 +642,60 @@ func proxyConfig() *cobra.Command {
 		},
 	}
 
-	clusterConfigCmd.PersistentFlags().StringVar(&fqdn, "fqdn", "", "Filter clusters by substring of Service FQDN field")
+	clusterConfigCmd.PersistentFlags().StringVar(&fqdn, "fqdn", "",
+		"Filter clusters by substring of Service FQDN field")
+	clusterConfigCmd.PersistentFlags().BoolVar(&fqdnExact, "fqdn-exact", false,
+		"Match --fqdn against the service host of the cluster exactly or by short name, like reviews or reviews.default")
+	clusterConfigCmd.PersistentFlags().BoolVar(&fqdnRegex, "fqdn-regex", false,
+		"Match --fqdn as a regular expression against the service host of the cluster, such as '.*\\.prod\\.svc.*'")
+	clusterConfigCmd.PersistentFlags().StringVar(&nameContains, "name-contains", "",
+		"Filter clusters by name containing the value, such as a service host")
 	clusterConfigCmd.PersistentFlags().StringVar(&direction, "direction", "", "Filter clusters by Direction field")
-	clusterConfigCmd.PersistentFlags().StringVar(&subset, "subset", "", "Filter clusters by substring of Subset field")
-	clusterConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter clusters by Port field")
+	clusterConfigCmd.PersistentFlags().StringVar(&subset, "subset", "", "Filter clusters by Subset field, such as v1")
+	clusterConfigCmd.PersistentFlags().IntVar(&port, "port", 0,
+		"Filter clusters by Port field, or by endpoint port for clusters not named by Istio such as xds-grpc")
+	clusterConfigCmd.PersistentFlags().StringVar(&clusterSortBy, "sort-by", "fqdn", "Sort clusters by fqdn, port or name")
+	clusterConfigCmd.PersistentFlags().StringVar(&clusterType, "type", "",
+		"Filter clusters by discovery type, such as STRICT_DNS, or by custom cluster type name")
+	clusterConfigCmd.PersistentFlags().StringVar(&clusterType, "cluster-type", "",
+		"Filter clusters by discovery type, such as STRICT_DNS, or by custom cluster type name")
+	_ = clusterConfigCmd.PersistentFlags().MarkHidden("cluster-type")
+	clusterConfigCmd.PersistentFlags().StringVar(&clusterLbPolicy, "lb-policy", "",
+		"Filter clusters by load balancing policy, such as LEAST_REQUEST or RING_HASH")
+	clusterConfigCmd.PersistentFlags().StringVar(&tlsMode, "tls-mode", "",
+		"Filter clusters by upstream TLS mode, such as DISABLE for plaintext clusters or AUTO for auto mTLS clusters")
+	clusterConfigCmd.PersistentFlags().StringVar(&clusterDestinationRule, "destination-rule", "",
+		"Filter clusters by the DestinationRule applied to them, named namespace/name")
+	clusterConfigCmd.PersistentFlags().BoolVar(&verboseProxyConfig, "verbose", false,
+		"Add the DestinationRule applied to each cluster, and its upstream HTTP protocol and idle timeout, to the summary")
+	clusterConfigCmd.PersistentFlags().BoolVar(&summaryProxyConfig, "summary", false,
+		"Output the cluster summary rather than the full cluster dump with -o json, yaml or jsonpath")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterNameOnly, "name-only", false,
+		"Print only the names of the clusters, one per line")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterLastUpdated, "last-updated", false,
+		"Add how long ago each dynamic cluster was last updated to the summary")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterCheckReferences, "check-references", false,
+		"Report the clusters routes and TCP proxies reference that are missing from the config dump, failing if any are found")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterUnreferenced, "show-unreferenced", false,
+		"With --check-references, also report the dynamic clusters no route or TCP proxy references")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterPriorities, "priorities", false,
+		"Output one row per cluster and endpoint priority with its number of localities and endpoints and their total weight")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterTLS, "tls", false,
+		"Output the upstream TLS settings of the clusters: SNI, subject alt names, certificates and client certificate")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterProtocolOptions, "protocol-options", false,
+		"Output the upstream HTTP protocol, max requests per connection and idle timeout of the clusters")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterCircuitBreakers, "circuit-breakers", false,
+		"Output one row per cluster and priority with its circuit breaker thresholds and outlier detection settings")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterOutlier, "outlier-detection", false,
+		"Output the outlier detection settings of the clusters, with the Envoy defaults of the unset ones")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterHealthChecks, "health-checks", false,
+		"Output one row per cluster and active health check, with the healthy panic threshold of the cluster")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterDNS, "dns", false,
+		"Output the DNS lookup family, refresh rate and resolved hostnames of the STRICT_DNS and LOGICAL_DNS clusters")
 	clusterConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"Envoy config dump JSON file")
+		"Envoy config dump JSON file, optionally gzip compressed")
+	clusterConfigCmd.PersistentFlags().StringVar(&clustersFile, "clusters-file", "",
+		"Envoy clusters?format=json output file, to count the endpoints of the clusters of --file")
 
 	listenerConfigCmd := &cobra.Command{
 		Use:   "listener [<pod-name[.namespace]>]",
@@ -351,9 +707,27 @@ func proxyConfig() *cobra.Command {
   # Retrieve listener summary for listeners with port 9080.
   istioctl proxy-config listeners <pod-name[.namespace]> --port 9080
 
+  # Retrieve listener summary for listeners with any of the ports 80, 443 and 15443.
+  istioctl proxy-config listeners <pod-name[.namespace]> --port 80,443,15443
+
+  # Retrieve listener summary for listeners with a port between 15000 and 15100.
+  istioctl proxy-config listeners <pod-name[.namespace]> --port 15000-15100
+
+  # Retrieve listener summary for the first 100 listeners with port 9080.
+  istioctl proxy-config listeners <pod-name[.namespace]> --port 9080 --limit 100
+
   # Retrieve full listener dump for HTTP listeners with a wildcard address (0.0.0.0).
   istioctl proxy-config listeners <pod-name[.namespace]> --type HTTP --address 0.0.0.0 -o json
 
+  # Retrieve listener summary for listeners with port 9080 as JSON, for scripts.
+  istioctl proxy-config listeners <pod-name[.namespace]> --port 9080 --summary -o json
+
+  # Retrieve the listeners with port 9080 as a static bootstrap config to run with a standalone Envoy.
+  istioctl proxy-config listeners <pod-name[.namespace]> --port 9080 --bootstrap > envoy-bootstrap.yaml
+
+  # Retrieve full listener dump, warning about filter configs of unknown or deprecated types.
+  istioctl proxy-config listeners <pod-name[.namespace]> -o json --strict
+
   # Retrieve listener summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config listeners --file envoy-config.json
@@ -367,27 +741,98 @@ func proxyConfig() *cobra.Command {
 			return nil
 		},
 		RunE: func(c *cobra.Command, args []string) error {
+			if c.Flags().Changed("address-regex") && addressRegex == "" {
+				return fmt.Errorf("--address-regex requires a non-empty pattern")
+			}
+			filter := configdump.ListenerFilter{
+				Name:          listenerName,
+				NameContains:  nameContains,
+				Address:       address,
+				AddressRegex:  addressRegex,
+				CIDR:          cidr,
+				Type:          listenerType,
+				Direction:     model.TrafficDirection(direction),
+				SNI:           sni,
+				TLSMode:       tlsMode,
+				FilterName:    listenerFilterName,
+				Verbose:       verboseProxyConfig,
+				Chains:        listenerChains,
+				Origin:        listenerOrigin,
+				LastUpdated:   listenerLastUpdated,
+				UpdatedSince:  listenerUpdatedSince,
+				ExpandInbound: expandInbound,
+				SortBy:        listenerSortBy,
+				Columns:       listenerColumns,
+				SkipVirtual:   skipVirtual,
+				Internal:      internalListeners,
+				SkipInternal:  skipInternal,
+				Limit:         listenerLimit,
+				Offset:        listenerOffset,
+				Strict:        listenerStrict,
+			}
+			if err := parseListenerPort(listenerPort, &filter); err != nil {
+				return err
+			}
 			var configWriter *configdump.ConfigWriter
 			var err error
 			if len(args) == 1 {
 				podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
-				configWriter, err = setupPodConfigdumpWriter(podName, ns, c.OutOrStdout())
+				path := "config_dump"
+				if listenerBootstrap {
+					// The bootstrap makes EDS clusters static with the endpoints of the EDS section
+					path = "config_dump?include_eds"
+				}
+				configWriter, err = setupPodConfigdumpPathWriter(podName, ns, path, c.OutOrStdout())
 			} else {
 				configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout())
 			}
 			if err != nil {
 				return err
 			}
-			filter := configdump.ListenerFilter{
-				Address: address,
-				Port:    uint32(port),
-				Type:    listenerType,
+			configWriter.Stderr = c.ErrOrStderr()
+
+			if listenerBootstrap {
+				switch {
+				case setupJSONPathOutput(configWriter, outputFormat):
+				case outputFormat == jsonOutput:
+					configWriter.OutputFormat = configdump.JSON
+				default:
+					configWriter.OutputFormat = configdump.YAML
+				}
+				return configWriter.PrintListenerBootstrap(filter)
+			}
+			if listenerCount {
+				return configWriter.PrintListenerCount(filter)
+			}
+			if listenerConflicts {
+				return configWriter.CheckListenerConflicts(filter)
+			}
+			if listenerHTTPSettings {
+				return configWriter.PrintListenerHTTPSettings(filter)
+			}
+			if setupJSONPathOutput(configWriter, outputFormat) {
+				if summaryProxyConfig {
+					return configWriter.PrintListenerSummary(filter)
+				}
+				return configWriter.PrintListenerDump(filter)
 			}
-
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintListenerSummary(filter)
+			case wideOutput:
+				filter.Wide = true
+				return configWriter.PrintListenerSummary(filter)
 			case jsonOutput:
+				if summaryProxyConfig {
+					configWriter.OutputFormat = configdump.JSON
+					return configWriter.PrintListenerSummary(filter)
+				}
+				return configWriter.PrintListenerDump(filter)
+			case yamlOutput:
+				configWriter.OutputFormat = configdump.YAML
+				if summaryProxyConfig {
+					return configWriter.PrintListenerSummary(filter)
+				}
 				return configWriter.PrintListenerDump(filter)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
@@ -395,11 +840,59 @@ func proxyConfig() *cobra.Command {
 		},
 	}
 
-	listenerConfigCmd.PersistentFlags().StringVar(&address, "address", "", "Filter listeners by address field")
-	listenerConfigCmd.PersistentFlags().StringVar(&listenerType, "type", "", "Filter listeners by type field")
-	listenerConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter listeners by Port field")
+	listenerConfigCmd.PersistentFlags().StringVar(&listenerName, "name", "",
+		"Filter listeners by name field, prefix with ~ to match a regular expression")
+	listenerConfigCmd.PersistentFlags().StringVar(&nameContains, "name-contains", "",
+		"Filter listeners by name containing the value, such as a service host")
+	listenerConfigCmd.PersistentFlags().StringVar(&address, "address", "", "Filter listeners by address field or pipe path, or by address range in CIDR notation")
+	listenerConfigCmd.PersistentFlags().StringVar(&addressRegex, "address-regex", "", "Filter listeners by address matching a regular expression")
+	listenerConfigCmd.PersistentFlags().StringVar(&cidr, "cidr", "", "Filter listeners by socket address inside the CIDR prefix")
+	listenerConfigCmd.PersistentFlags().StringVar(&listenerType, "type", "", "Filter listeners by type field, such as HTTP, TCP or INTERNAL")
+	listenerConfigCmd.PersistentFlags().StringVar(&listenerPort, "port", "",
+		"Filter listeners by Port field, a list of ports such as 80,443,15443 or a range of ports such as 8000-9000")
+	listenerConfigCmd.PersistentFlags().StringVar(&direction, "direction", "", "Filter listeners by Direction field")
+	listenerConfigCmd.PersistentFlags().StringVar(&sni, "sni", "", "Filter listeners by filter chain server name, wildcards are supported")
+	listenerConfigCmd.PersistentFlags().StringVar(&tlsMode, "tls-mode", "",
+		"Filter listeners by the TLS mode of their filter chains: mTLS, TLS, PERMISSIVE or DISABLE")
+	listenerConfigCmd.PersistentFlags().StringVar(&listenerFilterName, "filter-name", "",
+		"Filter listeners by the name of a network or HTTP filter they contain")
+	listenerConfigCmd.PersistentFlags().BoolVar(&verboseProxyConfig, "verbose", false,
+		"Output one row per filter chain with listener filters, match criteria, destination, access logs, security policies and the Istio config that generated it")
+	listenerConfigCmd.PersistentFlags().BoolVar(&summaryProxyConfig, "summary", false,
+		"Output the listener summary rather than the full listener dump with -o json, yaml or jsonpath")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerChains, "chains", false, "Add the number of filter chains of each listener to the summary")
+	listenerConfigCmd.PersistentFlags().StringVar(&listenerOrigin, "origin", "",
+		"Filter listeners by origin: static for bootstrap listeners, or dynamic for listeners received over LDS")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerLastUpdated, "last-updated", false,
+		"Add how long ago each dynamic listener was last updated to the summary")
+	listenerConfigCmd.PersistentFlags().DurationVar(&listenerUpdatedSince, "updated-since", 0,
+		"Filter dynamic listeners by last update within the duration, such as 10m")
+	listenerConfigCmd.PersistentFlags().StringSliceVar(&listenerColumns, "columns", nil,
+		"Comma separated summary columns to output, such as NAME,PORT,DESTINATION")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerCount, "count", false,
+		"Output only the number of listeners matching the filters")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerConflicts, "check-conflicts", false,
+		"Report filter chains whose match criteria duplicate or overlap those of another chain as specifically, failing if any are found")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerHTTPSettings, "http-settings", false,
+		"Output the X-Forwarded-For, path normalization and timeout settings of each HTTP filter chain")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerBootstrap, "bootstrap", false,
+		"Output the listeners with the routes, clusters, endpoints and secrets they reference as a static Envoy bootstrap config, in yaml unless -o json")
+	listenerConfigCmd.PersistentFlags().IntVar(&listenerLimit, "limit", 0,
+		"Output at most this number of listeners after filtering and sorting, 0 for all")
+	listenerConfigCmd.PersistentFlags().IntVar(&listenerOffset, "offset", 0,
+		"Skip this number of listeners after filtering and sorting")
+	listenerConfigCmd.PersistentFlags().BoolVar(&listenerStrict, "strict", false,
+		"Warn about the typed configs of unknown or deprecated types in the listener dump, failing if any are found")
+	listenerConfigCmd.PersistentFlags().BoolVar(&expandInbound, "expand-inbound", false,
+		"Summarize each filter chain of the virtual inbound listener on its own row")
+	listenerConfigCmd.PersistentFlags().StringVar(&listenerSortBy, "sort-by", "port", "Sort listeners by port, address or name")
+	listenerConfigCmd.PersistentFlags().BoolVar(&skipVirtual, "skip-virtual", false,
+		"Skip the traffic capture, Prometheus and health check listeners added to every proxy")
+	listenerConfigCmd.PersistentFlags().BoolVar(&internalListeners, "internal", false,
+		"Filter listeners by being internal listeners, which tunneling proxies address by name")
+	listenerConfigCmd.PersistentFlags().BoolVar(&skipInternal, "skip-internal", false, "Skip internal listeners")
 	listenerConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"Envoy config dump JSON file")
+		"Envoy config dump JSON file, optionally gzip compressed")
 
 	logCmd := &cobra.Command{
 		Use:   "log <pod-name[.namespace]>",
@@ -523,6 +1016,27 @@ func proxyConfig() *cobra.Command {
   # Retrieve full route dump for route 9080
   istioctl proxy-config route <pod-name[.namespace]> --name 9080 -o json
 
+  # Retrieve full route dump for route 9080 as YAML
+  istioctl proxy-config route <pod-name[.namespace]> --name 9080 -o yaml
+
+  # Retrieve route summary as JSON, for scripts.
+  istioctl proxy-config route <pod-name[.namespace]> --summary -o json
+
+  # Retrieve the virtual hosts of route 80 serving bookinfo.example.com.
+  istioctl proxy-config route <pod-name[.namespace]> --name 80 --vhosts --vhost-domain bookinfo.example.com
+
+  # Retrieve the traffic split of the routes of route 80.
+  istioctl proxy-config route <pod-name[.namespace]> --name 80 --weights
+
+  # Retrieve the timeouts and retry policies of the routes that retry failed requests.
+  istioctl proxy-config route <pod-name[.namespace]> --timeouts --retries
+
+  # Retrieve the routes that may serve POST requests to /api/v1.
+  istioctl proxy-config route <pod-name[.namespace]> --matches --method POST --path /api/v1
+
+  # Retrieve the headers the routes of route 80 add or remove, leaving out routes that keep headers untouched.
+  istioctl proxy-config route <pod-name[.namespace]> --name 80 --headers --with-headers
+
   # Retrieve route summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config routes --file envoy-config.json
@@ -548,12 +1062,50 @@ func proxyConfig() *cobra.Command {
 				return err
 			}
 			filter := configdump.RouteFilter{
-				Name: routeName,
+				Name:               routeName,
+				NameContains:       nameContains,
+				VirtualHostDomain:  routeVirtualHostDomain,
+				Retries:            routeRetries,
+				Method:             routeMethod,
+				Path:               routePath,
+				IncludeCatchAll:    routeIncludeCatchAll,
+				ManipulatesHeaders: routeWithHeaders,
+			}
+			if setupJSONPathOutput(configWriter, outputFormat) {
+				if summaryProxyConfig {
+					return configWriter.PrintRouteSummary(filter)
+				}
+				return configWriter.PrintRouteDump(filter)
 			}
 			switch outputFormat {
 			case summaryOutput:
+				if routeVirtualHosts {
+					return configWriter.PrintRouteVirtualHosts(filter)
+				}
+				if routeWeights {
+					return configWriter.PrintRouteWeightedClusters(filter)
+				}
+				if routeTimeouts {
+					return configWriter.PrintRouteTimeouts(filter)
+				}
+				if routeMatches {
+					return configWriter.PrintRouteMatches(filter)
+				}
+				if routeHeaders {
+					return configWriter.PrintRouteHeaders(filter)
+				}
 				return configWriter.PrintRouteSummary(filter)
 			case jsonOutput:
+				if summaryProxyConfig {
+					configWriter.OutputFormat = configdump.JSON
+					return configWriter.PrintRouteSummary(filter)
+				}
+				return configWriter.PrintRouteDump(filter)
+			case yamlOutput:
+				configWriter.OutputFormat = configdump.YAML
+				if summaryProxyConfig {
+					return configWriter.PrintRouteSummary(filter)
+				}
 				return configWriter.PrintRouteDump(filter)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
@@ -562,8 +1114,31 @@ func proxyConfig() *cobra.Command {
 	}
 
 	routeConfigCmd.PersistentFlags().StringVar(&routeName, "name", "", "Filter listeners by route name field")
+	routeConfigCmd.PersistentFlags().StringVar(&nameContains, "name-contains", "",
+		"Filter routes by route config name containing the value, such as a service host")
+	routeConfigCmd.PersistentFlags().BoolVar(&summaryProxyConfig, "summary", false,
+		"Output the route summary rather than the full route dump with -o json, yaml or jsonpath")
+	routeConfigCmd.PersistentFlags().StringVar(&routeVirtualHostDomain, "vhost-domain", "",
+		"Filter routes by the domain of their virtual hosts, wildcards are supported")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeVirtualHosts, "vhosts", false,
+		"Output one row per virtual host with its domains and number of routes")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeWeights, "weights", false,
+		"Output one row per route cluster with its weight and percentage of the route traffic")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeTimeouts, "timeouts", false,
+		"Output one row per route with its effective timeout and retry policy")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeRetries, "retries", false, "Filter routes by retries being enabled")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeMatches, "matches", false, "Output one row per route with its path match and methods")
+	routeConfigCmd.PersistentFlags().StringVar(&routeMethod, "method", "", "Filter routes by the HTTP method they match, such as POST")
+	routeConfigCmd.PersistentFlags().StringVar(&routePath, "path", "",
+		"Filter routes by the request path their prefix, exact path or regex match, such as /api/v1")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeIncludeCatchAll, "include-catch-all", false,
+		"Include the routes matching every path when filtering by --path")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeHeaders, "headers", false,
+		"Output one row per request or response header each route adds or removes")
+	routeConfigCmd.PersistentFlags().BoolVar(&routeWithHeaders, "with-headers", false,
+		"Filter routes by adding or removing request or response headers")
 	routeConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"Envoy config dump JSON file")
+		"Envoy config dump JSON file, optionally gzip compressed")
 
 	endpointConfigCmd := &cobra.Command{
 		Use:   "endpoint [<pod-name[.namespace]>]",
@@ -583,6 +1158,17 @@ func proxyConfig() *cobra.Command {
   # Retrieve full endpoint with the status (healthy).
   istioctl proxy-config endpoint <pod-name[.namespace]> --status healthy -ojson
 
+  # Retrieve endpoint summary for the endpoints of cluster outbound|9080||reviews.default.svc.cluster.local
+  # that do not receive traffic.
+  istioctl proxy-config endpoint <pod-name[.namespace]> --cluster "outbound|9080||reviews.default.svc.cluster.local" --status unhealthy
+
+  # Retrieve endpoint summary for the endpoints in zone us-west1-a of region us-west1.
+  istioctl proxy-config endpoint <pod-name[.namespace]> --locality region=us-west1,zone=us-west1-a
+
+  # Retrieve endpoint summary with the tls mode and Istio metadata labels of the endpoints, read from the config dump
+  # of the pod as they are not in the clusters output, so they are shown as ? with --file.
+  istioctl proxy-config endpoint <pod-name[.namespace]> -o wide
+
   # Retrieve endpoint summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/clusters?format=json' > envoy-clusters.json
   istioctl proxy-config endpoints --file envoy-clusters.json
@@ -601,6 +1187,10 @@ func proxyConfig() *cobra.Command {
 			if len(args) == 1 {
 				podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
 				configWriter, err = setupPodClustersWriter(podName, ns, c.OutOrStdout())
+				if err == nil && outputFormat == wideOutput {
+					// The clusters output has no endpoint metadata, which is read from the config dump instead
+					err = setupEndpointMetadata(configWriter, podName, ns, c.OutOrStdout())
+				}
 			} else {
 				configWriter, err = setupFileClustersWriter(configDumpFile, c.OutOrStdout())
 			}
@@ -609,15 +1199,20 @@ func proxyConfig() *cobra.Command {
 			}
 
 			filter := clusters.EndpointFilter{
-				Address: address,
-				Port:    uint32(port),
-				Cluster: clusterName,
-				Status:  status,
+				Address:      address,
+				Port:         uint32(port),
+				Cluster:      clusterName,
+				NameContains: nameContains,
+				Status:       status,
+				Locality:     endpointLocality,
 			}
 
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintEndpointsSummary(filter)
+			case wideOutput:
+				filter.Wide = true
+				return configWriter.PrintEndpointsSummary(filter)
 			case jsonOutput:
 				return configWriter.PrintEndpoints(filter)
 			default:
@@ -629,7 +1224,12 @@ func proxyConfig() *cobra.Command {
 	endpointConfigCmd.PersistentFlags().StringVar(&address, "address", "", "Filter endpoints by address field")
 	endpointConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter endpoints by Port field")
 	endpointConfigCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Filter endpoints by cluster name field")
-	endpointConfigCmd.PersistentFlags().StringVar(&status, "status", "", "Filter endpoints by status field")
+	endpointConfigCmd.PersistentFlags().StringVar(&nameContains, "name-contains", "",
+		"Filter endpoints by cluster name containing the value, such as a service host")
+	endpointConfigCmd.PersistentFlags().StringVar(&status, "status", "",
+		"Filter endpoints by status field, or by unhealthy for all endpoints that are unhealthy, draining, timed out or failed outlier detection")
+	endpointConfigCmd.PersistentFlags().StringVar(&endpointLocality, "locality", "",
+		"Filter endpoints by locality, a comma separated list of region, zone and subzone such as region=us-west1,zone=us-west1-a")
 	endpointConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -640,6 +1240,15 @@ func proxyConfig() *cobra.Command {
 		Example: `  # Retrieve full bootstrap configuration for a given pod from Envoy.
   istioctl proxy-config bootstrap <pod-name[.namespace]>
 
+  # Retrieve full bootstrap configuration for a given pod from Envoy as YAML.
+  istioctl proxy-config bootstrap <pod-name[.namespace]> -o yaml
+
+  # Retrieve summary about bootstrap configuration for a given pod from Envoy.
+  istioctl proxy-config bootstrap <pod-name[.namespace]> --summary
+
+  # Retrieve the Istio metadata the proxy registered with, flattened into key: value rows.
+  istioctl proxy-config bootstrap <pod-name[.namespace]> --node-metadata --key-prefix ISTIO_
+
   # Retrieve full bootstrap without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config bootstrap --file envoy-config.json
@@ -664,12 +1273,35 @@ func proxyConfig() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return configWriter.PrintBootstrapDump()
+			if nodeMetadata {
+				return configWriter.PrintNodeMetadata(configdump.NodeMetadataFilter{KeyPrefix: nodeMetadataKeyPrefix})
+			}
+			if summaryProxyConfig {
+				return configWriter.PrintBootstrapSummary()
+			}
+			if setupJSONPathOutput(configWriter, outputFormat) {
+				return configWriter.PrintBootstrapDump()
+			}
+			switch outputFormat {
+			case summaryOutput, jsonOutput:
+				return configWriter.PrintBootstrapDump()
+			case yamlOutput:
+				configWriter.OutputFormat = configdump.YAML
+				return configWriter.PrintBootstrapDump()
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
 		},
 	}
 
+	bootstrapConfigCmd.PersistentFlags().BoolVar(&summaryProxyConfig, "summary", false,
+		"Print the node, its Istio version and metadata, the xDS server, the stats sinks and the admin address rather than the full bootstrap")
+	bootstrapConfigCmd.PersistentFlags().BoolVar(&nodeMetadata, "node-metadata", false,
+		"Print the node metadata as key: value rows, with the keys of nested objects dotted")
+	bootstrapConfigCmd.PersistentFlags().StringVar(&nodeMetadataKeyPrefix, "key-prefix", "",
+		"Filter the node metadata by key prefix, such as ISTIO_ or PROXY_CONFIG.")
 	bootstrapConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"Envoy config dump JSON file")
+		"Envoy config dump JSON file, optionally gzip compressed")
 
 	secretConfigCmd := &cobra.Command{
 		Use:   "secret [<pod-name[.namespace]>]",
@@ -678,6 +1310,15 @@ func proxyConfig() *cobra.Command {
 		Example: `  # Retrieve full secret configuration for a given pod from Envoy.
   istioctl proxy-config secret <pod-name[.namespace]>
 
+  # Retrieve the root certificate secret, with its private key material redacted.
+  istioctl proxy-config secret <pod-name[.namespace]> --name ROOTCA -o json
+
+  # Retrieve the secrets whose certificates have expired or expire within a day.
+  istioctl proxy-config secret <pod-name[.namespace]> --expiring-within 24h
+
+  # Retrieve the secrets with whether their certificate chains verify against the root certificates of the proxy.
+  istioctl proxy-config secret <pod-name[.namespace]> --verify-chain
+
   # Retrieve full bootstrap without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config secret --file envoy-config.json
@@ -704,22 +1345,179 @@ THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
 			if err != nil {
 				return err
 			}
+			filter := configdump.SecretFilter{
+				Name:           secretName,
+				ExpiringWithin: secretExpiringWithin,
+				VerifyChain:    secretVerifyChain,
+			}
+			if setupJSONPathOutput(configWriter, outputFormat) {
+				return configWriter.PrintSecretDump(filter)
+			}
 			switch outputFormat {
 			case summaryOutput:
-				return configWriter.PrintSecretSummary()
+				return configWriter.PrintSecretSummary(filter)
 			case jsonOutput:
-				return configWriter.PrintSecretDump()
+				return configWriter.PrintSecretDump(filter)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
 			}
 		},
 	}
 
+	secretConfigCmd.PersistentFlags().StringVar(&secretName, "name", "", "Filter secrets by resource name, such as default or ROOTCA")
+	secretConfigCmd.PersistentFlags().DurationVar(&secretExpiringWithin, "expiring-within", 0,
+		"Filter secrets by their certificate having expired or expiring within the duration, such as 24h")
+	secretConfigCmd.PersistentFlags().BoolVar(&secretVerifyChain, "verify-chain", false,
+		"Verify the certificate chain of each secret against the root certificates of the SDS secrets and inline TLS contexts")
 	secretConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"Envoy config dump JSON file")
+		"Envoy config dump JSON file, optionally gzip compressed")
+
+	extensionConfigCmd := &cobra.Command{
+		Use:   "ecds [<pod-name[.namespace]>]",
+		Short: "Retrieves the ECDS extension configuration for the Envoy in the specified pod",
+		Long: `Retrieve information about the extension configuration, such as Wasm and ext_authz filters, the Envoy instance ` +
+			`in the specified pod received over the extension config discovery service (ECDS).`,
+		Example: `  # Retrieve summary about the ECDS extension configuration for a given pod from Envoy.
+  istioctl proxy-config ecds <pod-name[.namespace]>
+
+  # Retrieve the full typed config of a Wasm filter.
+  istioctl proxy-config ecds <pod-name[.namespace]> --name default.stats-filter -o json
+
+  # Retrieve the ECDS extension configuration summary without using Kubernetes API
+  ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
+  istioctl proxy-config ecds --file envoy-config.json
+`,
+		Aliases: []string{"extension"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) != (configDumpFile == "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("ecds requires pod name or --file parameter")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var configWriter *configdump.ConfigWriter
+			var err error
+			if len(args) == 1 {
+				podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+				configWriter, err = setupPodConfigdumpWriter(podName, ns, c.OutOrStdout())
+			} else {
+				configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout())
+			}
+			if err != nil {
+				return err
+			}
+			filter := configdump.ExtensionConfigFilter{Name: extensionConfigName}
+			if setupJSONPathOutput(configWriter, outputFormat) {
+				return configWriter.PrintExtensionConfigDump(filter)
+			}
+			switch outputFormat {
+			case summaryOutput:
+				return configWriter.PrintExtensionConfigSummary(filter)
+			case jsonOutput:
+				return configWriter.PrintExtensionConfigDump(filter)
+			case yamlOutput:
+				configWriter.OutputFormat = configdump.YAML
+				return configWriter.PrintExtensionConfigDump(filter)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+		},
+	}
+
+	extensionConfigCmd.PersistentFlags().StringVar(&extensionConfigName, "name", "",
+		"Filter extension configs by name, such as default.stats-filter")
+	extensionConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
+		"Envoy config dump JSON file, optionally gzip compressed")
+
+	diffConfigCmd := &cobra.Command{
+		Use:   "diff <before-file|pod-name[.namespace]> <after-file|pod-name[.namespace]>",
+		Short: "Compares two Envoy config dumps",
+		Long: `Compare the listeners, clusters, routes and endpoints of two Envoy config dumps, such as dumps taken before ` +
+			`and after an upgrade, printing the resources added, removed or changed by name along with a unified diff ` +
+			`of the JSON of the changed resources. Each config dump is read from the file named by its argument, ` +
+			`or else retrieved from the Envoy of the pod it names. With --fqdn only the matching clusters are compared, ` +
+			`and with the listener filters only the matching listeners.`,
+		Example: `  # Compare the Envoy configuration of a pod before and after an upgrade.
+  kubectl exec <pod-name> -c istio-proxy -- curl -s 'localhost:15000/config_dump?include_eds' > before.json
+  # ... upgrade ...
+  kubectl exec <pod-name> -c istio-proxy -- curl -s 'localhost:15000/config_dump?include_eds' > after.json
+  istioctl proxy-config diff before.json after.json
+
+  # Compare a config dump taken before an upgrade with the current configuration of the pod.
+  istioctl proxy-config diff before.json <pod-name[.namespace]>
+
+  # Compare the clusters of the reviews services of two config dumps.
+  istioctl proxy-config diff before.json after.json --fqdn reviews
+
+  # Compare the clusters of the services of the prod namespace of two config dumps.
+  istioctl proxy-config diff before.json after.json --fqdn '.*\.prod\.svc.*' --fqdn-regex
+
+  # Compare the listeners with port 9080 of two config dumps.
+  istioctl proxy-config diff before.json after.json --port 9080
+
+  # Compare two config dumps, highlighting added and removed lines.
+  istioctl proxy-config diff before.json after.json --color
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			listenerFilter := configdump.ListenerFilter{
+				Name:    listenerName,
+				Address: address,
+				Type:    listenerType,
+			}
+			if err := parseListenerPort(listenerPort, &listenerFilter); err != nil {
+				return err
+			}
+			diffListeners := listenerName != "" || address != "" || listenerType != "" || listenerPort != ""
+			if fqdn != "" && diffListeners {
+				return fmt.Errorf("--fqdn compares clusters and cannot be combined with the listener filters")
+			}
+			before, err := setupDiffConfigdumpWriter(args[0], c.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			after, err := setupDiffConfigdumpWriter(args[1], c.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			if !setupJSONPathOutput(before, outputFormat) {
+				switch outputFormat {
+				case summaryOutput:
+				case jsonOutput:
+					before.OutputFormat = configdump.JSON
+				case yamlOutput:
+					before.OutputFormat = configdump.YAML
+				default:
+					return fmt.Errorf("output format %q not supported", outputFormat)
+				}
+			}
+			switch {
+			case fqdn != "":
+				return before.DiffClusters(after, configdump.ClusterFilter{FQDN: host.Name(fqdn), FQDNRegex: fqdnRegex})
+			case diffListeners:
+				return before.DiffListeners(after, listenerFilter)
+			default:
+				return before.Diff(after)
+			}
+		},
+	}
 
-	configCmd.AddCommand(
-		clusterConfigCmd, listenerConfigCmd, logCmd, routeConfigCmd, bootstrapConfigCmd, endpointConfigCmd, secretConfigCmd)
+	diffConfigCmd.PersistentFlags().StringVar(&fqdn, "fqdn", "",
+		"Compare only the clusters matching the substring of Service FQDN field")
+	diffConfigCmd.PersistentFlags().BoolVar(&fqdnRegex, "fqdn-regex", false,
+		"Match --fqdn as a regular expression against the service host of the cluster, such as '.*\\.prod\\.svc.*'")
+	diffConfigCmd.PersistentFlags().StringVar(&listenerName, "name", "",
+		"Compare only the listeners matching the name field, prefix with ~ to match a regular expression")
+	diffConfigCmd.PersistentFlags().StringVar(&address, "address", "",
+		"Compare only the listeners matching the address field or pipe path, or the address range in CIDR notation")
+	diffConfigCmd.PersistentFlags().StringVar(&listenerType, "type", "",
+		"Compare only the listeners matching the type field, such as HTTP, TCP or INTERNAL")
+	diffConfigCmd.PersistentFlags().StringVar(&listenerPort, "port", "",
+		"Compare only the listeners matching the Port field, a list of ports such as 80,443,15443 or a range of ports such as 8000-9000")
+
+	configCmd.AddCommand(clusterConfigCmd, listenerConfigCmd, logCmd, routeConfigCmd, bootstrapConfigCmd, endpointConfigCmd,
+		secretConfigCmd, extensionConfigCmd, diffConfigCmd)
 
 	return configCmd
 }
@@ -50,6 +50,9 @@ func TestProxyConfig(t *testing.T) {
 	loggingConfig := map[string][]byte{
 		"details-v1-5b7f94f9bc-wp5tb": util.ReadFile("../pkg/writer/envoy/logging/testdata/logging.txt", t),
 	}
+	listenersConfig := map[string][]byte{
+		"details-v1-5b7f94f9bc-wp5tb": util.ReadFile("../pkg/writer/envoy/configdump/testdata/listeners.json", t),
+	}
 	cases := []execTestCase{
 		{
 			args:           strings.Split("proxy-config", " "),
@@ -69,6 +72,113 @@ func TestProxyConfig(t *testing.T) {
 			expectedString: "unable to retrieve Pod: pods \"invalid\" not found",
 			wantException:  true, // "istioctl proxy-config listeners invalid" should fail
 		},
+		{ // listeners by port range
+			args: strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 8000-9000", " "),
+			expectedOutput: "ADDRESS      PORT     TYPE         DIRECTION     STATE      DESTINATION\n" +
+				"0.0.0.0      8080     HTTP         outbound      ACTIVE     8080\n" +
+				"10.0.0.2     8443     HTTP+TCP     outbound      ACTIVE     outbound|8443||web.default.svc.cluster.local,web.default....\n",
+		},
+		{ // listeners by port list
+			args: strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 3306,9091", " "),
+			expectedOutput: "ADDRESS      PORT     TYPE     DIRECTION     STATE      DESTINATION\n" +
+				"10.0.0.1     3306     TCP      outbound      ACTIVE     outbound|3306||mysql.default.svc.cluster.local\n" +
+				"0.0.0.0      9091     TCP      -             ACTIVE     acme\n",
+		},
+		{ // clusters summary as JSON
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --port 9080 --summary -o json", " "),
+			expectedString: `"serviceFqdn": "reviews.default.svc.cluster.local"`,
+		},
+		{ // clusters endpoint priorities
+			args: strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --priorities", " "),
+			expectedOutput: "NAME                              PRIORITY     LOCALITIES     ENDPOINTS     WEIGHT\n" +
+				"outbound|443||api.example.com     0            1              1             1\n",
+		},
+		{ // clusters tls
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --tls", " "),
+			expectedString: "SUBJECT ALT NAMES",
+		},
+		{ // clusters protocol options
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --protocol-options", " "),
+			expectedString: "MAX REQUESTS PER CONNECTION",
+		},
+		{ // clusters circuit breakers
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --circuit-breakers", " "),
+			expectedString: "MAX PENDING REQUESTS",
+		},
+		{ // clusters outlier detection
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --outlier-detection", " "),
+			expectedString: "OUTLIER DETECTION",
+		},
+		{ // clusters health checks
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --health-checks", " "),
+			expectedString: "UNHEALTHY THRESHOLD",
+		},
+		{ // clusters dns
+			args:           strings.Split("proxy-config clusters -f ../pkg/writer/envoy/configdump/testdata/clusters.json --dns", " "),
+			expectedString: "LOOKUP FAMILY",
+		},
+		{ // listeners count
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --count --type HTTP", " "),
+			expectedOutput: "3\n",
+		},
+		{ // listeners conflicts
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --check-conflicts", " "),
+			expectedOutput: "No listener filter chain conflicts found\n",
+		},
+		{ // listeners columns
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 3306 --columns port,name", " "),
+			expectedOutput: "PORT     NAME\n3306     10.0.0.1_3306\n",
+		},
+		{ // listeners summary as JSON
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 3306 --summary -o json", " "),
+			expectedString: `"destination": "outbound|3306||mysql.default.svc.cluster.local"`,
+		},
+		{ // listeners summary as YAML
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 3306 --summary -o yaml", " "),
+			expectedString: "destination: outbound|3306||mysql.default.svc.cluster.local",
+		},
+		{ // listeners unknown column
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --columns port,cluster", " "),
+			expectedString: "unknown listener summary column",
+			wantException:  true,
+		},
+		{ // listeners port list invalid
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 3306,", " "),
+			expectedString: "invalid port list",
+			wantException:  true,
+		},
+		{ // listeners port range invalid
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 8000-", " "),
+			expectedString: "invalid port range",
+			wantException:  true,
+		},
+		{ // listeners address pattern empty
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --address-regex=", " "),
+			expectedString: "--address-regex requires a non-empty pattern",
+			wantException:  true,
+		},
+		{ // bootstrap of a TCP listener whose cluster is missing from the config dump
+			args:           strings.Split("proxy-config listeners -f ../pkg/writer/envoy/configdump/testdata/listeners.json --port 3306 --bootstrap", " "),
+			expectedString: "listeners reference clusters missing from the config dump",
+			wantException:  true,
+		},
+		{ // diff listeners of a file and a pod
+			execClientConfig: listenersConfig,
+			args: strings.Split("proxy-config diff ../pkg/writer/envoy/configdump/testdata/listeners.json "+
+				"details-v1-5b7f94f9bc-wp5tb --port 3306", " "),
+			expectedOutput: "No listener differences\n",
+		},
+		{ // diff clusters of two files
+			args: strings.Split("proxy-config diff ../pkg/writer/envoy/configdump/testdata/clusters.json "+
+				"../pkg/writer/envoy/configdump/testdata/clusters.json --fqdn reviews", " "),
+			expectedOutput: "No cluster differences\n",
+		},
+		{ // diff cluster and listener filters
+			args: strings.Split("proxy-config diff ../pkg/writer/envoy/configdump/testdata/listeners.json "+
+				"../pkg/writer/envoy/configdump/testdata/clusters.json --fqdn reviews --port 3306", " "),
+			expectedString: "--fqdn compares clusters and cannot be combined with the listener filters",
+			wantException:  true,
+		},
 		{ // logging invalid
 			args:           strings.Split("proxy-config log invalid", " "),
 			expectedString: "unable to retrieve Pod: pods \"invalid\" not found",
@@ -53,6 +53,9 @@ type SecretMeta struct {
 	NotAfter     string `json:"not_after"`
 	NotBefore    string `json:"not_before"`
 	Type         string `json:"type"`
+	// Subject and SANs identify the leaf certificate, the first of the chain
+	Subject string   `json:"subject,omitempty"`
+	SANs    []string `json:"sans,omitempty"`
 }
 
 // NewSecretItemBuilder returns a new builder to create a secret item
@@ -224,5 +227,21 @@ func secretMetaFromCert(rawCert []byte) (SecretMeta, error) {
 		NotAfter:     cert.NotAfter.Format(time.RFC3339),
 		NotBefore:    cert.NotBefore.Format(time.RFC3339),
 		Type:         certType,
+		Subject:      cert.Subject.String(),
+		SANs:         certSANs(cert),
 	}, nil
 }
+
+// certSANs returns the URI, DNS, IP and email subject alternative names of a certificate, such as the SPIFFE
+// identity of Istio workload certificates
+func certSANs(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.URIs)+len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.EmailAddresses))
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return append(sans, cert.EmailAddresses...)
+}
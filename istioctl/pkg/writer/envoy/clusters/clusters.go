@@ -25,9 +25,12 @@ import (
 
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 
 	"istio.io/istio/istioctl/pkg/util/clusters"
 	protio "istio.io/istio/istioctl/pkg/util/proto"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
 )
 
 // EndpointFilter is used to pass filter information into route based config writer print functions
@@ -35,13 +38,29 @@ type EndpointFilter struct {
 	Address string
 	Port    uint32
 	Cluster string
-	Status  string
+	// NameContains selects the endpoints of clusters with a name containing the value, such as foo.bar.svc
+	NameContains string
+	// Status selects endpoints by EDS health status, such as DRAINING. As unhealthy it selects all the endpoints
+	// Envoy does not send traffic to, see isUnhealthyEndpoint.
+	Status string
+	// Locality selects endpoints by comma separated parts of their locality, like region=us-west1,zone=us-west1-a.
+	// Endpoints without a locality never match.
+	Locality string
+	// Wide adds the tls mode and the Istio metadata labels of each endpoint to the summary
+	Wide bool
+
+	locality *core.Locality
 }
 
+// unhealthyStatus is the Status of the filter selecting all unhealthy endpoints
+const unhealthyStatus = "unhealthy"
+
 // ConfigWriter is a writer for processing responses from the Envoy Admin config_dump endpoint
 type ConfigWriter struct {
 	Stdout   io.Writer
 	clusters *clusters.Wrapper
+	// metadata is the metadata of the endpoints by cluster and endpoint address, see SetEndpointMetadata
+	metadata map[string]*core.Metadata
 }
 
 // EndpointCluster is used to store the endpoint and cluster
@@ -51,6 +70,10 @@ type EndpointCluster struct {
 	cluster            string
 	status             core.HealthStatus
 	failedOutlierCheck bool
+	weight             uint32
+	locality           string
+	tlsMode            string
+	labels             string
 }
 
 // Prime loads the clusters output into the writer ready for printing
@@ -88,11 +111,128 @@ func retrieveFailedOutlierCheck(l *adminapi.HostStatus) bool {
 	return l.HealthStatus.GetFailedOutlierCheck()
 }
 
+// retrieveEndpointLocality renders the locality of an endpoint as region/zone/subzone, leaving out the trailing
+// parts it does not set, or - when it has none
+func retrieveEndpointLocality(l *adminapi.HostStatus) string {
+	locality := l.GetLocality()
+	if locality.GetRegion() == "" && locality.GetZone() == "" && locality.GetSubZone() == "" {
+		return "-"
+	}
+	return strings.TrimRight(strings.Join([]string{locality.GetRegion(), locality.GetZone(), locality.GetSubZone()}, "/"), "/")
+}
+
+// SetEndpointMetadata keeps the metadata of the endpoints in the load assignments of the clusters by cluster name,
+// which Envoy does not report in its clusters output, for the wide endpoint summary. The wide summary shows ? for
+// the metadata of endpoints missing from the load assignments.
+func (c *ConfigWriter) SetEndpointMetadata(loadAssignments map[string]*endpoint.ClusterLoadAssignment) {
+	c.metadata = map[string]*core.Metadata{}
+	for clusterName, loadAssignment := range loadAssignments {
+		for _, localityEndpoints := range loadAssignment.GetEndpoints() {
+			for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+				addr := lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress()
+				if addr == nil {
+					continue
+				}
+				c.metadata[endpointMetadataKey(clusterName, addr.GetAddress(), addr.GetPortValue())] = lbEndpoint.GetMetadata()
+			}
+		}
+	}
+}
+
+func endpointMetadataKey(cluster, address string, port uint32) string {
+	return cluster + "/" + address + ":" + strconv.Itoa(int(port))
+}
+
+// retrieveEndpointTLSMode returns the tls mode Istio sets in the transport socket match metadata of an endpoint,
+// which selects the transport socket of the cluster used for the endpoint, or - when it has none
+func retrieveEndpointTLSMode(metadata *core.Metadata) string {
+	tlsMode := metadata.GetFilterMetadata()[util.EnvoyTransportSocketMetadataKey].GetFields()[model.TLSModeLabelShortname]
+	if tlsMode.GetStringValue() == "" {
+		return "-"
+	}
+	return tlsMode.GetStringValue()
+}
+
+// retrieveEndpointLabels renders the Istio metadata of an endpoint as sorted name=value labels, or - when it has none
+func retrieveEndpointLabels(metadata *core.Metadata) string {
+	fields := metadata.GetFilterMetadata()[util.IstioMetadataKey].GetFields()
+	if len(fields) == 0 {
+		return "-"
+	}
+	labels := make([]string, 0, len(fields))
+	for name, value := range fields {
+		labels = append(labels, name+"="+value.GetStringValue())
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+// isUnhealthyEndpoint returns true if Envoy does not send traffic to the endpoint, because its EDS health
+// status is unhealthy, draining or timed out or because it failed outlier detection
+func isUnhealthyEndpoint(l *adminapi.HostStatus) bool {
+	switch retrieveEndpointStatus(l) {
+	case core.HealthStatus_UNHEALTHY, core.HealthStatus_DRAINING, core.HealthStatus_TIMEOUT:
+		return true
+	}
+	return retrieveFailedOutlierCheck(l)
+}
+
+// parseLocalitySelector parses a locality selector like region=us-west1,zone=us-west1-a
+func parseLocalitySelector(selector string) (*core.Locality, error) {
+	locality := &core.Locality{}
+	for _, part := range strings.Split(selector, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid endpoint locality %q, expected region=, zone= or subzone= parts like region=us-west1", part)
+		}
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "region":
+			locality.Region = kv[1]
+		case "zone":
+			locality.Zone = kv[1]
+		case "subzone":
+			locality.SubZone = kv[1]
+		default:
+			return nil, fmt.Errorf("unknown endpoint locality part %q, expected region, zone or subzone", kv[0])
+		}
+	}
+	return locality, nil
+}
+
+// Validate returns an error if the filter fields are malformed
+func (e *EndpointFilter) Validate() error {
+	if e.Locality == "" {
+		return nil
+	}
+	locality, err := parseLocalitySelector(e.Locality)
+	if err != nil {
+		return err
+	}
+	e.locality = locality
+	return nil
+}
+
+// verifyLocality returns true if the locality of the host has all the parts of the locality of the filter
+func (e *EndpointFilter) verifyLocality(host *adminapi.HostStatus) bool {
+	if e.locality == nil {
+		if err := e.Validate(); err != nil {
+			return false
+		}
+	}
+	locality := host.GetLocality()
+	return (e.locality.Region == "" || e.locality.Region == locality.GetRegion()) &&
+		(e.locality.Zone == "" || e.locality.Zone == locality.GetZone()) &&
+		(e.locality.SubZone == "" || e.locality.SubZone == locality.GetSubZone())
+}
+
 // Verify returns true if the passed host matches the filter fields
 func (e *EndpointFilter) Verify(host *adminapi.HostStatus, cluster string) bool {
-	if e.Address == "" && e.Port == 0 && e.Cluster == "" && e.Status == "" {
+	if e.Address == "" && e.Port == 0 && e.Cluster == "" && e.NameContains == "" && e.Status == "" && e.Locality == "" {
 		return true
 	}
+	if e.Locality != "" && !e.verifyLocality(host) {
+		return false
+	}
 	if e.Address != "" && !strings.EqualFold(retrieveEndpointAddress(host), e.Address) {
 		return false
 	}
@@ -102,6 +242,12 @@ func (e *EndpointFilter) Verify(host *adminapi.HostStatus, cluster string) bool
 	if e.Cluster != "" && !strings.EqualFold(cluster, e.Cluster) {
 		return false
 	}
+	if e.NameContains != "" && !strings.Contains(cluster, e.NameContains) {
+		return false
+	}
+	if strings.EqualFold(e.Status, unhealthyStatus) {
+		return isUnhealthyEndpoint(host)
+	}
 	status := retrieveEndpointStatus(host)
 	if e.Status != "" && !strings.EqualFold(core.HealthStatus_name[int32(status)], e.Status) {
 		return false
@@ -109,29 +255,77 @@ func (e *EndpointFilter) Verify(host *adminapi.HostStatus, cluster string) bool
 	return true
 }
 
-// PrintEndpointsSummary prints just the endpoints config summary to the ConfigWriter stdout
-func (c *ConfigWriter) PrintEndpointsSummary(filter EndpointFilter) error {
+// GetEndpoints returns the statuses of the clusters with at least one host matching the filter, sorted by name,
+// with only their matching hosts sorted by address and port
+func (c *ConfigWriter) GetEndpoints(filter EndpointFilter) ([]*adminapi.ClusterStatus, error) {
 	if c.clusters == nil {
-		return fmt.Errorf("config writer has not been primed")
+		return nil, fmt.Errorf("config writer has not been primed")
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	filteredClusters := make([]*adminapi.ClusterStatus, 0)
+	for _, cluster := range c.clusters.ClusterStatuses {
+		hosts := make([]*adminapi.HostStatus, 0)
+		for _, host := range cluster.HostStatuses {
+			if filter.Verify(host, cluster.Name) {
+				hosts = append(hosts, host)
+			}
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		sort.SliceStable(hosts, func(i, j int) bool {
+			iAddr, jAddr := retrieveEndpointAddress(hosts[i]), retrieveEndpointAddress(hosts[j])
+			if iAddr == jAddr {
+				return retrieveEndpointPort(hosts[i]) < retrieveEndpointPort(hosts[j])
+			}
+			return iAddr < jAddr
+		})
+		// Copy the cluster status, so the hosts left out stay in the primed clusters
+		filtered := *cluster
+		filtered.HostStatuses = hosts
+		filteredClusters = append(filteredClusters, &filtered)
+	}
+	sort.SliceStable(filteredClusters, func(i, j int) bool {
+		return filteredClusters[i].Name < filteredClusters[j].Name
+	})
+	return filteredClusters, nil
+}
+
+// PrintEndpointsSummary prints just the endpoints config summary to the ConfigWriter stdout, with the health status,
+// load balancing weight and locality of each endpoint
+func (c *ConfigWriter) PrintEndpointsSummary(filter EndpointFilter) error {
+	clusterStatuses, err := c.GetEndpoints(filter)
+	if err != nil {
+		return err
 	}
 
 	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
 
 	clusterEndpoint := make([]EndpointCluster, 0)
-	for _, cluster := range c.clusters.ClusterStatuses {
+	for _, cluster := range clusterStatuses {
 		for _, host := range cluster.HostStatuses {
-			if filter.Verify(host, cluster.Name) {
-				addr := retrieveEndpointAddress(host)
-				port := retrieveEndpointPort(host)
-				status := retrieveEndpointStatus(host)
-				outlierCheck := retrieveFailedOutlierCheck(host)
-				clusterEndpoint = append(clusterEndpoint, EndpointCluster{addr, int(port), cluster.Name, status, outlierCheck})
+			addr := retrieveEndpointAddress(host)
+			port := retrieveEndpointPort(host)
+			status := retrieveEndpointStatus(host)
+			outlierCheck := retrieveFailedOutlierCheck(host)
+			// The metadata of endpoints missing from the load assignments of SetEndpointMetadata is unknown
+			tlsMode, labels := "?", "?"
+			if metadata, ok := c.metadata[endpointMetadataKey(cluster.Name, addr, port)]; ok {
+				tlsMode, labels = retrieveEndpointTLSMode(metadata), retrieveEndpointLabels(metadata)
 			}
+			clusterEndpoint = append(clusterEndpoint, EndpointCluster{addr, int(port), cluster.Name, status, outlierCheck,
+				host.GetWeight(), retrieveEndpointLocality(host), tlsMode, labels})
 		}
 	}
 
 	clusterEndpoint = retrieveSortedEndpointClusterSlice(clusterEndpoint)
-	fmt.Fprintln(w, "ENDPOINT\tSTATUS\tOUTLIER CHECK\tCLUSTER")
+	if filter.Wide {
+		fmt.Fprintln(w, "ENDPOINT\tSTATUS\tOUTLIER CHECK\tWEIGHT\tLOCALITY\tCLUSTER\tTLS MODE\tLABELS")
+	} else {
+		fmt.Fprintln(w, "ENDPOINT\tSTATUS\tOUTLIER CHECK\tWEIGHT\tLOCALITY\tCLUSTER")
+	}
 	for _, ce := range clusterEndpoint {
 		var endpoint string
 		if ce.port != 0 {
@@ -139,7 +333,12 @@ func (c *ConfigWriter) PrintEndpointsSummary(filter EndpointFilter) error {
 		} else {
 			endpoint = ce.address
 		}
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", endpoint, core.HealthStatus_name[int32(ce.status)], printFailedOutlierCheck(ce.failedOutlierCheck), ce.cluster)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%d\t%v\t%v", endpoint, core.HealthStatus_name[int32(ce.status)],
+			printFailedOutlierCheck(ce.failedOutlierCheck), ce.weight, ce.locality, ce.cluster)
+		if filter.Wide {
+			fmt.Fprintf(w, "\t%v\t%v", ce.tlsMode, ce.labels)
+		}
+		fmt.Fprintln(w)
 	}
 
 	return w.Flush()
@@ -147,19 +346,14 @@ func (c *ConfigWriter) PrintEndpointsSummary(filter EndpointFilter) error {
 
 // PrintEndpoints prints the endpoints config to the ConfigWriter stdout
 func (c *ConfigWriter) PrintEndpoints(filter EndpointFilter) error {
-	if c.clusters == nil {
-		return fmt.Errorf("config writer has not been primed")
+	clusterStatuses, err := c.GetEndpoints(filter)
+	if err != nil {
+		return err
 	}
 
 	filteredClusters := protio.MessageSlice{}
-	for _, cluster := range c.clusters.ClusterStatuses {
-		for _, host := range cluster.HostStatuses {
-			if filter.Verify(host, cluster.Name) {
-				filteredClusters = append(filteredClusters, cluster)
-				break
-			}
-		}
-
+	for _, cluster := range clusterStatuses {
+		filteredClusters = append(filteredClusters, cluster)
 	}
 	out, err := json.MarshalIndent(filteredClusters, "", "    ")
 	if err != nil {
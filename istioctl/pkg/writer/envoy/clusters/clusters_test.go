@@ -0,0 +1,290 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusters
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"istio.io/istio/pilot/test/util"
+)
+
+func newMetadataEndpoint(address string, port uint32, filterMetadata map[string]map[string]string) *endpoint.LbEndpoint {
+	metadata := &core.Metadata{FilterMetadata: map[string]*structpb.Struct{}}
+	for namespace, fields := range filterMetadata {
+		metadata.FilterMetadata[namespace] = &structpb.Struct{Fields: map[string]*structpb.Value{}}
+		for name, value := range fields {
+			metadata.FilterMetadata[namespace].Fields[name] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: value}}
+		}
+	}
+	return &endpoint.LbEndpoint{
+		HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+			Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+				Address:       address,
+				PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+			}}},
+		}},
+		Metadata: metadata,
+	}
+}
+
+func TestConfigWriter_PrintEndpointsSummary(t *testing.T) {
+	tests := []struct {
+		desc     string
+		filter   EndpointFilter
+		wantFile string
+	}{
+		{
+			desc:     "all",
+			wantFile: "testdata/endpointsummary.txt",
+		},
+		{
+			desc:     "unhealthy",
+			filter:   EndpointFilter{Status: "UNHEALTHY"},
+			wantFile: "testdata/endpointsummaryunhealthy.txt",
+		},
+	}
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut}
+			if err := cw.Prime(cd); err != nil {
+				t.Fatal(err)
+			}
+			if err := cw.PrintEndpointsSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+		})
+	}
+}
+
+func TestConfigWriter_GetEndpoints(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	clusterStatuses, err := cw.GetEndpoints(EndpointFilter{Status: unhealthyStatus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0)
+	for _, cluster := range clusterStatuses {
+		for _, host := range cluster.HostStatuses {
+			got = append(got, cluster.Name+" "+retrieveEndpointAddress(host))
+		}
+	}
+	want := []string{
+		"outbound|15014||istiod.istio-system.svc.cluster.local 10.44.0.9",
+		"outbound|9080||reviews.default.svc.cluster.local 10.44.0.13",
+		"outbound|9080||reviews.default.svc.cluster.local 10.44.0.14",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expect %v got %v", want, got)
+	}
+
+	// The hosts left out are still printed without a filter
+	all, err := cw.GetEndpoints(EndpointFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := 0
+	for _, cluster := range all {
+		hosts += len(cluster.HostStatuses)
+	}
+	if hosts != 4 {
+		t.Errorf("expected the 4 hosts of the clusters, got %v", hosts)
+	}
+}
+
+func TestEndpointFilter_VerifyStatus(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc   string
+		filter EndpointFilter
+		want   []bool
+	}{
+		{
+			desc: "no-status",
+			want: []bool{true, true, true, true},
+		},
+		{
+			desc:   "draining",
+			filter: EndpointFilter{Status: "draining"},
+			want:   []bool{false, true, false, false},
+		},
+		{
+			desc:   "healthy-includes-failed-outlier-check",
+			filter: EndpointFilter{Status: "HEALTHY"},
+			want:   []bool{true, false, true, false},
+		},
+		{
+			desc:   "unhealthy",
+			filter: EndpointFilter{Status: "unhealthy"},
+			want:   []bool{false, true, true, true},
+		},
+		{
+			desc:   "unhealthy-in-cluster",
+			filter: EndpointFilter{Status: "unhealthy", Cluster: "outbound|15014||istiod.istio-system.svc.cluster.local"},
+			want:   []bool{false, false, false, true},
+		},
+		{
+			desc:   "name-contains",
+			filter: EndpointFilter{NameContains: "reviews.default"},
+			want:   []bool{true, true, true, false},
+		},
+		{
+			desc:   "name-contains-and-status",
+			filter: EndpointFilter{NameContains: "reviews.default", Status: "healthy"},
+			want:   []bool{true, false, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := make([]bool, 0)
+			for _, cluster := range cw.clusters.ClusterStatuses {
+				for _, host := range cluster.HostStatuses {
+					got = append(got, tt.filter.Verify(host, cluster.Name))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEndpointFilter_VerifyLocality(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc    string
+		filter  EndpointFilter
+		want    []bool
+		wantErr bool
+	}{
+		{
+			desc:   "region",
+			filter: EndpointFilter{Locality: "region=us-central1"},
+			want:   []bool{true, true, false, false},
+		},
+		{
+			desc:   "region-and-zone",
+			filter: EndpointFilter{Locality: "region=us-central1,zone=us-central1-b"},
+			want:   []bool{false, true, false, false},
+		},
+		{
+			desc:   "zone-and-status",
+			filter: EndpointFilter{Locality: "zone=us-central1-a", Status: "healthy"},
+			want:   []bool{true, false, false, false},
+		},
+		{
+			desc:   "missing-subzone",
+			filter: EndpointFilter{Locality: "subzone=rack-1"},
+			want:   []bool{false, false, false, false},
+		},
+		{
+			desc:    "unknown-part",
+			filter:  EndpointFilter{Locality: "datacenter=us-central1"},
+			wantErr: true,
+		},
+		{
+			desc:    "missing-value",
+			filter:  EndpointFilter{Locality: "region"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("%s: expect error %v got %v", tt.desc, tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make([]bool, 0)
+			for _, cluster := range cw.clusters.ClusterStatuses {
+				for _, host := range cluster.HostStatuses {
+					got = append(got, tt.filter.Verify(host, cluster.Name))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintEndpointsSummaryWide(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	cw.SetEndpointMetadata(map[string]*endpoint.ClusterLoadAssignment{
+		"outbound|9080||reviews.default.svc.cluster.local": {
+			Endpoints: []*endpoint.LocalityLbEndpoints{{
+				LbEndpoints: []*endpoint.LbEndpoint{
+					newMetadataEndpoint("10.44.0.12", 9080, map[string]map[string]string{
+						"envoy.transport_socket_match": {"tlsMode": "istio"},
+						"istio":                        {"network": "network1", "uid": "kubernetes://reviews-v1.default"},
+					}),
+					newMetadataEndpoint("10.44.0.14", 9080, map[string]map[string]string{
+						"envoy.transport_socket_match": {"tlsMode": "disabled"},
+					}),
+					// The endpoint of another port of the service is not in the clusters output
+					newMetadataEndpoint("10.44.0.13", 9081, map[string]map[string]string{
+						"envoy.transport_socket_match": {"tlsMode": "istio"},
+					}),
+				},
+			}},
+		},
+	})
+	if err := cw.PrintEndpointsSummary(EndpointFilter{Wide: true}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/endpointsummarywide.txt", t)
+}
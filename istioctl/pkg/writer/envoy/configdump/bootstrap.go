@@ -0,0 +1,476 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+)
+
+// secretTypeURL is the v3 type of the secrets in the secret config dump
+const secretTypeURL = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret"
+
+// bootstrapMetadataFields are the Istio node metadata fields shown in the bootstrap summary, by label
+var bootstrapMetadataFields = []struct{ label, key string }{
+	{"Istio Version", "ISTIO_VERSION"},
+	{"Namespace", "NAMESPACE"},
+	{"Workload", "WORKLOAD_NAME"},
+	{"Cluster ID", "CLUSTER_ID"},
+	{"Mesh ID", "MESH_ID"},
+}
+
+// PrintBootstrapSummary prints the fields of the bootstrap config dump that orient an operator to the ConfigWriter
+// stdout: the node, its Istio version and metadata, the xDS server, the stats sinks and the admin address.
+// Fields the bootstrap leaves unset are shown as -.
+func (c *ConfigWriter) PrintBootstrapSummary() error {
+	if c.configDump == nil {
+		return fmt.Errorf("config writer has not been primed")
+	}
+	bootstrapDump, err := c.configDump.GetBootstrapConfigDump()
+	if err != nil {
+		return err
+	}
+	b := bootstrapDump.GetBootstrap()
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	fmt.Fprintf(w, "Node ID:\t%v\n", orDash(b.GetNode().GetId()))
+	fmt.Fprintf(w, "Cluster:\t%v\n", orDash(b.GetNode().GetCluster()))
+	metadata := b.GetNode().GetMetadata().GetFields()
+	for _, field := range bootstrapMetadataFields {
+		fmt.Fprintf(w, "%v:\t%v\n", field.label, orDash(metadata[field.key].GetStringValue()))
+	}
+	fmt.Fprintf(w, "xDS Server:\t%v\n", orDash(strings.Join(retrieveBootstrapXDSServers(b), ", ")))
+	fmt.Fprintf(w, "Stats Sinks:\t%v\n", orDash(strings.Join(retrieveBootstrapStatsSinks(b), ", ")))
+	fmt.Fprintf(w, "Admin Address:\t%v\n", orDash(formatBootstrapAddress(b.GetAdmin().GetAddress())))
+	return w.Flush()
+}
+
+// NodeMetadataFilter is used to pass filter information into the node metadata print function
+type NodeMetadataFilter struct {
+	// KeyPrefix selects the metadata keys starting with it, such as ISTIO_ or PROXY_CONFIG.
+	KeyPrefix string
+}
+
+// Verify returns true if the passed flattened metadata key matches the filter fields
+func (f *NodeMetadataFilter) Verify(key string) bool {
+	return strings.HasPrefix(key, f.KeyPrefix)
+}
+
+// nodeMetadataEntry is a scalar of the node metadata, keyed by the path of fields leading to it
+type nodeMetadataEntry struct {
+	key   string
+	value string
+}
+
+// PrintNodeMetadata prints the metadata the node of the bootstrap config registered with to the ConfigWriter stdout,
+// flattened into a key: value row per scalar. The keys of nested objects are dotted, like
+// PROXY_CONFIG.discoveryAddress, and the items of lists are indexed, like PROXY_CONFIG.extraStatTags[0].
+func (c *ConfigWriter) PrintNodeMetadata(filter NodeMetadataFilter) error {
+	if c.configDump == nil {
+		return fmt.Errorf("config writer has not been primed")
+	}
+	bootstrapDump, err := c.configDump.GetBootstrapConfigDump()
+	if err != nil {
+		return err
+	}
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	for _, entry := range flattenNodeMetadata(bootstrapDump.GetBootstrap().GetNode().GetMetadata()) {
+		if filter.Verify(entry.key) {
+			fmt.Fprintf(w, "%v:\t%v\n", entry.key, entry.value)
+		}
+	}
+	return w.Flush()
+}
+
+// flattenNodeMetadata returns the scalars of the metadata sorted by key. Empty objects and lists are kept as {} and
+// [], so keys the proxy registered without a value are still shown.
+func flattenNodeMetadata(metadata *structpb.Struct) []nodeMetadataEntry {
+	entries := make([]nodeMetadataEntry, 0)
+	var flatten func(key string, value *structpb.Value)
+	flatten = func(key string, value *structpb.Value) {
+		switch kind := value.GetKind().(type) {
+		case *structpb.Value_StructValue:
+			if len(kind.StructValue.GetFields()) == 0 {
+				entries = append(entries, nodeMetadataEntry{key, "{}"})
+			}
+			for name, field := range kind.StructValue.GetFields() {
+				flatten(key+"."+name, field)
+			}
+		case *structpb.Value_ListValue:
+			if len(kind.ListValue.GetValues()) == 0 {
+				entries = append(entries, nodeMetadataEntry{key, "[]"})
+			}
+			for i, item := range kind.ListValue.GetValues() {
+				flatten(fmt.Sprintf("%s[%d]", key, i), item)
+			}
+		case *structpb.Value_StringValue:
+			entries = append(entries, nodeMetadataEntry{key, kind.StringValue})
+		case *structpb.Value_NumberValue:
+			entries = append(entries, nodeMetadataEntry{key, strconv.FormatFloat(kind.NumberValue, 'f', -1, 64)})
+		case *structpb.Value_BoolValue:
+			entries = append(entries, nodeMetadataEntry{key, strconv.FormatBool(kind.BoolValue)})
+		default:
+			entries = append(entries, nodeMetadataEntry{key, "null"})
+		}
+	}
+	for name, field := range metadata.GetFields() {
+		flatten(name, field)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries
+}
+
+// retrieveBootstrapXDSServers returns the gRPC services the bootstrap receives ADS, CDS and LDS from. Envoy gRPC
+// services are named by their cluster, followed by the addresses of the cluster when it is a static cluster of
+// the bootstrap, like xds-grpc (./etc/istio/proxy/XDS).
+func retrieveBootstrapXDSServers(b *bootstrap.Bootstrap) []string {
+	staticClusters := map[string]*cluster.Cluster{}
+	for _, c := range b.GetStaticResources().GetClusters() {
+		staticClusters[c.Name] = c
+	}
+	dynamicResources := b.GetDynamicResources()
+	servers := make([]string, 0)
+	seen := map[string]bool{}
+	for _, source := range []*core.ApiConfigSource{
+		dynamicResources.GetAdsConfig(),
+		dynamicResources.GetCdsConfig().GetApiConfigSource(),
+		dynamicResources.GetLdsConfig().GetApiConfigSource(),
+	} {
+		for _, grpcService := range source.GetGrpcServices() {
+			server := grpcService.GetGoogleGrpc().GetTargetUri()
+			if name := grpcService.GetEnvoyGrpc().GetClusterName(); name != "" {
+				server = name
+				if addresses := retrieveStaticClusterAddresses(staticClusters[name]); len(addresses) > 0 {
+					server += " (" + strings.Join(addresses, ", ") + ")"
+				}
+			}
+			if server != "" && !seen[server] {
+				seen[server] = true
+				servers = append(servers, server)
+			}
+		}
+	}
+	return servers
+}
+
+// retrieveStaticClusterAddresses returns the addresses of the endpoints of a cluster with a load assignment
+func retrieveStaticClusterAddresses(c *cluster.Cluster) []string {
+	addresses := make([]string, 0)
+	for _, localityEndpoints := range c.GetLoadAssignment().GetEndpoints() {
+		for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+			if address := formatBootstrapAddress(lbEndpoint.GetEndpoint().GetAddress()); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses
+}
+
+func retrieveBootstrapStatsSinks(b *bootstrap.Bootstrap) []string {
+	sinks := make([]string, 0, len(b.GetStatsSinks()))
+	for _, sink := range b.GetStatsSinks() {
+		sinks = append(sinks, sink.GetName())
+	}
+	return sinks
+}
+
+// formatBootstrapAddress renders a socket address as host:port, or the path of a pipe address
+func formatBootstrapAddress(address *core.Address) string {
+	if pipe := address.GetPipe(); pipe != nil {
+		return pipe.Path
+	}
+	socketAddress := address.GetSocketAddress()
+	if socketAddress == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", formatListenerAddress(socketAddress.GetAddress()), socketAddress.GetPortValue())
+}
+
+// listenerBootstrap collects the static resources of a bootstrap config serving a set of listeners
+type listenerBootstrap struct {
+	routes   map[string]*route.RouteConfiguration
+	clusters map[string]*cluster.Cluster
+	secrets  map[string]*tls.Secret
+
+	referencedClusters map[string]bool
+	referencedSecrets  map[string]bool
+	missingRoutes      map[string]bool
+}
+
+// PrintListenerBootstrap prints the listeners matching the filter as the static resources of a minimal Envoy bootstrap
+// config to the ConfigWriter stdout, in the output format of the ConfigWriter, so they can be run by a standalone
+// Envoy without a control plane. The RDS route configs of HTTP connection managers are inlined, the clusters the
+// listeners reference are added as static resources, with the endpoints of the EDS section of the config dump for
+// EDS clusters, and the SDS secrets of the listeners and clusters are added as static secrets. An error is returned
+// if a referenced route config, cluster, EDS cluster endpoints or secret is not in the config dump.
+func (c *ConfigWriter) PrintListenerBootstrap(filter ListenerFilter) error {
+	listeners, err := c.retrieveFilteredListenerSlice(filter)
+	if err != nil {
+		return err
+	}
+	b := c.newListenerBootstrap()
+	staticResources := &bootstrap.Bootstrap_StaticResources{}
+	seen := map[string]bool{}
+	for _, l := range listeners {
+		// A listener is served once, in its first state
+		if seen[l.Name] {
+			continue
+		}
+		seen[l.Name] = true
+		staticListener, err := b.rewriteListener(l.Listener)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite listener %s: %v", l.Name, err)
+		}
+		staticResources.Listeners = append(staticResources.Listeners, staticListener)
+	}
+	if len(b.missingRoutes) > 0 {
+		return fmt.Errorf("listeners reference route configs missing from the config dump: %s",
+			strings.Join(sortedNames(b.missingRoutes), ", "))
+	}
+
+	missingClusters := make([]string, 0)
+	missingEndpoints := make([]string, 0)
+	for _, name := range sortedNames(b.referencedClusters) {
+		cl, ok := b.clusters[name]
+		if !ok {
+			missingClusters = append(missingClusters, name)
+			continue
+		}
+		loadAssignment := c.retrieveEDSLoadAssignment(cl)
+		if cl.GetType() == cluster.Cluster_EDS && loadAssignment == nil {
+			missingEndpoints = append(missingEndpoints, name)
+			continue
+		}
+		staticCluster, err := b.rewriteCluster(cl, loadAssignment)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite cluster %s: %v", name, err)
+		}
+		staticResources.Clusters = append(staticResources.Clusters, staticCluster)
+	}
+	if len(missingClusters) > 0 {
+		return fmt.Errorf("listeners reference clusters missing from the config dump: %s", strings.Join(missingClusters, ", "))
+	}
+	if len(missingEndpoints) > 0 {
+		return fmt.Errorf("listeners reference EDS clusters whose endpoints are missing from the config dump, "+
+			"take it with config_dump?include_eds: %s", strings.Join(missingEndpoints, ", "))
+	}
+	missingSecrets := make([]string, 0)
+	for _, name := range sortedNames(b.referencedSecrets) {
+		if secret, ok := b.secrets[name]; ok {
+			staticResources.Secrets = append(staticResources.Secrets, secret)
+		} else {
+			missingSecrets = append(missingSecrets, name)
+		}
+	}
+	if len(missingSecrets) > 0 {
+		return fmt.Errorf("listeners reference secrets missing from the config dump: %s", strings.Join(missingSecrets, ", "))
+	}
+
+	if err := c.printMessage(&bootstrap.Bootstrap{StaticResources: staticResources}); err != nil {
+		return fmt.Errorf("failed to marshal bootstrap: %v", err)
+	}
+	return nil
+}
+
+// newListenerBootstrap loads the routes, clusters and secrets of the config dump, which are all optional
+func (c *ConfigWriter) newListenerBootstrap() *listenerBootstrap {
+	b := &listenerBootstrap{
+		routes:             map[string]*route.RouteConfiguration{},
+		clusters:           map[string]*cluster.Cluster{},
+		secrets:            map[string]*tls.Secret{},
+		referencedClusters: map[string]bool{},
+		referencedSecrets:  map[string]bool{},
+		missingRoutes:      map[string]bool{},
+	}
+	if routes, err := c.retrieveSortedRouteSlice(); err == nil {
+		for _, r := range routes {
+			b.routes[r.Name] = r
+		}
+	}
+	if clusters, err := c.retrieveSortedClusterSlice(); err == nil {
+		for _, cluster := range clusters {
+			b.clusters[cluster.Name] = cluster
+		}
+	}
+	if secretDump, err := c.configDump.GetSecretConfigDump(); err == nil {
+		secretAnys := make([]*any.Any, 0)
+		for _, s := range secretDump.StaticSecrets {
+			secretAnys = append(secretAnys, s.GetSecret())
+		}
+		for _, s := range secretDump.DynamicActiveSecrets {
+			secretAnys = append(secretAnys, s.GetSecret())
+		}
+		for _, secretAny := range secretAnys {
+			secret := &tls.Secret{}
+			if err := unmarshalAnyAs(secretTypeURL, secretAny, secret); err == nil {
+				b.secrets[secret.Name] = secret
+			}
+		}
+	}
+	return b
+}
+
+// rewriteListener returns a copy of the listener with inline route configs and static SDS secrets, recording
+// the clusters and secrets it references and the route configs missing from the config dump
+func (b *listenerBootstrap) rewriteListener(l *listener.Listener) (*listener.Listener, error) {
+	l = proto.Clone(l).(*listener.Listener)
+	for _, filterChain := range l.GetFilterChains() {
+		for _, filter := range filterChain.GetFilters() {
+			switch {
+			case isHTTPConnectionManager(filter):
+				httpConnectionManager, err := retrieveHTTPConnectionManager(filter)
+				if err != nil {
+					return nil, err
+				}
+				if rds := httpConnectionManager.GetRds(); rds != nil {
+					routeConfig, ok := b.routes[rds.GetRouteConfigName()]
+					if !ok {
+						b.missingRoutes[rds.GetRouteConfigName()] = true
+						continue
+					}
+					httpConnectionManager.RouteSpecifier = &hcm.HttpConnectionManager_RouteConfig{RouteConfig: routeConfig}
+				}
+				b.referenceRouteClusters(httpConnectionManager.GetRouteConfig())
+				typedConfig, err := ptypes.MarshalAny(httpConnectionManager)
+				if err != nil {
+					return nil, err
+				}
+				filter.ConfigType = &listener.Filter_TypedConfig{TypedConfig: typedConfig}
+			case isTCPProxy(filter):
+				tcpProxy, err := retrieveTCPProxy(filter)
+				if err != nil {
+					return nil, err
+				}
+				b.referenceCluster(tcpProxy.GetCluster())
+				for _, weightedCluster := range tcpProxy.GetWeightedClusters().GetClusters() {
+					b.referenceCluster(weightedCluster.GetName())
+				}
+			}
+		}
+		if err := b.rewriteTransportSocket(filterChain); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// rewriteTransportSocket makes the SDS secrets of a TLS filter chain static, see referenceSecrets
+func (b *listenerBootstrap) rewriteTransportSocket(filterChain *listener.FilterChain) error {
+	transportSocket := filterChain.GetTransportSocket()
+	if transportSocket == nil || !isTLSTransportSocket(transportSocket) {
+		return nil
+	}
+	tlsContext, err := retrieveDownstreamTLSContext(transportSocket)
+	if err != nil {
+		return err
+	}
+	b.referenceSecrets(tlsContext.GetCommonTlsContext())
+	typedConfig, err := ptypes.MarshalAny(tlsContext)
+	if err != nil {
+		return err
+	}
+	transportSocket.ConfigType = &core.TransportSocket_TypedConfig{TypedConfig: typedConfig}
+	return nil
+}
+
+// rewriteCluster returns a copy of the cluster with static SDS secrets, recording the secrets it references. A cluster
+// with the load assignment of the EDS section of the config dump is made a STATIC cluster of that load assignment.
+func (b *listenerBootstrap) rewriteCluster(cl *cluster.Cluster, loadAssignment *endpoint.ClusterLoadAssignment) (*cluster.Cluster, error) {
+	cl = proto.Clone(cl).(*cluster.Cluster)
+	if loadAssignment != nil {
+		cl.ClusterDiscoveryType = &cluster.Cluster_Type{Type: cluster.Cluster_STATIC}
+		cl.EdsClusterConfig = nil
+		cl.LoadAssignment = proto.Clone(loadAssignment).(*endpoint.ClusterLoadAssignment)
+		cl.LoadAssignment.ClusterName = cl.Name
+	}
+	transportSockets := []*core.TransportSocket{cl.GetTransportSocket()}
+	for _, match := range cl.GetTransportSocketMatches() {
+		transportSockets = append(transportSockets, match.GetTransportSocket())
+	}
+	for _, transportSocket := range transportSockets {
+		if !isUpstreamTLSTransportSocket(transportSocket) {
+			continue
+		}
+		tlsContext, err := retrieveUpstreamTLSContext(transportSocket)
+		if err != nil {
+			return nil, err
+		}
+		b.referenceSecrets(tlsContext.GetCommonTlsContext())
+		typedConfig, err := ptypes.MarshalAny(tlsContext)
+		if err != nil {
+			return nil, err
+		}
+		transportSocket.ConfigType = &core.TransportSocket_TypedConfig{TypedConfig: typedConfig}
+	}
+	return cl, nil
+}
+
+// referenceSecrets makes the SDS secrets of a TLS context static, so they are read from the static resources instead
+// of from the control plane, recording them
+func (b *listenerBootstrap) referenceSecrets(commonTLSContext *tls.CommonTlsContext) {
+	secretConfigs := append([]*tls.SdsSecretConfig{}, commonTLSContext.GetTlsCertificateSdsSecretConfigs()...)
+	secretConfigs = append(secretConfigs, commonTLSContext.GetValidationContextSdsSecretConfig(),
+		commonTLSContext.GetCombinedValidationContext().GetValidationContextSdsSecretConfig())
+	for _, secretConfig := range secretConfigs {
+		if secretConfig == nil {
+			continue
+		}
+		b.referencedSecrets[secretConfig.Name] = true
+		secretConfig.SdsConfig = nil
+	}
+}
+
+func (b *listenerBootstrap) referenceRouteClusters(routeConfig *route.RouteConfiguration) {
+	for _, virtualHost := range routeConfig.GetVirtualHosts() {
+		for _, r := range virtualHost.GetRoutes() {
+			b.referenceCluster(r.GetRoute().GetCluster())
+			for _, weightedCluster := range r.GetRoute().GetWeightedClusters().GetClusters() {
+				b.referenceCluster(weightedCluster.GetName())
+			}
+		}
+	}
+}
+
+func (b *listenerBootstrap) referenceCluster(name string) {
+	if name != "" {
+		b.referencedClusters[name] = true
+	}
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,289 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	metrics "github.com/envoyproxy/go-control-plane/envoy/config/metrics/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/test/util"
+)
+
+func TestConfigWriter_PrintBootstrapSummary(t *testing.T) {
+	newStringValue := func(value string) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: value}}
+	}
+	xdsGrpc := []*core.GrpcService{{
+		TargetSpecifier: &core.GrpcService_EnvoyGrpc_{EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: "xds-grpc"}},
+	}}
+	tests := []struct {
+		desc      string
+		bootstrap *bootstrap.Bootstrap
+		wantFile  string
+		want      string
+	}{
+		{
+			desc: "sidecar",
+			bootstrap: &bootstrap.Bootstrap{
+				Node: &core.Node{
+					Id:      "sidecar~10.44.0.12~reviews-v1-5b8c6d6f8-x2mnp.default~default.svc.cluster.local",
+					Cluster: "reviews.default",
+					Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+						"ISTIO_VERSION": newStringValue("1.7.0"),
+						"NAMESPACE":     newStringValue("default"),
+						"WORKLOAD_NAME": newStringValue("reviews-v1"),
+						"CLUSTER_ID":    newStringValue("Kubernetes"),
+					}},
+				},
+				StaticResources: &bootstrap.Bootstrap_StaticResources{
+					Clusters: []*cluster.Cluster{{
+						Name: "xds-grpc",
+						LoadAssignment: &endpoint.ClusterLoadAssignment{Endpoints: []*endpoint.LocalityLbEndpoints{{
+							LbEndpoints: []*endpoint.LbEndpoint{{HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+								Address: &core.Address{Address: &core.Address_Pipe{Pipe: &core.Pipe{Path: "./etc/istio/proxy/XDS"}}},
+							}}}},
+						}}},
+					}},
+				},
+				DynamicResources: &bootstrap.Bootstrap_DynamicResources{
+					AdsConfig: &core.ApiConfigSource{ApiType: core.ApiConfigSource_GRPC, GrpcServices: xdsGrpc},
+					CdsConfig: &core.ConfigSource{ConfigSourceSpecifier: &core.ConfigSource_Ads{Ads: &core.AggregatedConfigSource{}}},
+				},
+				StatsSinks: []*metrics.StatsSink{{Name: "envoy.stat_sinks.metrics_service"}},
+				Admin: &bootstrap.Admin{Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+					Address:       "127.0.0.1",
+					PortSpecifier: &core.SocketAddress_PortValue{PortValue: 15000},
+				}}}},
+			},
+			wantFile: "testdata/bootstrapsummary.txt",
+		},
+		{
+			desc: "google-grpc-lds",
+			bootstrap: &bootstrap.Bootstrap{
+				DynamicResources: &bootstrap.Bootstrap_DynamicResources{
+					LdsConfig: &core.ConfigSource{ConfigSourceSpecifier: &core.ConfigSource_ApiConfigSource{ApiConfigSource: &core.ApiConfigSource{
+						ApiType: core.ApiConfigSource_GRPC,
+						GrpcServices: []*core.GrpcService{{TargetSpecifier: &core.GrpcService_GoogleGrpc_{
+							GoogleGrpc: &core.GrpcService_GoogleGrpc{TargetUri: "istiod.istio-system.svc:15012"},
+						}}},
+					}}},
+				},
+			},
+			want: "Node ID:           -\n" +
+				"Cluster:           -\n" +
+				"Istio Version:     -\n" +
+				"Namespace:         -\n" +
+				"Workload:          -\n" +
+				"Cluster ID:        -\n" +
+				"Mesh ID:           -\n" +
+				"xDS Server:        istiod.istio-system.svc:15012\n" +
+				"Stats Sinks:       -\n" +
+				"Admin Address:     -\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newConfigWriter(t, gotOut, &adminapi.BootstrapConfigDump{Bootstrap: tt.bootstrap})
+			if err := cw.PrintBootstrapSummary(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFile != "" {
+				util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+			} else if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintNodeMetadata(t *testing.T) {
+	newStringValue := func(value string) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: value}}
+	}
+	newStructValue := func(fields map[string]*structpb.Value) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{Fields: fields}}}
+	}
+	metadata := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"ISTIO_VERSION": newStringValue("1.7.0"),
+		"LABELS": newStructValue(map[string]*structpb.Value{
+			"app":     newStringValue("reviews"),
+			"version": newStringValue("v1"),
+		}),
+		"MESH_ID": newStringValue("cluster.local"),
+		"PROXY_CONFIG": newStructValue(map[string]*structpb.Value{
+			"concurrency":      {Kind: &structpb.Value_NumberValue{NumberValue: 2}},
+			"discoveryAddress": newStringValue("istiod.istio-system.svc:15012"),
+			"extraStatTags": {Kind: &structpb.Value_ListValue{ListValue: &structpb.ListValue{Values: []*structpb.Value{
+				newStringValue("request_host"),
+				newStringValue("upstream_proxy_version"),
+			}}}},
+			"holdApplicationUntilProxyStarts": {Kind: &structpb.Value_BoolValue{BoolValue: true}},
+			"tracing":                         newStructValue(nil),
+		}),
+	}}
+	tests := []struct {
+		desc     string
+		filter   NodeMetadataFilter
+		wantFile string
+		want     string
+	}{
+		{
+			desc:     "all",
+			wantFile: "testdata/nodemetadata.txt",
+		},
+		{
+			desc:   "key-prefix",
+			filter: NodeMetadataFilter{KeyPrefix: "LABELS."},
+			want:   "LABELS.app:         reviews\nLABELS.version:     v1\n",
+		},
+		{
+			desc:   "unknown-key-prefix",
+			filter: NodeMetadataFilter{KeyPrefix: "ISTIO_META_"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newConfigWriter(t, gotOut, &adminapi.BootstrapConfigDump{Bootstrap: &bootstrap.Bootstrap{Node: &core.Node{Metadata: metadata}}})
+			if err := cw.PrintNodeMetadata(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFile != "" {
+				util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+			} else if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintListenerBootstrap(t *testing.T) {
+	newHTTPListener := func(port uint32) *listener.Listener {
+		l := newSocketListener("0.0.0.0", port)
+		l.Name = fmt.Sprintf("0.0.0.0_%d", port)
+		l.FilterChains = []*listener.FilterChain{{
+			Filters: []*listener.Filter{newTypedFilter(t, HTTPListenerCanonical, &hcm.HttpConnectionManager{
+				RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: fmt.Sprint(port)}},
+			})},
+		}}
+		return l
+	}
+	tcpListener := newSocketListener("10.0.0.1", 3306)
+	tcpListener.Name = "10.0.0.1_3306"
+	tcpListener.FilterChains = []*listener.FilterChain{{
+		Filters: []*listener.Filter{newTypedFilter(t, TCPListenerCanonical, &tcp.TcpProxy{
+			ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "outbound|3306||mysql.default.svc.cluster.local"},
+		})},
+	}}
+	routeConfig := &route.RouteConfiguration{
+		Name: "8080",
+		VirtualHosts: []*route.VirtualHost{{
+			Name:    "reviews.default.svc.cluster.local:8080",
+			Domains: []string{"reviews.default.svc.cluster.local"},
+			Routes: []*route.Route{{
+				Action: &route.Route_Route{Route: &route.RouteAction{
+					ClusterSpecifier: &route.RouteAction_Cluster{Cluster: "outbound|8080||reviews.default.svc.cluster.local"},
+				}},
+			}},
+		}},
+	}
+	ads := &core.ConfigSource{ConfigSourceSpecifier: &core.ConfigSource_Ads{Ads: &core.AggregatedConfigSource{}}}
+	reviews := &cluster.Cluster{
+		Name:                 "outbound|8080||reviews.default.svc.cluster.local",
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		EdsClusterConfig:     &cluster.Cluster_EdsClusterConfig{EdsConfig: ads},
+		TransportSocket: &core.TransportSocket{
+			Name: "envoy.transport_sockets.tls",
+			ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: mustMarshalAny(t, &tls.UpstreamTlsContext{
+				CommonTlsContext: &tls.CommonTlsContext{
+					TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{{Name: "default", SdsConfig: ads}},
+				},
+			})},
+		},
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigDumpWriter(t, gotOut, []*listener.Listener{newHTTPListener(8080), newHTTPListener(9090), tcpListener},
+		[]*cluster.Cluster{reviews}, []*route.RouteConfiguration{routeConfig}, &adminapi.SecretsConfigDump{
+			DynamicActiveSecrets: []*adminapi.SecretsConfigDump_DynamicSecret{{
+				Name:   "default",
+				Secret: mustMarshalAny(t, &tls.Secret{Name: "default"}),
+			}},
+		})
+	cw.OutputFormat = YAML
+	cw.edsLoadAssignments = map[string]*endpoint.ClusterLoadAssignment{
+		reviews.Name: newSocketLoadAssignment("10.44.0.12", 8080),
+	}
+
+	if err := cw.PrintListenerBootstrap(ListenerFilter{Port: 8080}); err != nil {
+		t.Fatal(err)
+	}
+	out := gotOut.String()
+	for _, want := range []string{
+		"staticResources:", "routeConfig:", "- name: outbound|8080||reviews.default.svc.cluster.local", "loadAssignment:",
+		"address: 10.44.0.12", "secrets:", "- name: default",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	for _, dynamic := range []string{"rds:", "type: EDS", "edsClusterConfig:", "sdsConfig:"} {
+		if strings.Contains(out, dynamic) {
+			t.Errorf("expected no %q left in:\n%s", dynamic, out)
+		}
+	}
+	if err := yaml.Unmarshal(gotOut.Bytes(), &map[string]interface{}{}); err != nil {
+		t.Errorf("bootstrap is not valid YAML: %v", err)
+	}
+
+	for _, tt := range []struct {
+		desc   string
+		filter ListenerFilter
+		noEDS  bool
+		want   string
+	}{
+		{desc: "missing-cluster", filter: ListenerFilter{Port: 3306}, want: "outbound|3306||mysql.default.svc.cluster.local"},
+		{desc: "missing-route", filter: ListenerFilter{Port: 9090}, want: "route configs missing from the config dump: 9090"},
+		{desc: "missing-eds-endpoints", filter: ListenerFilter{Port: 8080}, noEDS: true, want: "config_dump?include_eds"},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			edsLoadAssignments := cw.edsLoadAssignments
+			if tt.noEDS {
+				cw.edsLoadAssignments = nil
+			}
+			err := cw.PrintListenerBootstrap(tt.filter)
+			cw.edsLoadAssignments = edsLoadAssignments
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error with %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -16,97 +16,1213 @@ package configdump
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/duration"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
 
 	protio "istio.io/istio/istioctl/pkg/util/proto"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/proxy/envoy/v3"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config/host"
 )
 
 // ClusterFilter is used to pass filter information into cluster based config writer print functions
 type ClusterFilter struct {
-	FQDN      host.Name
+	// FQDN selects clusters by a substring of their name
+	FQDN host.Name
+	// FQDNRegex matches FQDN as a regular expression against the service host of Istio cluster names, or else the
+	// whole name, like .*\.example\.com
+	FQDNRegex bool
+	// FQDNExact matches FQDN against the service host of Istio cluster names, or else the whole name, exactly or by
+	// its leading DNS labels, so reviews and reviews.default match reviews.default.svc.cluster.local
+	FQDNExact bool
+	// NameContains selects clusters with a name containing the value, such as foo.bar.svc
+	NameContains string
+	// Port selects clusters by the port of Istio cluster names, or by the port of the load assignment of other
+	// clusters, see retrieveClusterPort
 	Port      int
 	Subset    string
 	Direction model.TrafficDirection
+	// Type selects clusters by discovery type, such as STRICT_DNS, or by the name of their custom cluster type
+	Type string
+	// LbPolicy selects clusters by load balancing policy, such as LEAST_REQUEST, see retrieveClusterLbPolicy
+	LbPolicy string
+	// TLSMode selects clusters by the TLS mode of their upstream connections, such as DISABLE for plaintext clusters
+	TLSMode string
+	// NonDefaultCircuitBreakers selects clusters with circuit breaker thresholds that differ from the Envoy defaults
+	NonDefaultCircuitBreakers bool
+	// DestinationRule selects clusters shaped by the DestinationRule, named namespace/name
+	DestinationRule string
+	// Verbose adds the DestinationRule, upstream HTTP protocol and idle timeout of each cluster to the summary
+	Verbose bool
+	// LastUpdated adds how long ago each cluster was last updated to the summary
+	LastUpdated bool
+	// SortBy orders clusters by fqdn, port or name, fqdn being the default. Clusters are ordered by service FQDN,
+	// port, subset and direction, by port first when sorted by port, and by their whole name when sorted by name.
+	SortBy string
+
+	fqdnRegex *regexp.Regexp
 }
 
+// States of clusters in the config dump. Warming clusters are waiting for their initial endpoints or secrets, such
+// as EDS clusters whose endpoints never arrive, and are not used until they are active.
+const (
+	clusterStateActive  = "ACTIVE"
+	clusterStateWarming = "WARMING"
+	clusterStateStatic  = "STATIC"
+)
+
+// clusterWithState is a cluster along with the config dump state it was found in
+type clusterWithState struct {
+	*cluster.Cluster
+	state       string
+	lastUpdated *timestamp.Timestamp
+}
+
+// TLS modes of the upstream connections of clusters, named after the DestinationRule TLS modes
+const (
+	clusterTLSModeIstioMutual = "ISTIO_MUTUAL"
+	clusterTLSModeMutual      = "MUTUAL"
+	clusterTLSModeSimple      = "SIMPLE"
+	clusterTLSModeDisable     = "DISABLE"
+	// clusterTLSModeAuto is the mode of auto mTLS clusters, which send Istio mTLS to endpoints labeled with
+	// tlsMode istio and plaintext to the others
+	clusterTLSModeAuto = "AUTO"
+)
+
+// Envoy defaults of the outlier detection settings of a cluster
+const (
+	defaultConsecutive5xx     = 5
+	defaultOutlierInterval    = 10 * time.Second
+	defaultBaseEjectionTime   = 30 * time.Second
+	defaultMaxEjectionPercent = 10
+)
+
+// envoyDefaultSuffix marks the Envoy defaults shown for the settings a cluster leaves unset
+const envoyDefaultSuffix = " (default)"
+
+// Upstream HTTP protocols of clusters
+const (
+	upstreamProtocolExplicitHTTP1 = "explicit-http1"
+	upstreamProtocolExplicitHTTP2 = "explicit-http2"
+	upstreamProtocolExplicitHTTP3 = "explicit-http3"
+	upstreamProtocolDownstream    = "downstream-protocol"
+	// upstreamProtocolAuto selects HTTP/2 or HTTP/1.1 by the ALPN the upstream endpoint negotiates
+	upstreamProtocolAuto    = "auto"
+	upstreamProtocolDefault = "http1 (default)"
+)
+
+// httpProtocolOptionsExtension is the key of the HTTP protocol options in the typed extension protocol options of a cluster
+const httpProtocolOptionsExtension = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
+
+// upstreamTLSContextTypeURL is the v3 type of the TLS transport socket config of a cluster
+const upstreamTLSContextTypeURL = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext"
+
+// sortByFQDN orders clusters by their service FQDN, see sortClusters
+const sortByFQDN = "fqdn"
+
+// defaultHealthyPanicThreshold is the Envoy default of the healthy panic threshold of a cluster, in percent
+const defaultHealthyPanicThreshold = 50
+
+// defaultDNSRefreshRate is the Envoy default of the DNS refresh rate of STRICT_DNS and LOGICAL_DNS clusters
+const defaultDNSRefreshRate = 5 * time.Second
+
+// Envoy defaults of the circuit breaker thresholds of a cluster
+const (
+	defaultMaxConnections     = 1024
+	defaultMaxPendingRequests = 1024
+	defaultMaxRequests        = 1024
+	defaultMaxRetries         = 3
+)
+
 // Verify returns true if the passed cluster matches the filter fields
 func (c *ClusterFilter) Verify(cluster *cluster.Cluster) bool {
 	name := cluster.Name
-	if c.FQDN == "" && c.Port == 0 && c.Subset == "" && c.Direction == "" {
+	if c.FQDN == "" && c.NameContains == "" && c.Port == 0 && c.Subset == "" && c.Direction == "" && c.Type == "" && c.LbPolicy == "" &&
+		c.TLSMode == "" && !c.NonDefaultCircuitBreakers && c.DestinationRule == "" {
 		return true
 	}
-	if c.FQDN != "" && !strings.Contains(name, string(c.FQDN)) {
+	if c.FQDN != "" && !c.verifyFQDN(name) {
+		return false
+	}
+	if c.NameContains != "" && !strings.Contains(name, c.NameContains) {
+		return false
+	}
+	// The direction, port and subset are parsed from Istio cluster names like
+	// outbound|8080|v1|foo.default.svc.cluster.local, so clusters named otherwise, like BlackHoleCluster, never match
+	direction, subset, _, _ := safelyParseSubsetKey(name)
+	if c.Direction != "" && direction != c.Direction {
+		return false
+	}
+	if c.Subset != "" && subset != c.Subset {
 		return false
 	}
-	if c.Direction != "" && !strings.Contains(name, string(c.Direction)) {
+	if c.Port != 0 && retrieveClusterPort(cluster) != c.Port {
 		return false
 	}
-	if c.Subset != "" && !strings.Contains(name, c.Subset) {
+	if c.Type != "" && !strings.EqualFold(retrieveClusterType(cluster), c.Type) {
 		return false
 	}
-	if c.Port != 0 {
-		p := fmt.Sprintf("|%v|", c.Port)
-		if !strings.Contains(name, p) {
+	if c.LbPolicy != "" && !strings.EqualFold(retrieveClusterLbPolicy(cluster), c.LbPolicy) {
+		return false
+	}
+	if c.TLSMode != "" && !strings.EqualFold(retrieveClusterTLSMode(cluster), c.TLSMode) {
+		return false
+	}
+	if c.NonDefaultCircuitBreakers && !hasNonDefaultCircuitBreakers(cluster) {
+		return false
+	}
+	if c.DestinationRule != "" && retrieveClusterDestinationRule(cluster) != c.DestinationRule {
+		return false
+	}
+	return true
+}
+
+// Validate returns an error if the filter fields cannot select any cluster
+func (c *ClusterFilter) Validate() error {
+	// Custom cluster types are named like extensions, e.g. envoy.clusters.aggregate
+	if _, ok := cluster.Cluster_DiscoveryType_value[strings.ToUpper(c.Type)]; c.Type != "" && !ok && !strings.Contains(c.Type, ".") {
+		return fmt.Errorf("unknown cluster type %q, expected one of STATIC, STRICT_DNS, LOGICAL_DNS, EDS, ORIGINAL_DST "+
+			"or the name of a custom cluster type", c.Type)
+	}
+	if _, ok := cluster.Cluster_LbPolicy_value[strings.ToUpper(c.LbPolicy)]; c.LbPolicy != "" && !ok && !strings.Contains(c.LbPolicy, ".") {
+		return fmt.Errorf("unknown cluster load balancing policy %q, expected one of ROUND_ROBIN, LEAST_REQUEST, RING_HASH, RANDOM, "+
+			"MAGLEV, CLUSTER_PROVIDED or the name of a load balancing policy", c.LbPolicy)
+	}
+	switch c.SortBy {
+	case "", sortByFQDN, sortByPort, sortByName:
+	default:
+		return fmt.Errorf("cannot sort clusters by %q, expected one of %s, %s or %s", c.SortBy, sortByFQDN, sortByPort, sortByName)
+	}
+	if c.FQDNRegex {
+		fqdnRegex, err := compileAnchoredPattern(string(c.FQDN))
+		if err != nil {
+			return fmt.Errorf("invalid cluster FQDN pattern %q: %v", c.FQDN, err)
+		}
+		c.fqdnRegex = fqdnRegex
+	}
+	return nil
+}
+
+// verifyFQDN returns true if the cluster name contains the FQDN, or if its service host matches the FQDN as a
+// regular expression or, with FQDNExact, exactly or by short name. A short name matches the services of every
+// namespace with that name, so all of them are selected.
+func (c *ClusterFilter) verifyFQDN(name string) bool {
+	_, _, fqdn, _ := safelyParseSubsetKey(name)
+	if !c.FQDNRegex {
+		if c.FQDNExact {
+			return fqdn == c.FQDN || strings.HasPrefix(string(fqdn), string(c.FQDN)+".")
+		}
+		return strings.Contains(name, string(c.FQDN))
+	}
+	if c.fqdnRegex == nil {
+		fqdnRegex, err := compileAnchoredPattern(string(c.FQDN))
+		if err != nil {
 			return false
 		}
+		c.fqdnRegex = fqdnRegex
 	}
-	return true
+	return c.fqdnRegex.MatchString(string(fqdn))
+}
+
+// retrieveClusterType returns the discovery type of a cluster, or the name of its custom cluster type
+func retrieveClusterType(c *cluster.Cluster) string {
+	if clusterType := c.GetClusterType(); clusterType != nil {
+		return clusterType.GetName()
+	}
+	return c.GetType().String()
 }
 
-// PrintClusterSummary prints a summary of the relevant clusters in the config dump to the ConfigWriter stdout
+// retrieveClusterLbPolicy returns the load balancing policy of a cluster. Clusters configuring their policy with
+// load_balancing_policy get the first policy Envoy supports, shown as RING_HASH or MAGLEV for the hashing policies
+// and by name for the others.
+func retrieveClusterLbPolicy(c *cluster.Cluster) string {
+	if c.GetLbPolicy() != cluster.Cluster_LOAD_BALANCING_POLICY_CONFIG {
+		return c.GetLbPolicy().String()
+	}
+	for _, policy := range c.GetLoadBalancingPolicy().GetPolicies() {
+		switch {
+		case strings.Contains(policy.GetName(), "ring_hash"):
+			return cluster.Cluster_RING_HASH.String()
+		case strings.Contains(policy.GetName(), "maglev"):
+			return cluster.Cluster_MAGLEV.String()
+		case policy.GetName() != "":
+			return policy.GetName()
+		}
+	}
+	return c.GetLbPolicy().String()
+}
+
+// retrieveClusterDestinationRule returns the namespace/name of the DestinationRule Istio records in the istio
+// metadata of a cluster, or "" when no DestinationRule applies to the cluster
+func retrieveClusterDestinationRule(c *cluster.Cluster) string {
+	config := retrieveIstioConfig(c.GetMetadata())
+	if !strings.HasPrefix(config, "DestinationRule/") {
+		return ""
+	}
+	return strings.TrimPrefix(config, "DestinationRule/")
+}
+
+// ClusterSummary is a cluster summarized as a row of the cluster summary, for tooling reading the
+// summary as JSON or YAML. Service, port, subset and direction are parsed from Istio cluster names
+// and left out for other clusters.
+type ClusterSummary struct {
+	Name        string `json:"name"`
+	ServiceFQDN string `json:"serviceFqdn,omitempty"`
+	Port        int    `json:"port,omitempty"`
+	Subset      string `json:"subset,omitempty"`
+	Direction   string `json:"direction,omitempty"`
+	Type        string `json:"type"`
+	LbPolicy    string `json:"lbPolicy"`
+	TLSMode     string `json:"tlsMode"`
+	// HealthyEndpoints and Endpoints count the endpoints of the cluster, see retrieveClusterEndpoints. They are
+	// left out when the endpoints of the cluster are not known.
+	HealthyEndpoints *int `json:"healthyEndpoints,omitempty"`
+	Endpoints        *int `json:"endpoints,omitempty"`
+	// DestinationRule is the namespace/name of the DestinationRule applied to the cluster
+	DestinationRule string `json:"destinationRule,omitempty"`
+	// State is ACTIVE or WARMING for clusters received over CDS, and STATIC for the clusters of the bootstrap
+	State       string     `json:"state"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+func (c *ConfigWriter) retrieveClusterSummaries(clusters []*clusterWithState, filter ClusterFilter) []ClusterSummary {
+	summaries := make([]ClusterSummary, 0, len(clusters))
+	for _, cs := range clusters {
+		cl := cs.Cluster
+		summary := ClusterSummary{
+			Name:            cl.Name,
+			Type:            retrieveClusterType(cl),
+			LbPolicy:        retrieveClusterLbPolicy(cl),
+			TLSMode:         retrieveClusterTLSMode(cl),
+			DestinationRule: retrieveClusterDestinationRule(cl),
+			State:           cs.state,
+		}
+		if healthy, total, ok := c.retrieveClusterEndpoints(cl); ok {
+			summary.HealthyEndpoints = &healthy
+			summary.Endpoints = &total
+		}
+		if filter.LastUpdated {
+			summary.LastUpdated = retrieveClusterLastUpdated(cs)
+		}
+		if len(strings.Split(cl.Name, "|")) > 3 {
+			direction, subset, fqdn, port := model.ParseSubsetKey(cl.Name)
+			summary.ServiceFQDN = string(fqdn)
+			summary.Port = port
+			summary.Subset = subset
+			summary.Direction = string(direction)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// PrintClusterSummary prints a summary of the relevant clusters in the config dump to the ConfigWriter stdout,
+// as a table or, when the output format of the ConfigWriter is JSON or YAML, as a list of ClusterSummary
 func (c *ConfigWriter) PrintClusterSummary(filter ClusterFilter) error {
-	w, clusters, err := c.setupClusterConfigWriter()
+	clusters, err := c.retrieveFilteredClusterSlice(filter)
 	if err != nil {
 		return err
 	}
-	_, _ = fmt.Fprintln(w, "SERVICE FQDN\tPORT\tSUBSET\tDIRECTION\tTYPE")
-	for _, c := range clusters {
-		if filter.Verify(c) {
-			if len(strings.Split(c.Name, "|")) > 3 {
-				direction, subset, fqdn, port := model.ParseSubsetKey(c.Name)
-				if subset == "" {
-					subset = "-"
-				}
-				_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%s\n", fqdn, port, subset, direction, c.GetType())
+	if c.OutputFormat != Table {
+		out, err := json.MarshalIndent(c.retrieveClusterSummaries(clusters, filter), "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal cluster summary: %v", err)
+		}
+		return c.printJSON(out)
+	}
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	header := "SERVICE FQDN\tPORT\tSUBSET\tDIRECTION\tTYPE\tLB\tTLS MODE\tENDPOINTS\tSTATE"
+	if filter.LastUpdated {
+		header += "\tLAST UPDATED"
+	}
+	if filter.Verbose {
+		header += "\tDESTINATION RULE\tPROTOCOL\tIDLE TIMEOUT"
+	}
+	_, _ = fmt.Fprintln(w, header)
+	now := time.Now()
+	for _, cs := range clusters {
+		cl := cs.Cluster
+		if len(strings.Split(cl.Name, "|")) > 3 {
+			direction, subset, fqdn, port := model.ParseSubsetKey(cl.Name)
+			if subset == "" {
+				subset = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%s\t%s\t%s\t%s", fqdn, port, subset, direction,
+				retrieveClusterType(cl), retrieveClusterLbPolicy(cl), retrieveClusterTLSMode(cl), c.formatClusterEndpoints(cl))
+		} else {
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%s\t%s\t%s\t%s", cl.Name, "-", "-", "-",
+				retrieveClusterType(cl), retrieveClusterLbPolicy(cl), retrieveClusterTLSMode(cl), c.formatClusterEndpoints(cl))
+		}
+		_, _ = fmt.Fprintf(w, "\t%s", cs.state)
+		if filter.LastUpdated {
+			_, _ = fmt.Fprintf(w, "\t%s", formatLastUpdated(retrieveClusterLastUpdated(cs), now))
+		}
+		if filter.Verbose {
+			destinationRule := retrieveClusterDestinationRule(cl)
+			if destinationRule == "" {
+				destinationRule = "-"
+			}
+			_, _ = fmt.Fprintf(w, "\t%s\t%s\t%s", destinationRule, c.retrieveClusterUpstreamProtocol(cl),
+				c.retrieveClusterIdleTimeout(cl))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// retrieveClusterTLSMode returns the TLS mode of the upstream connections of a cluster. Clusters with transport
+// socket matches sending Istio mTLS to the endpoints labeled with tlsMode istio and plaintext to the others, which
+// Istio generates for auto mTLS, are AUTO. Other clusters with transport socket matches get the modes of their
+// matches joined by "/", like MUTUAL/DISABLE.
+func retrieveClusterTLSMode(c *cluster.Cluster) string {
+	if len(c.GetTransportSocketMatches()) == 0 {
+		return describeUpstreamTransportSocket(c.GetTransportSocket())
+	}
+	seen := map[string]bool{}
+	modes := make([]string, 0, len(c.GetTransportSocketMatches()))
+	istioMutual := false
+	for _, match := range c.GetTransportSocketMatches() {
+		mode := describeUpstreamTransportSocket(match.GetTransportSocket())
+		if mode == clusterTLSModeIstioMutual && isIstioTLSModeMatch(match) {
+			istioMutual = true
+		}
+		if !seen[mode] {
+			seen[mode] = true
+			modes = append(modes, mode)
+		}
+	}
+	if istioMutual && seen[clusterTLSModeDisable] && len(modes) == 2 {
+		return clusterTLSModeAuto
+	}
+	return strings.Join(modes, "/")
+}
+
+// isIstioTLSModeMatch returns true if the transport socket match selects the endpoints labeled with tlsMode istio
+func isIstioTLSModeMatch(match *cluster.Cluster_TransportSocketMatch) bool {
+	return match.GetMatch().GetFields()[model.TLSModeLabelShortname].GetStringValue() == model.IstioMutualTLSModeLabel
+}
+
+// describeUpstreamTransportSocket returns the TLS mode of a cluster transport socket. Istio mTLS is recognized by
+// the workload certificate or root certificate Istio serves over SDS, or by the ALPN Istio sets for it.
+func describeUpstreamTransportSocket(transportSocket *core.TransportSocket) string {
+	if !isUpstreamTLSTransportSocket(transportSocket) {
+		return clusterTLSModeDisable
+	}
+	tlsContext, err := retrieveUpstreamTLSContext(transportSocket)
+	if err != nil {
+		return clusterTLSModeSimple
+	}
+	commonTLSContext := tlsContext.GetCommonTlsContext()
+	for _, sdsConfig := range commonTLSContext.GetTlsCertificateSdsSecretConfigs() {
+		if sdsConfig.GetName() == authn_model.SDSDefaultResourceName {
+			return clusterTLSModeIstioMutual
+		}
+	}
+	if commonTLSContext.GetValidationContextSdsSecretConfig().GetName() == authn_model.SDSRootResourceName ||
+		commonTLSContext.GetCombinedValidationContext().GetValidationContextSdsSecretConfig().GetName() == authn_model.SDSRootResourceName {
+		return clusterTLSModeIstioMutual
+	}
+	for _, alpn := range commonTLSContext.GetAlpnProtocols() {
+		if alpn == "istio" {
+			return clusterTLSModeIstioMutual
+		}
+	}
+	if len(commonTLSContext.GetTlsCertificates()) > 0 || len(commonTLSContext.GetTlsCertificateSdsSecretConfigs()) > 0 {
+		return clusterTLSModeMutual
+	}
+	return clusterTLSModeSimple
+}
+
+func retrieveUpstreamTLSContext(transportSocket *core.TransportSocket) (*tls.UpstreamTlsContext, error) {
+	tlsContext := &tls.UpstreamTlsContext{}
+	if err := unmarshalAnyAs(upstreamTLSContextTypeURL, transportSocket.GetTypedConfig(), tlsContext); err != nil {
+		return nil, err
+	}
+	return tlsContext, nil
+}
+
+// PrintClusterTLS prints the upstream TLS settings of the relevant clusters in the config dump to the ConfigWriter
+// stdout: the SNI, the subject alt names the upstream certificate must match, the SDS secrets or files of the client
+// and root certificates, and whether a client certificate is sent at all, to track down the TLS handshake errors a
+// wrong DestinationRule TLS setting causes. Clusters with transport socket matches, like auto mTLS clusters, get one
+// row per match with its criteria.
+func (c *ConfigWriter) PrintClusterTLS(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tMATCH\tMODE\tSNI\tSUBJECT ALT NAMES\tCERTIFICATE\tROOT CA\tCLIENT CERT")
+	for _, cl := range clusters {
+		if len(cl.GetTransportSocketMatches()) == 0 {
+			_, _ = fmt.Fprintf(w, "%v\t-\t%v\n", cl.Name, strings.Join(describeUpstreamTLS(cl.GetTransportSocket()), "\t"))
+			continue
+		}
+		for _, match := range cl.GetTransportSocketMatches() {
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\n", cl.Name, formatTransportSocketMatch(match),
+				strings.Join(describeUpstreamTLS(match.GetTransportSocket()), "\t"))
+		}
+	}
+	return w.Flush()
+}
+
+// describeUpstreamTLS renders the TLS mode, SNI, subject alt names, client certificate, root certificate and whether
+// a client certificate is configured of a cluster transport socket, with - for the settings plaintext sockets lack
+// and ? for the settings of TLS sockets whose config could not be read
+func describeUpstreamTLS(transportSocket *core.TransportSocket) []string {
+	mode := describeUpstreamTransportSocket(transportSocket)
+	if mode == clusterTLSModeDisable {
+		return []string{mode, "-", "-", "-", "-", "no"}
+	}
+	tlsContext, err := retrieveUpstreamTLSContext(transportSocket)
+	if err != nil {
+		return []string{mode, "?", "?", "?", "?", "?"}
+	}
+	commonTLSContext := tlsContext.GetCommonTlsContext()
+	sni := tlsContext.GetSni()
+	if sni == "" {
+		sni = "-"
+	}
+	clientCertificate := "no"
+	if len(commonTLSContext.GetTlsCertificates()) > 0 || len(commonTLSContext.GetTlsCertificateSdsSecretConfigs()) > 0 {
+		clientCertificate = "yes"
+	}
+	return []string{mode, sni, formatSubjectAltNames(commonTLSContext), formatClientCertificates(commonTLSContext),
+		formatRootCertificate(commonTLSContext), clientCertificate}
+}
+
+// retrieveValidationContext returns the inline validation context of a TLS context, or the default validation
+// context combined with the one served over SDS
+func retrieveValidationContext(commonTLSContext *tls.CommonTlsContext) *tls.CertificateValidationContext {
+	if validationContext := commonTLSContext.GetValidationContext(); validationContext != nil {
+		return validationContext
+	}
+	return commonTLSContext.GetCombinedValidationContext().GetDefaultValidationContext()
+}
+
+// formatSubjectAltNames renders the subject alt names matchers the upstream certificate is verified against, or -
+// when any certificate the root certificate signed is accepted
+func formatSubjectAltNames(commonTLSContext *tls.CommonTlsContext) string {
+	matchers := retrieveValidationContext(commonTLSContext).GetMatchSubjectAltNames()
+	if len(matchers) == 0 {
+		return "-"
+	}
+	subjectAltNames := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		subjectAltNames = append(subjectAltNames, formatStringMatcher(m))
+	}
+	return strings.Join(subjectAltNames, ",")
+}
+
+// formatStringMatcher renders a string matcher by its value, prefixed with the kind of match unless it is exact
+func formatStringMatcher(m *matcher.StringMatcher) string {
+	switch {
+	case m.GetPrefix() != "":
+		return "prefix:" + m.GetPrefix()
+	case m.GetSuffix() != "":
+		return "suffix:" + m.GetSuffix()
+	case m.GetSafeRegex() != nil:
+		return "regex:" + m.GetSafeRegex().GetRegex()
+	}
+	return m.GetExact()
+}
+
+// formatClientCertificates renders the SDS secret names of the client certificates of a TLS context, like default
+// for Istio mTLS, or the files of the certificate chains configured inline, or - when there are none
+func formatClientCertificates(commonTLSContext *tls.CommonTlsContext) string {
+	certificates := make([]string, 0)
+	for _, sdsConfig := range commonTLSContext.GetTlsCertificateSdsSecretConfigs() {
+		certificates = append(certificates, sdsConfig.GetName())
+	}
+	for _, certificate := range commonTLSContext.GetTlsCertificates() {
+		certificates = append(certificates, formatDataSource(certificate.GetCertificateChain()))
+	}
+	if len(certificates) == 0 {
+		return "-"
+	}
+	return strings.Join(certificates, ",")
+}
+
+// formatRootCertificate renders the SDS secret name of the root certificate of a TLS context, like ROOTCA for Istio
+// mTLS, or the file of the trusted CA configured inline, or - when the upstream certificate is not verified
+func formatRootCertificate(commonTLSContext *tls.CommonTlsContext) string {
+	if sdsConfig := commonTLSContext.GetValidationContextSdsSecretConfig(); sdsConfig != nil {
+		return sdsConfig.GetName()
+	}
+	if sdsConfig := commonTLSContext.GetCombinedValidationContext().GetValidationContextSdsSecretConfig(); sdsConfig != nil {
+		return sdsConfig.GetName()
+	}
+	if trustedCA := retrieveValidationContext(commonTLSContext).GetTrustedCa(); trustedCA != nil {
+		return formatDataSource(trustedCA)
+	}
+	return "-"
+}
+
+// formatDataSource renders a certificate data source as file:<path>, or inline when the certificate is in the config
+func formatDataSource(dataSource *core.DataSource) string {
+	if filename := dataSource.GetFilename(); filename != "" {
+		return "file:" + filename
+	}
+	return "inline"
+}
+
+// formatTransportSocketMatch renders the endpoint metadata criteria of a transport socket match like tlsMode=istio,
+// sorted by key, or * for the match of all endpoints
+func formatTransportSocketMatch(match *cluster.Cluster_TransportSocketMatch) string {
+	fields := match.GetMatch().GetFields()
+	if len(fields) == 0 {
+		return "*"
+	}
+	criteria := make([]string, 0, len(fields))
+	for key, value := range fields {
+		criteria = append(criteria, key+"="+formatStructValue(value))
+	}
+	sort.Strings(criteria)
+	return strings.Join(criteria, ",")
+}
+
+// formatStructValue renders a string, number or bool metadata value, or ? for the other kinds
+func formatStructValue(value *structpb.Value) string {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(kind.NumberValue, 'f', -1, 64)
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(kind.BoolValue)
+	}
+	return "?"
+}
+
+func isUpstreamTLSTransportSocket(transportSocket *core.TransportSocket) bool {
+	if transportSocket == nil {
+		return false
+	}
+	switch transportSocket.GetName() {
+	case util.EnvoyTLSSocketName, "tls":
+		return true
+	}
+	return transportSocket.GetTypedConfig().GetTypeUrl() == upstreamTLSContextTypeURL
+}
+
+// retrieveClusterEndpoints counts the healthy and total endpoints of the cluster in the clusters admin output when
+// the ConfigWriter has been primed with it, see PrimeClusters, else in the EDS section of the config dump for EDS
+// clusters, see parseEndpointsConfigDump, and else in the load assignment of the cluster.
+// ok is false when none has the endpoints of the cluster, as for EDS and ORIGINAL_DST clusters without a load
+// assignment, whose endpoints Envoy discovers at runtime.
+func (c *ConfigWriter) retrieveClusterEndpoints(cl *cluster.Cluster) (healthy, total int, ok bool) {
+	if clusterStatus, found := c.clusterStatuses[cl.Name]; found {
+		for _, hostStatus := range clusterStatus.GetHostStatuses() {
+			total++
+			if !isUnhealthyHost(hostStatus) {
+				healthy++
+			}
+		}
+		return healthy, total, true
+	}
+	if loadAssignment := c.retrieveEDSLoadAssignment(cl); loadAssignment != nil {
+		healthy, total = retrieveLoadAssignmentEndpoints(loadAssignment)
+		return healthy, total, true
+	}
+	if cl.GetLoadAssignment() == nil && (cl.GetType() == cluster.Cluster_EDS || cl.GetType() == cluster.Cluster_ORIGINAL_DST) {
+		return 0, 0, false
+	}
+	healthy, total = retrieveLoadAssignmentEndpoints(cl.GetLoadAssignment())
+	return healthy, total, true
+}
+
+// retrieveLoadAssignmentEndpoints counts the healthy and total endpoints of the load assignment.
+// Envoy load balances to endpoints of unknown health, so they are counted as healthy.
+func retrieveLoadAssignmentEndpoints(loadAssignment *endpoint.ClusterLoadAssignment) (healthy, total int) {
+	for _, localityEndpoints := range loadAssignment.GetEndpoints() {
+		for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+			total++
+			switch lbEndpoint.GetHealthStatus() {
+			case core.HealthStatus_UNKNOWN, core.HealthStatus_HEALTHY:
+				healthy++
+			}
+		}
+	}
+	return healthy, total
+}
+
+// isUnhealthyHost returns true if Envoy does not send traffic to the host of the clusters admin output, because of
+// its EDS health status or because it failed active health checking or outlier detection
+func isUnhealthyHost(hostStatus *adminapi.HostStatus) bool {
+	health := hostStatus.GetHealthStatus()
+	switch health.GetEdsHealthStatus() {
+	case core.HealthStatus_UNHEALTHY, core.HealthStatus_DRAINING, core.HealthStatus_TIMEOUT:
+		return true
+	}
+	return health.GetFailedActiveHealthCheck() || health.GetFailedOutlierCheck()
+}
+
+// formatClusterEndpoints describes the endpoints of the cluster as healthy/total, 0 when it has none, or ? when
+// they are not known, see retrieveClusterEndpoints
+func (c *ConfigWriter) formatClusterEndpoints(cl *cluster.Cluster) string {
+	healthy, total, ok := c.retrieveClusterEndpoints(cl)
+	if !ok {
+		return "?"
+	}
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%d", healthy, total)
+}
+
+// endpointPriority totals the endpoints of the localities of a cluster with the same priority
+type endpointPriority struct {
+	priority   uint32
+	localities int
+	endpoints  int
+	weight     uint32
+}
+
+// PrintClusterEndpointPriorities prints the number of localities and endpoints and the total endpoint weight of each
+// priority of the endpoints of the relevant clusters in the config dump to the ConfigWriter stdout, one row per
+// cluster and priority, to audit locality failover. The endpoints are read like retrieveClusterEndpoints does, so
+// EDS clusters are left out unless the ConfigWriter has been primed with the clusters admin output, see
+// PrimeClusters, or the config dump has an EDS section.
+func (c *ConfigWriter) PrintClusterEndpointPriorities(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tPRIORITY\tLOCALITIES\tENDPOINTS\tWEIGHT")
+	for _, cl := range clusters {
+		for _, p := range c.retrieveClusterEndpointPriorities(cl) {
+			_, _ = fmt.Fprintf(w, "%v\t%d\t%d\t%d\t%d\n", cl.Name, p.priority, p.localities, p.endpoints, p.weight)
+		}
+	}
+	return w.Flush()
+}
+
+// retrieveClusterEndpointPriorities groups the endpoints of the cluster by priority from the clusters admin output,
+// the EDS section of the config dump or the load assignment of the cluster, in the order retrieveClusterEndpoints
+// reads them
+func (c *ConfigWriter) retrieveClusterEndpointPriorities(cl *cluster.Cluster) []endpointPriority {
+	if clusterStatus, found := c.clusterStatuses[cl.Name]; found {
+		return retrieveHostStatusPriorities(clusterStatus)
+	}
+	if loadAssignment := c.retrieveEDSLoadAssignment(cl); loadAssignment != nil {
+		return retrieveEndpointPriorities(loadAssignment)
+	}
+	return retrieveEndpointPriorities(cl.GetLoadAssignment())
+}
+
+// retrieveEndpointPriorities groups the locality endpoints of the load assignment by priority, sorted from the
+// highest priority 0. Endpoints without a load balancing weight are weighted 1, like Envoy does.
+func retrieveEndpointPriorities(loadAssignment *endpoint.ClusterLoadAssignment) []endpointPriority {
+	byPriority := map[uint32]*endpointPriority{}
+	for _, localityEndpoints := range loadAssignment.GetEndpoints() {
+		p, ok := byPriority[localityEndpoints.GetPriority()]
+		if !ok {
+			p = &endpointPriority{priority: localityEndpoints.GetPriority()}
+			byPriority[p.priority] = p
+		}
+		p.localities++
+		for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+			p.endpoints++
+			if weight := lbEndpoint.GetLoadBalancingWeight(); weight != nil {
+				p.weight += weight.GetValue()
 			} else {
-				_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%s\n", c.Name, "-", "-", "-", c.GetType())
+				p.weight++
 			}
 		}
 	}
+	return sortEndpointPriorities(byPriority)
+}
+
+// retrieveHostStatusPriorities groups the hosts of the cluster of the clusters admin output by priority, sorted from
+// the highest priority 0, counting the distinct localities of each priority. The weight of the hosts is the one
+// Envoy load balances with.
+func retrieveHostStatusPriorities(clusterStatus *adminapi.ClusterStatus) []endpointPriority {
+	byPriority := map[uint32]*endpointPriority{}
+	localities := map[uint32]map[string]bool{}
+	for _, hostStatus := range clusterStatus.GetHostStatuses() {
+		p, ok := byPriority[hostStatus.GetPriority()]
+		if !ok {
+			p = &endpointPriority{priority: hostStatus.GetPriority()}
+			byPriority[p.priority] = p
+			localities[p.priority] = map[string]bool{}
+		}
+		locality := hostStatus.GetLocality()
+		localities[p.priority][fmt.Sprintf("%s/%s/%s", locality.GetRegion(), locality.GetZone(), locality.GetSubZone())] = true
+		p.localities = len(localities[p.priority])
+		p.endpoints++
+		p.weight += hostStatus.GetWeight()
+	}
+	return sortEndpointPriorities(byPriority)
+}
+
+func sortEndpointPriorities(byPriority map[uint32]*endpointPriority) []endpointPriority {
+	priorities := make([]endpointPriority, 0, len(byPriority))
+	for _, p := range byPriority {
+		priorities = append(priorities, *p)
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i].priority < priorities[j].priority
+	})
+	return priorities
+}
+
+// PrintClusterProtocolOptions prints the upstream HTTP protocol and connection pool options of the relevant
+// clusters in the config dump to the ConfigWriter stdout, showing whether requests are sent upstream over
+// HTTP/2, over the protocol of the downstream request, or over HTTP/1.1 which Envoy uses by default
+func (c *ConfigWriter) PrintClusterProtocolOptions(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tPROTOCOL\tMAX REQUESTS PER CONNECTION\tIDLE TIMEOUT")
+	for _, cl := range clusters {
+		maxRequests := "-"
+		if cl.GetMaxRequestsPerConnection() != nil {
+			maxRequests = strconv.Itoa(int(cl.GetMaxRequestsPerConnection().GetValue()))
+		}
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", cl.Name, c.retrieveClusterUpstreamProtocol(cl), maxRequests,
+			c.retrieveClusterIdleTimeout(cl))
+	}
+	return w.Flush()
+}
+
+// retrieveClusterUpstreamProtocol returns the HTTP protocol a cluster uses for upstream requests, from its HTTP
+// protocol options typed extension when it has one, see parseHTTPProtocolOptions, or else from the legacy
+// http2_protocol_options and protocol_selection fields
+func (c *ConfigWriter) retrieveClusterUpstreamProtocol(cl *cluster.Cluster) string {
+	if protocol := c.httpProtocolOptions[cl.Name].protocol(); protocol != "" {
+		return protocol
+	}
+	return retrieveUpstreamProtocol(cl)
+}
+
+// retrieveClusterIdleTimeout returns the idle timeout of the upstream connections of a cluster, from its HTTP
+// protocol options typed extension or else from the legacy common_http_protocol_options, or - when it has none
+func (c *ConfigWriter) retrieveClusterIdleTimeout(cl *cluster.Cluster) string {
+	if timeout, ok := c.httpProtocolOptions[cl.Name].idleTimeout(); ok {
+		return timeout.String()
+	}
+	if timeout, err := ptypes.Duration(cl.GetCommonHttpProtocolOptions().GetIdleTimeout()); err == nil {
+		return timeout.String()
+	}
+	return "-"
+}
+
+// retrieveUpstreamProtocol returns the HTTP protocol a cluster uses for upstream requests by its legacy fields.
+// Clusters configured by an HTTP protocol options typed extension the ConfigWriter could not read from the config
+// dump JSON, whose config is dropped when the config dump is unmarshalled, are shown with a "?".
+func retrieveUpstreamProtocol(c *cluster.Cluster) string {
+	switch {
+	case c.GetProtocolSelection() == cluster.Cluster_USE_DOWNSTREAM_PROTOCOL:
+		return upstreamProtocolDownstream
+	case c.GetHttp2ProtocolOptions() != nil:
+		return upstreamProtocolExplicitHTTP2
+	}
+	if _, ok := c.GetTypedExtensionProtocolOptions()[httpProtocolOptionsExtension]; ok {
+		return "?"
+	}
+	return upstreamProtocolDefault
+}
+
+// PrintClusterOutlierDetection prints the outlier detection settings of the relevant clusters in the config dump
+// to the ConfigWriter stdout. Settings the cluster leaves unset are shown with the values Envoy fills in, marked
+// (default), and clusters without outlier detection are listed as disabled.
+func (c *ConfigWriter) PrintClusterOutlierDetection(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tOUTLIER DETECTION\tCONSECUTIVE 5XX\tINTERVAL\tBASE EJECTION TIME\tMAX EJECTION %")
+	for _, c := range clusters {
+		state := "ENABLED"
+		if c.GetOutlierDetection() == nil {
+			state = "DISABLED"
+		}
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\n", c.Name, state, strings.Join(formatOutlierDetection(c.GetOutlierDetection()), "\t"))
+	}
+	return w.Flush()
+}
+
+// formatOutlierDetection renders the consecutive 5xx, interval, base ejection time and max ejection percent
+// settings of the outlier detection of a cluster, or - for each when outlier detection is disabled
+func formatOutlierDetection(outlierDetection *cluster.OutlierDetection) []string {
+	if outlierDetection == nil {
+		return []string{"-", "-", "-", "-"}
+	}
+	return []string{
+		formatThreshold(outlierDetection.GetConsecutive_5Xx(), defaultConsecutive5xx),
+		formatDurationThreshold(outlierDetection.GetInterval(), defaultOutlierInterval),
+		formatDurationThreshold(outlierDetection.GetBaseEjectionTime(), defaultBaseEjectionTime),
+		formatThreshold(outlierDetection.GetMaxEjectionPercent(), defaultMaxEjectionPercent),
+	}
+}
+
+// durationValue returns the value of a duration setting, or the Envoy default when it is unset
+func durationValue(value *duration.Duration, defaultValue time.Duration) time.Duration {
+	d, err := ptypes.Duration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// PrintClusterCircuitBreakers prints the circuit breaker thresholds and the outlier detection settings of the relevant
+// clusters in the config dump to the ConfigWriter stdout, one row per cluster and priority, to check that the
+// connection pool and outlier detection of a DestinationRule landed on the proxy. Settings the cluster leaves unset
+// are shown with the values Envoy fills in, marked (default). The DEFAULT and HIGH priorities are always shown since
+// Envoy applies its thresholds to both.
+func (c *ConfigWriter) PrintClusterCircuitBreakers(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tPRIORITY\tMAX CONNECTIONS\tMAX PENDING REQUESTS\tMAX REQUESTS\tMAX RETRIES\t"+
+		"CONSECUTIVE 5XX\tINTERVAL\tBASE EJECTION TIME\tMAX EJECTION %")
+	for _, c := range clusters {
+		outlierDetection := strings.Join(formatOutlierDetection(c.GetOutlierDetection()), "\t")
+		for _, thresholds := range retrieveCircuitBreakerThresholds(c) {
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", c.Name, thresholds.GetPriority(),
+				formatThreshold(thresholds.GetMaxConnections(), defaultMaxConnections),
+				formatThreshold(thresholds.GetMaxPendingRequests(), defaultMaxPendingRequests),
+				formatThreshold(thresholds.GetMaxRequests(), defaultMaxRequests),
+				formatThreshold(thresholds.GetMaxRetries(), defaultMaxRetries),
+				outlierDetection)
+		}
+	}
 	return w.Flush()
 }
 
-// PrintClusterDump prints the relevant clusters in the config dump to the ConfigWriter stdout
+// PrintClusterHealthChecks prints the active health checks of the relevant clusters in the config dump to the
+// ConfigWriter stdout, one row per cluster and health check, to confirm the health checks an EnvoyFilter adds landed
+// on the proxy. Clusters without health checks get a single row with the type none. The healthy panic threshold of
+// each cluster is shown too, since once the share of healthy endpoints drops below it Envoy ignores both the health
+// checks and outlier detection and balances over all the endpoints.
+func (c *ConfigWriter) PrintClusterHealthChecks(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tPATH\tINTERVAL\tTIMEOUT\tHEALTHY THRESHOLD\tUNHEALTHY THRESHOLD\tPANIC THRESHOLD")
+	for _, cl := range clusters {
+		panicThreshold := formatHealthyPanicThreshold(cl)
+		if len(cl.GetHealthChecks()) == 0 {
+			_, _ = fmt.Fprintf(w, "%v\tnone\t-\t-\t-\t-\t-\t%v\n", cl.Name, panicThreshold)
+			continue
+		}
+		for _, healthCheck := range cl.GetHealthChecks() {
+			healthCheckType, path := describeHealthChecker(healthCheck)
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", cl.Name, healthCheckType, path,
+				formatHealthCheckDuration(healthCheck.GetInterval()), formatHealthCheckDuration(healthCheck.GetTimeout()),
+				formatHealthCheckThreshold(healthCheck.GetHealthyThreshold()),
+				formatHealthCheckThreshold(healthCheck.GetUnhealthyThreshold()), panicThreshold)
+		}
+	}
+	return w.Flush()
+}
+
+// PrintClusterDNS prints the DNS settings of the relevant STRICT_DNS and LOGICAL_DNS clusters in the config dump to
+// the ConfigWriter stdout, along with the hostnames of their load assignment Envoy resolves, to see how the proxy
+// resolves an external service, like over IPv6 first with the AUTO lookup family. Clusters of other types, like EDS
+// clusters, are left out.
+func (c *ConfigWriter) PrintClusterDNS(filter ClusterFilter) error {
+	w, clusters, err := c.setupClusterConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tLOOKUP FAMILY\tREFRESH RATE\tRESPECT TTL\tHOSTNAMES")
+	for _, cl := range clusters {
+		if !isDNSCluster(cl) {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", cl.Name, cl.GetType(), cl.GetDnsLookupFamily(),
+			formatDurationThreshold(cl.GetDnsRefreshRate(), defaultDNSRefreshRate), cl.GetRespectDnsTtl(),
+			strings.Join(retrieveDNSHostnames(cl), ","))
+	}
+	return w.Flush()
+}
+
+// isDNSCluster returns true if Envoy resolves the endpoints of the cluster with DNS
+func isDNSCluster(c *cluster.Cluster) bool {
+	if c.GetClusterType() != nil {
+		return false
+	}
+	return c.GetType() == cluster.Cluster_STRICT_DNS || c.GetType() == cluster.Cluster_LOGICAL_DNS
+}
+
+// retrieveDNSHostnames returns the hostname and port of each endpoint of the load assignment of a DNS cluster, in
+// the order of the load assignment, or - when it has none
+func retrieveDNSHostnames(c *cluster.Cluster) []string {
+	hostnames := make([]string, 0)
+	for _, localityEndpoints := range c.GetLoadAssignment().GetEndpoints() {
+		for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+			address := lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress()
+			if address == nil {
+				continue
+			}
+			hostnames = append(hostnames, net.JoinHostPort(address.GetAddress(), strconv.Itoa(int(address.GetPortValue()))))
+		}
+	}
+	if len(hostnames) == 0 {
+		return []string{"-"}
+	}
+	return hostnames
+}
+
+// describeHealthChecker returns the type of a health check, HTTP, TCP, gRPC or the name of a custom health
+// checker, and what it checks: the path of HTTP health checks and the service name of gRPC ones, or - otherwise
+func describeHealthChecker(healthCheck *core.HealthCheck) (string, string) {
+	healthCheckType, checked := "UNKNOWN", ""
+	switch {
+	case healthCheck.GetHttpHealthCheck() != nil:
+		healthCheckType, checked = "HTTP", healthCheck.GetHttpHealthCheck().GetPath()
+	case healthCheck.GetTcpHealthCheck() != nil:
+		healthCheckType = "TCP"
+	case healthCheck.GetGrpcHealthCheck() != nil:
+		healthCheckType, checked = "gRPC", healthCheck.GetGrpcHealthCheck().GetServiceName()
+	case healthCheck.GetCustomHealthCheck() != nil:
+		healthCheckType = healthCheck.GetCustomHealthCheck().GetName()
+	}
+	if checked == "" {
+		checked = "-"
+	}
+	return healthCheckType, checked
+}
+
+// formatHealthyPanicThreshold renders the healthy panic threshold of a cluster as a percentage, or the Envoy
+// default marked (default) when it is unset. A threshold of 0% disables the panic mode.
+func formatHealthyPanicThreshold(c *cluster.Cluster) string {
+	threshold := c.GetCommonLbConfig().GetHealthyPanicThreshold()
+	if threshold == nil {
+		return strconv.Itoa(defaultHealthyPanicThreshold) + "%" + envoyDefaultSuffix
+	}
+	return strconv.FormatFloat(threshold.GetValue(), 'f', -1, 64) + "%"
+}
+
+// formatHealthCheckDuration renders the interval or timeout of a health check, or - when it is unset
+func formatHealthCheckDuration(value *duration.Duration) string {
+	d, err := ptypes.Duration(value)
+	if err != nil {
+		return "-"
+	}
+	return d.String()
+}
+
+// formatHealthCheckThreshold renders the healthy or unhealthy threshold of a health check, or - when it is unset
+func formatHealthCheckThreshold(value *wrappers.UInt32Value) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatUint(uint64(value.GetValue()), 10)
+}
+
+// retrieveCircuitBreakerThresholds returns the circuit breaker thresholds of the cluster sorted by priority,
+// adding empty thresholds for the DEFAULT and HIGH priorities when the cluster does not configure them
+func retrieveCircuitBreakerThresholds(c *cluster.Cluster) []*cluster.CircuitBreakers_Thresholds {
+	thresholds := append([]*cluster.CircuitBreakers_Thresholds{}, c.GetCircuitBreakers().GetThresholds()...)
+	configured := map[core.RoutingPriority]bool{}
+	for _, t := range thresholds {
+		configured[t.GetPriority()] = true
+	}
+	for _, priority := range []core.RoutingPriority{core.RoutingPriority_DEFAULT, core.RoutingPriority_HIGH} {
+		if !configured[priority] {
+			thresholds = append(thresholds, &cluster.CircuitBreakers_Thresholds{Priority: priority})
+		}
+	}
+	sort.SliceStable(thresholds, func(i, j int) bool {
+		return thresholds[i].GetPriority() < thresholds[j].GetPriority()
+	})
+	return thresholds
+}
+
+// hasNonDefaultCircuitBreakers returns true if any circuit breaker threshold of the cluster differs from the Envoy default
+func hasNonDefaultCircuitBreakers(c *cluster.Cluster) bool {
+	for _, t := range c.GetCircuitBreakers().GetThresholds() {
+		if thresholdValue(t.GetMaxConnections(), defaultMaxConnections) != defaultMaxConnections ||
+			thresholdValue(t.GetMaxPendingRequests(), defaultMaxPendingRequests) != defaultMaxPendingRequests ||
+			thresholdValue(t.GetMaxRequests(), defaultMaxRequests) != defaultMaxRequests ||
+			thresholdValue(t.GetMaxRetries(), defaultMaxRetries) != defaultMaxRetries {
+			return true
+		}
+	}
+	return false
+}
+
+// thresholdValue returns the value of a circuit breaker threshold, or the Envoy default when it is unset
+func thresholdValue(value *wrappers.UInt32Value, defaultValue uint32) uint32 {
+	if value == nil {
+		return defaultValue
+	}
+	return value.GetValue()
+}
+
+// formatThreshold renders the value of a circuit breaker threshold or outlier detection setting, or the Envoy
+// default marked (default) when it is unset, so defaults can be told apart from values Istio set explicitly
+func formatThreshold(value *wrappers.UInt32Value, defaultValue uint32) string {
+	if value == nil {
+		return strconv.FormatUint(uint64(defaultValue), 10) + envoyDefaultSuffix
+	}
+	return strconv.FormatUint(uint64(value.GetValue()), 10)
+}
+
+// formatDurationThreshold renders a duration setting like formatThreshold
+func formatDurationThreshold(value *duration.Duration, defaultValue time.Duration) string {
+	if value == nil {
+		return defaultValue.String() + envoyDefaultSuffix
+	}
+	return durationValue(value, defaultValue).String()
+}
+
+// PrintClusterDump prints the relevant clusters in the config dump to the ConfigWriter stdout, as JSON or YAML
+// depending on the output format
 func (c *ConfigWriter) PrintClusterDump(filter ClusterFilter) error {
-	_, clusters, err := c.setupClusterConfigWriter()
+	_, clusters, err := c.setupClusterConfigWriter(filter)
 	if err != nil {
 		return err
 	}
 	filteredClusters := protio.MessageSlice{}
 	for _, cluster := range clusters {
-		if filter.Verify(cluster) {
-			filteredClusters = append(filteredClusters, cluster)
-		}
+		filteredClusters = append(filteredClusters, cluster)
 	}
-	out, err := json.MarshalIndent(filteredClusters, "", "    ")
+	return c.printMessages(filteredClusters)
+}
+
+// PrintClusterNames prints the names of the relevant clusters in the config dump to the ConfigWriter stdout, one per
+// line, for scripts
+func (c *ConfigWriter) PrintClusterNames(filter ClusterFilter) error {
+	clusters, err := c.GetClusters(filter)
 	if err != nil {
 		return err
 	}
-	_, _ = fmt.Fprintln(c.Stdout, string(out))
+	for _, cluster := range clusters {
+		fmt.Fprintln(c.Stdout, cluster.Name)
+	}
 	return nil
 }
 
-func (c *ConfigWriter) setupClusterConfigWriter() (*tabwriter.Writer, []*cluster.Cluster, error) {
-	clusters, err := c.retrieveSortedClusterSlice()
+// GetClusters returns the clusters in the config dump matching the filter, sorted by service, port, subset and direction
+// unless the filter sorts them otherwise. Warming clusters are included, after the active cluster of the same name.
+func (c *ConfigWriter) GetClusters(filter ClusterFilter) ([]*cluster.Cluster, error) {
+	clusters, err := c.retrieveFilteredClusterSlice(filter)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapClusters(clusters), nil
+}
+
+// DiffClusters prints how the clusters matching the filter changed from the config dump of the ConfigWriter to
+// the config dump of the other one: clusters only found in the other config dump are added, clusters missing
+// from it are removed, and the clusters with a different config are changed. Clusters are compared by name,
+// using their active state over their warming state, so the order of the config dumps and their update times
+// and versions are ignored.
+func (c *ConfigWriter) DiffClusters(other *ConfigWriter, filter ClusterFilter) error {
+	from, err := c.retrieveClustersByName(filter)
+	if err != nil {
+		return err
+	}
+	to, err := other.retrieveClustersByName(filter)
+	if err != nil {
+		return err
+	}
+	diffs, err := diffResources(from, to)
+	if err != nil {
+		return err
+	}
+	return c.printResourceDiffs("cluster", diffs)
+}
+
+func (c *ConfigWriter) retrieveClustersByName(filter ClusterFilter) (map[string]proto.Message, error) {
+	clusters, err := c.retrieveFilteredClusterSlice(filter)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]proto.Message, len(clusters))
+	for _, cluster := range clusters {
+		if _, ok := byName[cluster.Name]; !ok {
+			byName[cluster.Name] = cluster.Cluster
+		}
+	}
+	return byName, nil
+}
+
+// retrieveLoadAssignmentsByName returns the load assignments of the clusters by cluster name, those of the EDS
+// section of the config dump for EDS clusters, see GetClusterLoadAssignments
+func (c *ConfigWriter) retrieveLoadAssignmentsByName(filter ClusterFilter) (map[string]proto.Message, error) {
+	loadAssignments, err := c.GetClusterLoadAssignments(filter)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]proto.Message, len(loadAssignments))
+	for name, loadAssignment := range loadAssignments {
+		byName[name] = loadAssignment
+	}
+	return byName, nil
+}
+
+func (c *ConfigWriter) retrieveFilteredClusterSlice(filter ClusterFilter) ([]*clusterWithState, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	clusters, err := c.retrieveSortedClusterStates()
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]*clusterWithState, 0, len(clusters))
+	for _, cluster := range clusters {
+		if filter.Verify(cluster.Cluster) {
+			filtered = append(filtered, cluster)
+		}
+	}
+	if filter.SortBy != "" && filter.SortBy != sortByFQDN {
+		sortClusters(filtered, filter.SortBy)
+	}
+	return filtered, nil
+}
+
+func unwrapClusters(clusters []*clusterWithState) []*cluster.Cluster {
+	unwrapped := make([]*cluster.Cluster, 0, len(clusters))
+	for _, c := range clusters {
+		unwrapped = append(unwrapped, c.Cluster)
+	}
+	return unwrapped
+}
+
+// retrieveClusterLastUpdated returns when a dynamic cluster was last updated. Like static listeners, static clusters
+// are never updated, so they have no update time.
+func retrieveClusterLastUpdated(c *clusterWithState) *time.Time {
+	if c.state == clusterStateStatic || c.lastUpdated == nil {
+		return nil
+	}
+	lastUpdated, err := ptypes.Timestamp(c.lastUpdated)
+	if err != nil {
+		return nil
+	}
+	return &lastUpdated
+}
+
+func (c *ConfigWriter) setupClusterConfigWriter(filter ClusterFilter) (*tabwriter.Writer, []*cluster.Cluster, error) {
+	clusters, err := c.GetClusters(filter)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -115,6 +1231,19 @@ func (c *ConfigWriter) setupClusterConfigWriter() (*tabwriter.Writer, []*cluster
 }
 
 func (c *ConfigWriter) retrieveSortedClusterSlice() ([]*cluster.Cluster, error) {
+	clusters, err := c.retrieveSortedClusterStates()
+	if err != nil {
+		return nil, err
+	}
+	return unwrapClusters(clusters), nil
+}
+
+// errNoClusters is returned when the cluster section of the config dump has no clusters
+var errNoClusters = errors.New("no clusters found")
+
+// retrieveSortedClusterStates returns the active, warming and static clusters of the config dump. A config dump
+// with only warming clusters is not empty, such clusters are what is left when their endpoints never arrive.
+func (c *ConfigWriter) retrieveSortedClusterStates() ([]*clusterWithState, error) {
 	if c.configDump == nil {
 		return nil, fmt.Errorf("config writer has not been primed")
 	}
@@ -122,49 +1251,107 @@ func (c *ConfigWriter) retrieveSortedClusterSlice() ([]*cluster.Cluster, error)
 	if err != nil {
 		return nil, err
 	}
-	clusters := make([]*cluster.Cluster, 0)
-	for _, c := range clusterDump.DynamicActiveClusters {
-		if c.Cluster != nil {
-			clusterTyped := &cluster.Cluster{}
-			// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
-			c.Cluster.TypeUrl = v3.ClusterType
-			err = ptypes.UnmarshalAny(c.Cluster, clusterTyped)
+	clusters := make([]*clusterWithState, 0)
+	dynamicClusters := []struct {
+		state    string
+		clusters []*adminapi.ClustersConfigDump_DynamicCluster
+	}{
+		{clusterStateActive, clusterDump.DynamicActiveClusters},
+		{clusterStateWarming, clusterDump.DynamicWarmingClusters},
+	}
+	for _, d := range dynamicClusters {
+		for _, c := range d.clusters {
+			if c.Cluster == nil {
+				continue
+			}
+			clusterTyped, err := unmarshalCluster(c.Cluster)
 			if err != nil {
 				return nil, err
 			}
-			clusters = append(clusters, clusterTyped)
+			clusters = append(clusters, &clusterWithState{Cluster: clusterTyped, state: d.state, lastUpdated: c.LastUpdated})
 		}
 	}
 	for _, c := range clusterDump.StaticClusters {
 		if c.Cluster != nil {
-			clusterTyped := &cluster.Cluster{}
-			// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
-			c.Cluster.TypeUrl = v3.ClusterType
-			err = ptypes.UnmarshalAny(c.Cluster, clusterTyped)
+			clusterTyped, err := unmarshalCluster(c.Cluster)
 			if err != nil {
 				return nil, err
 			}
-			clusters = append(clusters, clusterTyped)
+			clusters = append(clusters, &clusterWithState{Cluster: clusterTyped, state: clusterStateStatic, lastUpdated: c.LastUpdated})
 		}
 	}
 	if len(clusters) == 0 {
-		return nil, fmt.Errorf("no clusters found")
-	}
-	sort.Slice(clusters, func(i, j int) bool {
-		iDirection, iSubset, iName, iPort := safelyParseSubsetKey(clusters[i].Name)
-		jDirection, jSubset, jName, jPort := safelyParseSubsetKey(clusters[j].Name)
-		if iName == jName {
-			if iSubset == jSubset {
-				if iPort == jPort {
-					return iDirection < jDirection
-				}
-				return iPort < jPort
+		return nil, errNoClusters
+	}
+	sortClusters(clusters, sortByFQDN)
+	return clusters, nil
+}
+
+// sortClusters orders clusters by service FQDN, port, subset and direction, or by port, service FQDN, subset and
+// direction, or by name, so the output is the same whatever the order of the config dump
+func sortClusters(clusters []*clusterWithState, sortBy string) {
+	compareFQDNs := func(i, j int) int {
+		_, _, iFQDN, _ := safelyParseSubsetKey(clusters[i].Name)
+		_, _, jFQDN, _ := safelyParseSubsetKey(clusters[j].Name)
+		return strings.Compare(string(iFQDN), string(jFQDN))
+	}
+	comparePorts := func(i, j int) int {
+		return retrieveClusterPort(clusters[i].Cluster) - retrieveClusterPort(clusters[j].Cluster)
+	}
+	compareSubsets := func(i, j int) int {
+		_, iSubset, _, _ := safelyParseSubsetKey(clusters[i].Name)
+		_, jSubset, _, _ := safelyParseSubsetKey(clusters[j].Name)
+		return strings.Compare(iSubset, jSubset)
+	}
+	compareDirections := func(i, j int) int {
+		iDirection, _, _, _ := safelyParseSubsetKey(clusters[i].Name)
+		jDirection, _, _, _ := safelyParseSubsetKey(clusters[j].Name)
+		return strings.Compare(string(iDirection), string(jDirection))
+	}
+	compareNames := func(i, j int) int {
+		return strings.Compare(clusters[i].Name, clusters[j].Name)
+	}
+	keys := []func(i, j int) int{compareFQDNs, comparePorts, compareSubsets, compareDirections}
+	switch sortBy {
+	case sortByPort:
+		keys = []func(i, j int) int{comparePorts, compareFQDNs, compareSubsets, compareDirections}
+	case sortByName:
+		keys = []func(i, j int) int{compareNames}
+	}
+	// Stable, so the warming cluster of a name follows the active one
+	sort.SliceStable(clusters, func(i, j int) bool {
+		for _, key := range keys {
+			if c := key(i, j); c != 0 {
+				return c < 0
 			}
-			return iSubset < jSubset
 		}
-		return iName < jName
+		return false
 	})
-	return clusters, nil
+}
+
+// retrieveClusterPort returns the port of a cluster, parsed from Istio cluster names like
+// outbound|8080||foo.default.svc.cluster.local, or else the port of the first endpoint of its load assignment, like
+// the discovery port of the xds-grpc cluster of the bootstrap. Clusters without either have port 0.
+func retrieveClusterPort(c *cluster.Cluster) int {
+	if _, _, _, port := safelyParseSubsetKey(c.Name); port != 0 {
+		return port
+	}
+	for _, localityEndpoints := range c.GetLoadAssignment().GetEndpoints() {
+		for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+			if port := lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress().GetPortValue(); port != 0 {
+				return int(port)
+			}
+		}
+	}
+	return 0
+}
+
+func unmarshalCluster(clusterAny *any.Any) (*cluster.Cluster, error) {
+	clusterTyped := &cluster.Cluster{}
+	if err := unmarshalAnyAs(v3.ClusterType, clusterAny, clusterTyped); err != nil {
+		return nil, err
+	}
+	return clusterTyped, nil
 }
 
 func safelyParseSubsetKey(key string) (model.TrafficDirection, string, host.Name, int) {
@@ -13,3 +13,1642 @@
 // limitations under the License.
 
 package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/util"
+)
+
+func TestClusterFilter_Verify(t *testing.T) {
+	tests := []struct {
+		desc      string
+		inFilter  *ClusterFilter
+		inCluster *cluster.Cluster
+		expect    bool
+	}{
+		{
+			desc:      "empty-filter",
+			inFilter:  &ClusterFilter{},
+			inCluster: &cluster.Cluster{Name: "xds-grpc"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-substring",
+			inFilter:  &ClusterFilter{FQDN: "prod.svc"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-substring-is-not-a-short-name",
+			inFilter:  &ClusterFilter{FQDN: "foo"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo-v2.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-exact",
+			inFilter:  &ClusterFilter{FQDN: "foo.prod.svc.cluster.local", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-short-name",
+			inFilter:  &ClusterFilter{FQDN: "foo", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-short-name-and-namespace",
+			inFilter:  &ClusterFilter{FQDN: "foo.prod", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-short-name-other-namespace",
+			inFilter:  &ClusterFilter{FQDN: "foo", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.staging.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-short-name-is-not-a-prefix",
+			inFilter:  &ClusterFilter{FQDN: "foo", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo-v2.prod.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "fqdn-exact-is-not-a-substring",
+			inFilter:  &ClusterFilter{FQDN: "prod.svc", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "fqdn-non-istio-cluster",
+			inFilter:  &ClusterFilter{FQDN: "BlackHoleCluster", FQDNExact: true},
+			inCluster: &cluster.Cluster{Name: "BlackHoleCluster"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-tilde-is-a-substring",
+			inFilter:  &ClusterFilter{FQDN: `~.*\.example\.com`},
+			inCluster: &cluster.Cluster{Name: "outbound|443||api.example.com"},
+			expect:    false,
+		},
+		{
+			desc:      "fqdn-regex-match",
+			inFilter:  &ClusterFilter{FQDN: `.*\.prod\.svc.*`, FQDNRegex: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "fqdn-regex-mismatch",
+			inFilter:  &ClusterFilter{FQDN: `.*\.prod\.svc.*`, FQDNRegex: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.preprod.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "fqdn-regex-matches-service-host-only",
+			inFilter:  &ClusterFilter{FQDN: "outbound.*", FQDNRegex: true},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "fqdn-regex-non-istio-cluster",
+			inFilter:  &ClusterFilter{FQDN: "xds-.*", FQDNRegex: true},
+			inCluster: &cluster.Cluster{Name: "xds-grpc"},
+			expect:    true,
+		},
+		{
+			desc:      "name-contains",
+			inFilter:  &ClusterFilter{NameContains: "foo.prod.svc"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.prod.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "name-contains-mismatch",
+			inFilter:  &ClusterFilter{NameContains: "foo.prod.svc"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.staging.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "name-contains-non-istio-cluster",
+			inFilter:  &ClusterFilter{NameContains: "Hole"},
+			inCluster: &cluster.Cluster{Name: "BlackHoleCluster"},
+			expect:    true,
+		},
+		{
+			desc:      "subset-match",
+			inFilter:  &ClusterFilter{Subset: "v1"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080|v1|foo.default.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "subset-is-not-a-substring",
+			inFilter:  &ClusterFilter{Subset: "v1"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080|v10|foo.default.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "subset-not-in-service-name",
+			inFilter:  &ClusterFilter{Subset: "v1"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||v1.default.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "no-subset",
+			inFilter:  &ClusterFilter{Subset: "v1"},
+			inCluster: &cluster.Cluster{Name: "xds-grpc"},
+			expect:    false,
+		},
+		{
+			desc:      "direction-match",
+			inFilter:  &ClusterFilter{Direction: model.TrafficDirectionInbound},
+			inCluster: &cluster.Cluster{Name: "inbound|9080|http|reviews.default.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "direction-not-in-service-name",
+			inFilter:  &ClusterFilter{Direction: model.TrafficDirectionInbound},
+			inCluster: &cluster.Cluster{Name: "outbound|9080||inbound.default.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "direction-non-istio-cluster",
+			inFilter:  &ClusterFilter{Direction: model.TrafficDirectionOutbound},
+			inCluster: &cluster.Cluster{Name: "BlackHoleCluster"},
+			expect:    false,
+		},
+		{
+			desc:      "port-match",
+			inFilter:  &ClusterFilter{Port: 9080},
+			inCluster: &cluster.Cluster{Name: "outbound|9080|v2|reviews.default.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "port-non-istio-cluster",
+			inFilter:  &ClusterFilter{Port: 9080},
+			inCluster: &cluster.Cluster{Name: "outbound|9080|"},
+			expect:    false,
+		},
+		{
+			desc:      "port-static-cluster",
+			inFilter:  &ClusterFilter{Port: 15010},
+			inCluster: &cluster.Cluster{Name: "xds-grpc", LoadAssignment: newSocketLoadAssignment("istiod.istio-system.svc", 15010)},
+			expect:    true,
+		},
+		{
+			desc:      "port-static-cluster-mismatch",
+			inFilter:  &ClusterFilter{Port: 15012},
+			inCluster: &cluster.Cluster{Name: "xds-grpc", LoadAssignment: newSocketLoadAssignment("istiod.istio-system.svc", 15010)},
+			expect:    false,
+		},
+		{
+			desc:      "direction-subset-and-port",
+			inFilter:  &ClusterFilter{Direction: model.TrafficDirectionOutbound, Subset: "v2", Port: 9080, FQDN: "reviews"},
+			inCluster: &cluster.Cluster{Name: "outbound|9080|v2|reviews.default.svc.cluster.local"},
+			expect:    true,
+		},
+		{
+			desc:      "subset-and-port",
+			inFilter:  &ClusterFilter{Subset: "v1", Port: 9080},
+			inCluster: &cluster.Cluster{Name: "outbound|8080|v1|foo.default.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:     "type-match",
+			inFilter: &ClusterFilter{Type: "strict_dns"},
+			inCluster: &cluster.Cluster{
+				Name:                 "xds-grpc",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STRICT_DNS},
+			},
+			expect: true,
+		},
+		{
+			desc:     "type-mismatch",
+			inFilter: &ClusterFilter{Type: "STRICT_DNS"},
+			inCluster: &cluster.Cluster{
+				Name:                 "outbound|8080||foo.default.svc.cluster.local",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			},
+			expect: false,
+		},
+		{
+			desc:     "custom-type",
+			inFilter: &ClusterFilter{Type: "envoy.clusters.aggregate"},
+			inCluster: &cluster.Cluster{
+				Name: "aggregate",
+				ClusterDiscoveryType: &cluster.Cluster_ClusterType{
+					ClusterType: &cluster.Cluster_CustomClusterType{Name: "envoy.clusters.aggregate"},
+				},
+			},
+			expect: true,
+		},
+		{
+			desc:      "lb-policy-match",
+			inFilter:  &ClusterFilter{LbPolicy: "least_request"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.default.svc.cluster.local", LbPolicy: cluster.Cluster_LEAST_REQUEST},
+			expect:    true,
+		},
+		{
+			desc:      "lb-policy-mismatch",
+			inFilter:  &ClusterFilter{LbPolicy: "LEAST_REQUEST"},
+			inCluster: &cluster.Cluster{Name: "outbound|8080||foo.default.svc.cluster.local"},
+			expect:    false,
+		},
+		{
+			desc:      "plaintext",
+			inFilter:  &ClusterFilter{TLSMode: "disable"},
+			inCluster: &cluster.Cluster{Name: "xds-grpc"},
+			expect:    true,
+		},
+		{
+			desc:     "not-plaintext",
+			inFilter: &ClusterFilter{TLSMode: "DISABLE"},
+			inCluster: &cluster.Cluster{
+				Name:            "outbound|8080||foo.default.svc.cluster.local",
+				TransportSocket: newUpstreamTLSTransportSocket(t, &tls.CommonTlsContext{}),
+			},
+			expect: false,
+		},
+		{
+			desc:     "default-circuit-breakers",
+			inFilter: &ClusterFilter{NonDefaultCircuitBreakers: true},
+			inCluster: &cluster.Cluster{Name: "xds-grpc", CircuitBreakers: &cluster.CircuitBreakers{
+				Thresholds: []*cluster.CircuitBreakers_Thresholds{{MaxRetries: &wrappers.UInt32Value{Value: 3}}},
+			}},
+			expect: false,
+		},
+		{
+			desc:     "non-default-circuit-breakers",
+			inFilter: &ClusterFilter{NonDefaultCircuitBreakers: true},
+			inCluster: &cluster.Cluster{Name: "xds-grpc", CircuitBreakers: &cluster.CircuitBreakers{
+				Thresholds: []*cluster.CircuitBreakers_Thresholds{{MaxRetries: &wrappers.UInt32Value{Value: 10}}},
+			}},
+			expect: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := tt.inFilter.Verify(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestClusterFilter_Validate(t *testing.T) {
+	tests := []struct {
+		desc     string
+		inFilter *ClusterFilter
+		wantErr  bool
+	}{
+		{
+			desc:     "empty-filter",
+			inFilter: &ClusterFilter{},
+		},
+		{
+			desc:     "discovery-type",
+			inFilter: &ClusterFilter{Type: "logical_dns"},
+		},
+		{
+			desc:     "custom-type",
+			inFilter: &ClusterFilter{Type: "envoy.clusters.aggregate"},
+		},
+		{
+			desc:     "unknown-type",
+			inFilter: &ClusterFilter{Type: "DNS"},
+			wantErr:  true,
+		},
+		{
+			desc:     "unknown-lb-policy",
+			inFilter: &ClusterFilter{LbPolicy: "FASTEST"},
+			wantErr:  true,
+		},
+		{
+			desc:     "lb-policy-name",
+			inFilter: &ClusterFilter{LbPolicy: "envoy.lb.ring_hash"},
+		},
+		{
+			desc:     "fqdn-regex",
+			inFilter: &ClusterFilter{FQDN: `.*\.prod\.svc.*`, FQDNRegex: true},
+		},
+		{
+			desc:     "malformed-fqdn-regex",
+			inFilter: &ClusterFilter{FQDN: "foo.(prod", FQDNRegex: true},
+			wantErr:  true,
+		},
+		{
+			desc:     "malformed-fqdn-regex-prefix",
+			inFilter: &ClusterFilter{FQDN: "~foo.(prod"},
+			wantErr:  true,
+		},
+		{
+			desc:     "fqdn-substring-is-not-a-pattern",
+			inFilter: &ClusterFilter{FQDN: "foo.(prod"},
+		},
+		{
+			desc:     "sort-by-port",
+			inFilter: &ClusterFilter{SortBy: "port"},
+		},
+		{
+			desc:     "sort-by-unknown",
+			inFilter: &ClusterFilter{SortBy: "subset"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := tt.inFilter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("%s: expect error %v got %v", tt.desc, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRetrieveClusterType(t *testing.T) {
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc:      "static-by-default",
+			inCluster: &cluster.Cluster{},
+			expect:    "STATIC",
+		},
+		{
+			desc: "original-dst",
+			inCluster: &cluster.Cluster{
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_ORIGINAL_DST},
+				LbPolicy:             cluster.Cluster_CLUSTER_PROVIDED,
+			},
+			expect: "ORIGINAL_DST",
+		},
+		{
+			desc: "custom-type",
+			inCluster: &cluster.Cluster{
+				ClusterDiscoveryType: &cluster.Cluster_ClusterType{
+					ClusterType: &cluster.Cluster_CustomClusterType{Name: "envoy.clusters.aggregate"},
+				},
+			},
+			expect: "envoy.clusters.aggregate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveClusterType(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestRetrieveClusterLbPolicy(t *testing.T) {
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc:      "round-robin-by-default",
+			inCluster: &cluster.Cluster{},
+			expect:    "ROUND_ROBIN",
+		},
+		{
+			desc:      "ring-hash",
+			inCluster: &cluster.Cluster{LbPolicy: cluster.Cluster_RING_HASH, LbConfig: &cluster.Cluster_RingHashLbConfig_{}},
+			expect:    "RING_HASH",
+		},
+		{
+			desc: "ring-hash-policy-config",
+			inCluster: &cluster.Cluster{
+				LbPolicy: cluster.Cluster_LOAD_BALANCING_POLICY_CONFIG,
+				LoadBalancingPolicy: &cluster.LoadBalancingPolicy{
+					Policies: []*cluster.LoadBalancingPolicy_Policy{{Name: "envoy.lb.ring_hash"}},
+				},
+			},
+			expect: "RING_HASH",
+		},
+		{
+			desc: "maglev-policy-config",
+			inCluster: &cluster.Cluster{
+				LbPolicy: cluster.Cluster_LOAD_BALANCING_POLICY_CONFIG,
+				LoadBalancingPolicy: &cluster.LoadBalancingPolicy{
+					Policies: []*cluster.LoadBalancingPolicy_Policy{{Name: "envoy.lb.maglev"}},
+				},
+			},
+			expect: "MAGLEV",
+		},
+		{
+			desc: "custom-policy-config",
+			inCluster: &cluster.Cluster{
+				LbPolicy: cluster.Cluster_LOAD_BALANCING_POLICY_CONFIG,
+				LoadBalancingPolicy: &cluster.LoadBalancingPolicy{
+					Policies: []*cluster.LoadBalancingPolicy_Policy{{Name: "example.lb.weighted"}},
+				},
+			},
+			expect: "example.lb.weighted",
+		},
+		{
+			desc: "aggregate-cluster",
+			inCluster: &cluster.Cluster{
+				ClusterDiscoveryType: &cluster.Cluster_ClusterType{
+					ClusterType: &cluster.Cluster_CustomClusterType{Name: "envoy.clusters.aggregate"},
+				},
+				LbPolicy: cluster.Cluster_CLUSTER_PROVIDED,
+			},
+			expect: "CLUSTER_PROVIDED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveClusterLbPolicy(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func newUpstreamTLSTransportSocket(t *testing.T, commonTLSContext *tls.CommonTlsContext) *core.TransportSocket {
+	t.Helper()
+	typedConfig, err := ptypes.MarshalAny(&tls.UpstreamTlsContext{CommonTlsContext: commonTLSContext})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &core.TransportSocket{
+		Name:       "envoy.transport_sockets.tls",
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: typedConfig},
+	}
+}
+
+func TestConfigWriter_PrintClusterTLS(t *testing.T) {
+	newTLSTransportSocket := func(tlsContext *tls.UpstreamTlsContext) *core.TransportSocket {
+		return &core.TransportSocket{
+			Name:       "envoy.transport_sockets.tls",
+			ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: mustMarshalAny(t, tlsContext)},
+		}
+	}
+	simple := newTLSTransportSocket(&tls.UpstreamTlsContext{
+		Sni: "api.example.com",
+		CommonTlsContext: &tls.CommonTlsContext{
+			ValidationContextType: &tls.CommonTlsContext_ValidationContext{ValidationContext: &tls.CertificateValidationContext{
+				TrustedCa: &core.DataSource{Specifier: &core.DataSource_Filename{Filename: "/etc/ssl/certs/ca-certificates.crt"}},
+				MatchSubjectAltNames: []*matcher.StringMatcher{
+					{MatchPattern: &matcher.StringMatcher_Exact{Exact: "api.example.com"}},
+				},
+			}},
+		},
+	})
+	mutual := newTLSTransportSocket(&tls.UpstreamTlsContext{
+		CommonTlsContext: &tls.CommonTlsContext{
+			TlsCertificates: []*tls.TlsCertificate{{
+				CertificateChain: &core.DataSource{Specifier: &core.DataSource_Filename{Filename: "/etc/certs/client.pem"}},
+			}},
+			ValidationContextType: &tls.CommonTlsContext_ValidationContext{ValidationContext: &tls.CertificateValidationContext{
+				TrustedCa: &core.DataSource{Specifier: &core.DataSource_Filename{Filename: "/etc/certs/root.pem"}},
+				MatchSubjectAltNames: []*matcher.StringMatcher{
+					{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "spiffe://example.com/"}},
+				},
+			}},
+		},
+	})
+	istioMutual := newTLSTransportSocket(&tls.UpstreamTlsContext{
+		Sni: "outbound_.9080_._.reviews.default.svc.cluster.local",
+		CommonTlsContext: &tls.CommonTlsContext{
+			TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{{Name: "default"}},
+			ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
+				CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+					DefaultValidationContext: &tls.CertificateValidationContext{
+						MatchSubjectAltNames: []*matcher.StringMatcher{
+							{MatchPattern: &matcher.StringMatcher_Exact{Exact: "spiffe://cluster.local/ns/default/sa/reviews"}},
+						},
+					},
+					ValidationContextSdsSecretConfig: &tls.SdsSecretConfig{Name: "ROOTCA"},
+				},
+			},
+		},
+	})
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{Name: "outbound|443||api.example.com", TransportSocket: simple},
+		{Name: "outbound|8443||mtls.example.com", TransportSocket: mutual},
+		{
+			Name: "outbound|9080||reviews.default.svc.cluster.local",
+			TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{
+				{
+					Name: "tlsMode-istio",
+					Match: &structpb.Struct{Fields: map[string]*structpb.Value{
+						"tlsMode": {Kind: &structpb.Value_StringValue{StringValue: "istio"}},
+					}},
+					TransportSocket: istioMutual,
+				},
+				{Name: "tlsMode-disabled", TransportSocket: &core.TransportSocket{Name: "envoy.transport_sockets.raw_buffer"}},
+			},
+		},
+		{Name: "xds-grpc"},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	if err := cw.PrintClusterTLS(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/clustertls.txt", t)
+}
+
+func TestRetrieveClusterTLSMode(t *testing.T) {
+	istioMutual := newUpstreamTLSTransportSocket(t, &tls.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{{Name: "default"}},
+		ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+				ValidationContextSdsSecretConfig: &tls.SdsSecretConfig{Name: "ROOTCA"},
+			},
+		},
+		AlpnProtocols: []string{"istio-peer-exchange", "istio"},
+	})
+	istioTLSModeMatch := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"tlsMode": {Kind: &structpb.Value_StringValue{StringValue: "istio"}},
+	}}
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc:      "no-transport-socket",
+			inCluster: &cluster.Cluster{},
+			expect:    "DISABLE",
+		},
+		{
+			desc:      "istio-mutual",
+			inCluster: &cluster.Cluster{TransportSocket: istioMutual},
+			expect:    "ISTIO_MUTUAL",
+		},
+		{
+			desc: "simple",
+			inCluster: &cluster.Cluster{TransportSocket: newUpstreamTLSTransportSocket(t, &tls.CommonTlsContext{
+				ValidationContextType: &tls.CommonTlsContext_ValidationContext{ValidationContext: &tls.CertificateValidationContext{}},
+			})},
+			expect: "SIMPLE",
+		},
+		{
+			desc: "mutual",
+			inCluster: &cluster.Cluster{TransportSocket: newUpstreamTLSTransportSocket(t, &tls.CommonTlsContext{
+				TlsCertificates: []*tls.TlsCertificate{{}},
+			})},
+			expect: "MUTUAL",
+		},
+		{
+			desc: "istio-mutual-by-root-certificate",
+			inCluster: &cluster.Cluster{TransportSocket: newUpstreamTLSTransportSocket(t, &tls.CommonTlsContext{
+				ValidationContextType: &tls.CommonTlsContext_ValidationContextSdsSecretConfig{
+					ValidationContextSdsSecretConfig: &tls.SdsSecretConfig{Name: "ROOTCA"},
+				},
+			})},
+			expect: "ISTIO_MUTUAL",
+		},
+		{
+			desc: "auto",
+			inCluster: &cluster.Cluster{
+				TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{
+					{Name: "tlsMode-istio", Match: istioTLSModeMatch, TransportSocket: istioMutual},
+					{Name: "tlsMode-disabled", TransportSocket: &core.TransportSocket{Name: "envoy.transport_sockets.raw_buffer"}},
+				},
+			},
+			expect: "AUTO",
+		},
+		{
+			desc: "istio-mutual-or-plaintext-without-tls-mode-match",
+			inCluster: &cluster.Cluster{
+				TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{
+					{Name: "secure", TransportSocket: istioMutual},
+					{Name: "insecure", TransportSocket: &core.TransportSocket{Name: "envoy.transport_sockets.raw_buffer"}},
+				},
+			},
+			expect: "ISTIO_MUTUAL/DISABLE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveClusterTLSMode(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func newLoadAssignment(statuses ...core.HealthStatus) *endpoint.ClusterLoadAssignment {
+	lbEndpoints := make([]*endpoint.LbEndpoint, 0, len(statuses))
+	for _, status := range statuses {
+		lbEndpoints = append(lbEndpoints, &endpoint.LbEndpoint{HealthStatus: status})
+	}
+	return &endpoint.ClusterLoadAssignment{Endpoints: []*endpoint.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}}}
+}
+
+func newSocketLoadAssignment(address string, port uint32) *endpoint.ClusterLoadAssignment {
+	return &endpoint.ClusterLoadAssignment{Endpoints: []*endpoint.LocalityLbEndpoints{{LbEndpoints: []*endpoint.LbEndpoint{{
+		HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+			Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+				Address:       address,
+				PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+			}}},
+		}},
+	}}}}}
+}
+
+func TestSortClusters(t *testing.T) {
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{
+			sortBy: "fqdn",
+			want: []string{
+				"outbound|9443||details.default.svc.cluster.local",
+				"outbound|8080|v2|reviews.default.svc.cluster.local",
+				"inbound|9080||reviews.default.svc.cluster.local",
+				"outbound|9080|v1|reviews.default.svc.cluster.local",
+				"xds-grpc",
+			},
+		},
+		{
+			sortBy: "port",
+			want: []string{
+				"outbound|8080|v2|reviews.default.svc.cluster.local",
+				"inbound|9080||reviews.default.svc.cluster.local",
+				"outbound|9080|v1|reviews.default.svc.cluster.local",
+				"outbound|9443||details.default.svc.cluster.local",
+				"xds-grpc",
+			},
+		},
+		{
+			sortBy: "name",
+			want: []string{
+				"inbound|9080||reviews.default.svc.cluster.local",
+				"outbound|8080|v2|reviews.default.svc.cluster.local",
+				"outbound|9080|v1|reviews.default.svc.cluster.local",
+				"outbound|9443||details.default.svc.cluster.local",
+				"xds-grpc",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			clusters := make([]*clusterWithState, 0)
+			for _, c := range []*cluster.Cluster{
+				{Name: "xds-grpc", LoadAssignment: newSocketLoadAssignment("istiod.istio-system.svc", 15010)},
+				{Name: "outbound|9080|v1|reviews.default.svc.cluster.local"},
+				{Name: "outbound|9443||details.default.svc.cluster.local"},
+				{Name: "inbound|9080||reviews.default.svc.cluster.local"},
+				{Name: "outbound|8080|v2|reviews.default.svc.cluster.local"},
+			} {
+				clusters = append(clusters, &clusterWithState{Cluster: c, state: clusterStateActive})
+			}
+			sortClusters(clusters, tt.sortBy)
+			gotNames := make([]string, 0, len(clusters))
+			for _, c := range clusters {
+				gotNames = append(gotNames, c.Name)
+			}
+			if !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("sort by %s: expect %v got %v", tt.sortBy, tt.want, gotNames)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestFormatClusterEndpoints(t *testing.T) {
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc:      "eds-without-assignment",
+			inCluster: &cluster.Cluster{ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS}},
+			expect:    "?",
+		},
+		{
+			desc:      "static-without-assignment",
+			inCluster: &cluster.Cluster{ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STATIC}},
+			expect:    "0",
+		},
+		{
+			desc:      "unknown-health-is-healthy",
+			inCluster: &cluster.Cluster{LoadAssignment: newLoadAssignment(core.HealthStatus_UNKNOWN, core.HealthStatus_HEALTHY)},
+			expect:    "2/2",
+		},
+		{
+			desc: "unhealthy-and-draining",
+			inCluster: &cluster.Cluster{
+				LoadAssignment: newLoadAssignment(core.HealthStatus_HEALTHY, core.HealthStatus_UNHEALTHY, core.HealthStatus_DRAINING),
+			},
+			expect: "1/3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := (&ConfigWriter{}).formatClusterEndpoints(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrimeClusters(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	// One endpoint of reviews failed outlier detection and one is draining
+	clusterStatuses := `{"cluster_statuses": [
+		{"name": "outbound|9080||reviews.default.svc.cluster.local", "host_statuses": [
+			{"health_status": {"eds_health_status": "HEALTHY"}},
+			{"health_status": {"eds_health_status": "HEALTHY", "failed_outlier_check": true}},
+			{"health_status": {"eds_health_status": "DRAINING"}}
+		]},
+		{"name": "BlackHoleCluster"}
+	]}`
+	if err := cw.PrimeClusters([]byte(clusterStatuses)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc: "eds",
+			inCluster: &cluster.Cluster{
+				Name:                 "outbound|9080||reviews.default.svc.cluster.local",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			},
+			expect: "1/3",
+		},
+		{
+			desc:      "no-hosts",
+			inCluster: &cluster.Cluster{Name: "BlackHoleCluster"},
+			expect:    "0",
+		},
+		{
+			desc: "missing-from-clusters-output",
+			inCluster: &cluster.Cluster{
+				Name:                 "outbound|9080||ratings.default.svc.cluster.local",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			},
+			expect: "?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := cw.formatClusterEndpoints(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+
+	if err := cw.PrimeClusters([]byte("{")); err == nil {
+		t.Error("expected an error priming malformed clusters output")
+	}
+}
+
+func TestConfigWriter_PrimeEndpoints(t *testing.T) {
+	// One endpoint of reviews is unhealthy, and ratings has its load assignment under its EDS service name
+	configDump := `{"configs": [{
+		"@type": "type.googleapis.com/envoy.admin.v3.EndpointsConfigDump",
+		"dynamic_endpoint_configs": [
+			{"endpoint_config": {
+				"@type": "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment",
+				"cluster_name": "outbound|9080||reviews.default.svc.cluster.local",
+				"endpoints": [{"lb_endpoints": [{"health_status": "HEALTHY"}, {"health_status": "UNHEALTHY"}]}]
+			}},
+			{"endpoint_config": {
+				"@type": "type.googleapis.com/envoy.api.v2.ClusterLoadAssignment",
+				"cluster_name": "ratings",
+				"endpoints": [{"lb_endpoints": [{}]}]
+			}}
+		]
+	}]}`
+	cw := &ConfigWriter{}
+	if err := cw.Prime([]byte(configDump)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc: "eds",
+			inCluster: &cluster.Cluster{
+				Name:                 "outbound|9080||reviews.default.svc.cluster.local",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			},
+			expect: "1/2",
+		},
+		{
+			desc: "eds-service-name",
+			inCluster: &cluster.Cluster{
+				Name:                 "outbound|9080||ratings.default.svc.cluster.local",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+				EdsClusterConfig:     &cluster.Cluster_EdsClusterConfig{ServiceName: "ratings"},
+			},
+			expect: "1/1",
+		},
+		{
+			desc: "missing-from-eds-section",
+			inCluster: &cluster.Cluster{
+				Name:                 "outbound|9080||details.default.svc.cluster.local",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			},
+			expect: "?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := cw.formatClusterEndpoints(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_GetClusterLoadAssignments(t *testing.T) {
+	// details is an EDS cluster missing from the EDS section
+	configDump := `{"configs": [{
+		"@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+		"static_clusters": [{"cluster": {
+			"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+			"name": "xds-grpc",
+			"type": "STRICT_DNS",
+			"load_assignment": {"cluster_name": "xds-grpc", "endpoints": [{"lb_endpoints": [{}]}]}
+		}}],
+		"dynamic_active_clusters": [
+			{"cluster": {
+				"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+				"name": "outbound|9080||reviews.default.svc.cluster.local",
+				"type": "EDS"
+			}},
+			{"cluster": {
+				"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+				"name": "outbound|9080||details.default.svc.cluster.local",
+				"type": "EDS"
+			}}
+		]
+	}, {
+		"@type": "type.googleapis.com/envoy.admin.v3.EndpointsConfigDump",
+		"dynamic_endpoint_configs": [{"endpoint_config": {
+			"@type": "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment",
+			"cluster_name": "outbound|9080||reviews.default.svc.cluster.local",
+			"endpoints": [{"lb_endpoints": [{}, {}]}]
+		}}]
+	}]}`
+	cw := &ConfigWriter{}
+	if err := cw.Prime([]byte(configDump)); err != nil {
+		t.Fatal(err)
+	}
+	loadAssignments, err := cw.GetClusterLoadAssignments(ClusterFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for name, loadAssignment := range loadAssignments {
+		_, got[name] = retrieveLoadAssignmentEndpoints(loadAssignment)
+	}
+	want := map[string]int{
+		"xds-grpc": 1,
+		"outbound|9080||reviews.default.svc.cluster.local": 2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expect %v got %v", want, got)
+	}
+}
+
+func TestConfigWriter_PrintClusterSummary(t *testing.T) {
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name:                 "outbound|15004||istio-policy.istio-system.svc.cluster.local",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		},
+		{
+			Name:                 "xds-grpc",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STRICT_DNS},
+			LoadAssignment:       newLoadAssignment(core.HealthStatus_UNKNOWN),
+		},
+	} {
+		clusterDump.StaticClusters = append(clusterDump.StaticClusters, &adminapi.ClustersConfigDump_StaticCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	tests := []struct {
+		desc     string
+		filter   ClusterFilter
+		wantFile string
+	}{
+		{
+			desc:     "all",
+			wantFile: "testdata/clustersummary.txt",
+		},
+		{
+			desc:     "filtered",
+			filter:   ClusterFilter{FQDN: "istio-policy.istio-system.svc.cluster.local"},
+			wantFile: "testdata/clustersummaryfiltered.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newConfigWriter(t, gotOut, clusterDump)
+			if err := cw.PrintClusterSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+		})
+	}
+}
+
+func TestConfigWriter_PrintClusterSummaryTLS(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc     string
+		filter   ClusterFilter
+		wantFile string
+		want     string
+	}{
+		{
+			desc:     "all",
+			wantFile: "testdata/clustersummarytls.txt",
+		},
+		{
+			desc:   "auto",
+			filter: ClusterFilter{TLSMode: "auto"},
+			want: "SERVICE FQDN                          PORT     SUBSET     DIRECTION     TYPE     LB              TLS MODE     ENDPOINTS     STATE\n" +
+				"reviews.default.svc.cluster.local     9080     -          outbound      EDS      ROUND_ROBIN     AUTO         ?             ACTIVE\n",
+		},
+		{
+			desc:   "simple",
+			filter: ClusterFilter{TLSMode: "SIMPLE"},
+			want: "SERVICE FQDN        PORT     SUBSET     DIRECTION     TYPE           LB              TLS MODE     ENDPOINTS     STATE\n" +
+				"api.example.com     443      -          outbound      STRICT_DNS     ROUND_ROBIN     SIMPLE       1/1           ACTIVE\n",
+		},
+		{
+			desc:     "verbose",
+			filter:   ClusterFilter{Verbose: true},
+			wantFile: "testdata/clustersummaryverbose.txt",
+		},
+		{
+			desc:   "destination-rule",
+			filter: ClusterFilter{DestinationRule: "default/reviews"},
+			want: "SERVICE FQDN                          PORT     SUBSET     DIRECTION     TYPE     LB              TLS MODE     ENDPOINTS     STATE\n" +
+				"reviews.default.svc.cluster.local     9080     -          outbound      EDS      ROUND_ROBIN     AUTO         ?             ACTIVE\n",
+		},
+		{
+			desc:   "unknown-destination-rule",
+			filter: ClusterFilter{DestinationRule: "istio-system/reviews"},
+			want:   "SERVICE FQDN     PORT     SUBSET     DIRECTION     TYPE     LB     TLS MODE     ENDPOINTS     STATE\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut}
+			if err := cw.Prime(cd); err != nil {
+				t.Fatal(err)
+			}
+			if err := cw.PrintClusterSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFile != "" {
+				util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+			} else if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintClusterSummaryJSON(t *testing.T) {
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name:                 "outbound|9080|v1|reviews.default.svc.cluster.local",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			LoadAssignment:       newLoadAssignment(core.HealthStatus_HEALTHY, core.HealthStatus_UNHEALTHY),
+		},
+		{
+			Name:                 "xds-grpc",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STRICT_DNS},
+		},
+	} {
+		clusterDump.StaticClusters = append(clusterDump.StaticClusters, &adminapi.ClustersConfigDump_StaticCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	cw.OutputFormat = JSON
+	if err := cw.PrintClusterSummary(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	var summaries []ClusterSummary
+	if err := json.Unmarshal(gotOut.Bytes(), &summaries); err != nil {
+		t.Fatalf("summary is not a JSON array: %v\n%s", err, gotOut.String())
+	}
+	want := []ClusterSummary{
+		{
+			Name:             "outbound|9080|v1|reviews.default.svc.cluster.local",
+			ServiceFQDN:      "reviews.default.svc.cluster.local",
+			Port:             9080,
+			Subset:           "v1",
+			Direction:        "outbound",
+			Type:             "EDS",
+			LbPolicy:         "ROUND_ROBIN",
+			TLSMode:          clusterTLSModeDisable,
+			HealthyEndpoints: intPtr(1),
+			Endpoints:        intPtr(2),
+			State:            clusterStateStatic,
+		},
+		{
+			Name:             "xds-grpc",
+			Type:             "STRICT_DNS",
+			LbPolicy:         "ROUND_ROBIN",
+			TLSMode:          clusterTLSModeDisable,
+			HealthyEndpoints: intPtr(0),
+			Endpoints:        intPtr(0),
+			State:            clusterStateStatic,
+		},
+	}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("expect %+v got %+v", want, summaries)
+	}
+
+	gotOut.Reset()
+	cw.OutputFormat = YAML
+	if err := cw.PrintClusterSummary(ClusterFilter{FQDN: "xds-grpc"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(gotOut.String(), "- endpoints: 0\n") {
+		t.Errorf("expected a YAML list in:\n%s", gotOut.String())
+	}
+}
+
+func TestConfigWriter_PrintClusterSummaryWarming(t *testing.T) {
+	lastUpdated, err := ptypes.TimestampProto(time.Now().Add(-3 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviews := &cluster.Cluster{
+		Name:                 "outbound|9080||reviews.default.svc.cluster.local",
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+	}
+	warming := &adminapi.ClustersConfigDump{
+		DynamicWarmingClusters: []*adminapi.ClustersConfigDump_DynamicCluster{{
+			Cluster:     mustMarshalAny(t, reviews),
+			LastUpdated: lastUpdated,
+		}},
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, warming)
+	if err := cw.PrintClusterSummary(ClusterFilter{}); err != nil {
+		t.Fatalf("expected the warming clusters, got %v", err)
+	}
+	want := "SERVICE FQDN                          PORT     SUBSET     DIRECTION     TYPE     LB              TLS MODE     ENDPOINTS     STATE\n" +
+		"reviews.default.svc.cluster.local     9080     -          outbound      EDS      ROUND_ROBIN     DISABLE      ?             WARMING\n"
+	if gotOut.String() != want {
+		t.Errorf("expect:\n%s\ngot:\n%s", want, gotOut.String())
+	}
+
+	mixed := &adminapi.ClustersConfigDump{
+		DynamicActiveClusters:  warming.DynamicWarmingClusters,
+		DynamicWarmingClusters: warming.DynamicWarmingClusters,
+		StaticClusters: []*adminapi.ClustersConfigDump_StaticCluster{{
+			Cluster:     mustMarshalAny(t, &cluster.Cluster{Name: "xds-grpc"}),
+			LastUpdated: lastUpdated,
+		}},
+	}
+	gotOut.Reset()
+	cw = newConfigWriter(t, gotOut, mixed)
+	if err := cw.PrintClusterSummary(ClusterFilter{LastUpdated: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"LAST UPDATED", "ACTIVE      3m ago", "WARMING     3m ago", "STATIC      -"} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, gotOut.String())
+		}
+	}
+}
+
+func TestConfigWriter_DiffClusters(t *testing.T) {
+	newCluster := func(service string, maxConnections uint32) *cluster.Cluster {
+		return &cluster.Cluster{
+			Name:                 "outbound|9080||" + service + ".default.svc.cluster.local",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+			CircuitBreakers: &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{{
+				MaxConnections: &wrappers.UInt32Value{Value: maxConnections},
+			}}},
+		}
+	}
+	newVersionWriter := func(out *bytes.Buffer, version string, clusters ...*cluster.Cluster) *ConfigWriter {
+		lastUpdated, err := ptypes.TimestampProto(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dump := &adminapi.ClustersConfigDump{VersionInfo: version}
+		for _, c := range clusters {
+			dump.DynamicActiveClusters = append(dump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+				VersionInfo: version,
+				Cluster:     mustMarshalAny(t, c),
+				LastUpdated: lastUpdated,
+			})
+		}
+		return newConfigWriter(t, out, dump)
+	}
+	gotOut := &bytes.Buffer{}
+	before := newVersionWriter(gotOut, "2020-06-02T09:12:55Z/5",
+		newCluster("details", 100), newCluster("ratings", 100), newCluster("reviews", 100))
+	// The clusters of the other config dump are in another order, with another version and update time
+	after := newVersionWriter(nil, "2020-06-02T10:01:12Z/6",
+		newCluster("reviews", 10), newCluster("ratings", 100), newCluster("productpage", 100))
+	if err := before.DiffClusters(after, ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"- outbound|9080||details.default.svc.cluster.local\n",
+		"+ outbound|9080||productpage.default.svc.cluster.local\n",
+		"~ outbound|9080||reviews.default.svc.cluster.local\n",
+		`-` + strings.Repeat(" ", 16) + `"maxConnections": 100`,
+		`+` + strings.Repeat(" ", 16) + `"maxConnections": 10`,
+	} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, gotOut.String())
+		}
+	}
+	if strings.Contains(gotOut.String(), "ratings") {
+		t.Errorf("expected the unchanged cluster to be left out of:\n%s", gotOut.String())
+	}
+
+	gotOut.Reset()
+	before.OutputFormat = JSON
+	if err := before.DiffClusters(after, ClusterFilter{FQDN: "productpage"}); err != nil {
+		t.Fatal(err)
+	}
+	var diffs []ResourceDiff
+	if err := json.Unmarshal(gotOut.Bytes(), &diffs); err != nil {
+		t.Fatalf("diff is not a JSON array: %v\n%s", err, gotOut.String())
+	}
+	if want := []ResourceDiff{{Name: "outbound|9080||productpage.default.svc.cluster.local", Change: "added"}}; !reflect.DeepEqual(diffs, want) {
+		t.Errorf("expect %+v got %+v", want, diffs)
+	}
+}
+
+func TestConfigWriter_PrintClusterDump(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc     string
+		filter   ClusterFilter
+		nameOnly bool
+		template string
+		wantFile string
+		want     string
+	}{
+		{
+			desc:     "yaml",
+			wantFile: "testdata/clusterdump.yaml",
+		},
+		{
+			desc:   "yaml-filtered",
+			filter: ClusterFilter{Type: "STATIC"},
+			want:   "- connectTimeout: 10s\n  name: BlackHoleCluster\n  type: STATIC\n\n",
+		},
+		{
+			desc:     "name-only",
+			nameOnly: true,
+			wantFile: "testdata/clusternames.txt",
+		},
+		{
+			desc:     "name-only-filtered",
+			filter:   ClusterFilter{Direction: model.TrafficDirectionOutbound, TLSMode: "AUTO"},
+			nameOnly: true,
+			want:     "outbound|9080||reviews.default.svc.cluster.local\n",
+		},
+		{
+			desc:     "jsonpath",
+			filter:   ClusterFilter{Direction: model.TrafficDirectionOutbound},
+			template: `{range .[*]}{.name}{" "}{.connectTimeout}{"\n"}{end}`,
+			want:     "outbound|443||api.example.com 10s\noutbound|9080||reviews.default.svc.cluster.local 10s\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut, OutputFormat: YAML}
+			if tt.template != "" {
+				cw.OutputFormat = JSONPath
+				cw.JSONPathTemplate = tt.template
+			}
+			if err := cw.Prime(cd); err != nil {
+				t.Fatal(err)
+			}
+			printClusters := cw.PrintClusterDump
+			if tt.nameOnly {
+				printClusters = cw.PrintClusterNames
+			}
+			if err := printClusters(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFile != "" {
+				util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+			} else if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintClusterCircuitBreakers(t *testing.T) {
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name: "outbound|9080||reviews.default.svc.cluster.local",
+			CircuitBreakers: &cluster.CircuitBreakers{
+				Thresholds: []*cluster.CircuitBreakers_Thresholds{
+					{Priority: core.RoutingPriority_HIGH, MaxRetries: &wrappers.UInt32Value{Value: 10}},
+					{Priority: core.RoutingPriority_DEFAULT, MaxConnections: &wrappers.UInt32Value{Value: 100}},
+				},
+			},
+			OutlierDetection: &cluster.OutlierDetection{
+				Consecutive_5Xx:  &wrappers.UInt32Value{Value: 7},
+				BaseEjectionTime: ptypes.DurationProto(3 * time.Minute),
+			},
+		},
+		{Name: "xds-grpc"},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	if err := cw.PrintClusterCircuitBreakers(ClusterFilter{NonDefaultCircuitBreakers: true}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/clustercircuitbreakers.txt", t)
+
+	gotOut.Reset()
+	if err := cw.PrintClusterCircuitBreakers(ClusterFilter{FQDN: "xds-grpc"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"xds-grpc     DEFAULT      1024 (default)",
+		"xds-grpc     HIGH         1024 (default)",
+	} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected the Envoy defaults %q in:\n%s", want, gotOut.String())
+		}
+	}
+}
+
+func newHealthCheck(interval, timeout time.Duration, healthy, unhealthy uint32) *core.HealthCheck {
+	return &core.HealthCheck{
+		Interval:           ptypes.DurationProto(interval),
+		Timeout:            ptypes.DurationProto(timeout),
+		HealthyThreshold:   &wrappers.UInt32Value{Value: healthy},
+		UnhealthyThreshold: &wrappers.UInt32Value{Value: unhealthy},
+	}
+}
+
+func TestConfigWriter_PrintClusterHealthChecks(t *testing.T) {
+	httpHealthCheck := newHealthCheck(10*time.Second, time.Second, 2, 3)
+	httpHealthCheck.HealthChecker = &core.HealthCheck_HttpHealthCheck_{
+		HttpHealthCheck: &core.HealthCheck_HttpHealthCheck{Path: "/healthz"},
+	}
+	tcpHealthCheck := newHealthCheck(5*time.Second, time.Second, 1, 1)
+	tcpHealthCheck.HealthChecker = &core.HealthCheck_TcpHealthCheck_{TcpHealthCheck: &core.HealthCheck_TcpHealthCheck{}}
+	grpcHealthCheck := newHealthCheck(30*time.Second, 5*time.Second, 1, 2)
+	grpcHealthCheck.HealthChecker = &core.HealthCheck_GrpcHealthCheck_{
+		GrpcHealthCheck: &core.HealthCheck_GrpcHealthCheck{ServiceName: "reviews.Reviews"},
+	}
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name:         "outbound|9080||reviews.default.svc.cluster.local",
+			HealthChecks: []*core.HealthCheck{grpcHealthCheck},
+			CommonLbConfig: &cluster.Cluster_CommonLbConfig{
+				HealthyPanicThreshold: &xdstype.Percent{Value: 0},
+			},
+		},
+		{
+			Name:         "outbound|443||api.example.com",
+			HealthChecks: []*core.HealthCheck{httpHealthCheck, tcpHealthCheck},
+		},
+		{Name: "xds-grpc"},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	if err := cw.PrintClusterHealthChecks(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/clusterhealthchecks.txt", t)
+}
+
+func TestConfigWriter_PrintClusterDNS(t *testing.T) {
+	httpbin := newSocketLoadAssignment("httpbin.org", 80)
+	httpbin.Endpoints[0].LbEndpoints = append(httpbin.Endpoints[0].LbEndpoints,
+		newSocketLoadAssignment("www.httpbin.org", 80).Endpoints[0].LbEndpoints...)
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name:                 "outbound|443||api.example.com",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STRICT_DNS},
+			DnsLookupFamily:      cluster.Cluster_V4_ONLY,
+			DnsRefreshRate:       ptypes.DurationProto(30 * time.Second),
+			RespectDnsTtl:        true,
+			LoadAssignment:       newSocketLoadAssignment("api.example.com", 443),
+		},
+		{
+			Name:                 "outbound|80||httpbin.org",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_LOGICAL_DNS},
+			LoadAssignment:       httpbin,
+		},
+		{
+			Name:                 "outbound|9080||reviews.default.svc.cluster.local",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		},
+		{Name: "xds-grpc", LoadAssignment: newSocketLoadAssignment("127.0.0.1", 15010)},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	if err := cw.PrintClusterDNS(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/clusterdns.txt", t)
+}
+
+func TestConfigWriter_PrintClusterEndpointPriorities(t *testing.T) {
+	failover := newLoadAssignment(core.HealthStatus_HEALTHY, core.HealthStatus_HEALTHY)
+	failover.Endpoints[0].LbEndpoints[1].LoadBalancingWeight = &wrappers.UInt32Value{Value: 3}
+	failover.Endpoints = append(failover.Endpoints,
+		&endpoint.LocalityLbEndpoints{Priority: 2, LbEndpoints: newLoadAssignment(core.HealthStatus_HEALTHY).Endpoints[0].LbEndpoints},
+		&endpoint.LocalityLbEndpoints{Priority: 1, LbEndpoints: newLoadAssignment(core.HealthStatus_HEALTHY).Endpoints[0].LbEndpoints},
+		&endpoint.LocalityLbEndpoints{Priority: 1, LbEndpoints: newLoadAssignment(core.HealthStatus_UNHEALTHY).Endpoints[0].LbEndpoints})
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{Name: "outbound|443||api.example.com", LoadAssignment: failover},
+		{Name: "outbound|80||httpbin.org", LoadAssignment: newLoadAssignment(core.HealthStatus_HEALTHY, core.HealthStatus_UNKNOWN)},
+		{
+			Name:                 "outbound|9080||reviews.default.svc.cluster.local",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		},
+		{
+			Name:                 "outbound|9080||ratings.default.svc.cluster.local",
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	// The endpoints of reviews come from the clusters admin output, ratings is missing from it
+	clusterStatuses := `{"cluster_statuses": [
+		{"name": "outbound|9080||reviews.default.svc.cluster.local", "host_statuses": [
+			{"priority": 0, "weight": 1, "locality": {"region": "us-east", "zone": "a"}},
+			{"priority": 0, "weight": 2, "locality": {"region": "us-east", "zone": "b"}},
+			{"priority": 1, "weight": 1, "locality": {"region": "us-west", "zone": "a"}}
+		]}
+	]}`
+	if err := cw.PrimeClusters([]byte(clusterStatuses)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintClusterEndpointPriorities(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/clusterendpointpriorities.txt", t)
+}
+
+func TestRetrieveUpstreamProtocol(t *testing.T) {
+	tests := []struct {
+		desc      string
+		inCluster *cluster.Cluster
+		expect    string
+	}{
+		{
+			desc:      "envoy-default",
+			inCluster: &cluster.Cluster{},
+			expect:    "http1 (default)",
+		},
+		{
+			desc:      "explicit-http2",
+			inCluster: &cluster.Cluster{Http2ProtocolOptions: &core.Http2ProtocolOptions{}},
+			expect:    "explicit-http2",
+		},
+		{
+			desc: "downstream-protocol",
+			inCluster: &cluster.Cluster{
+				Http2ProtocolOptions: &core.Http2ProtocolOptions{},
+				ProtocolSelection:    cluster.Cluster_USE_DOWNSTREAM_PROTOCOL,
+			},
+			expect: "downstream-protocol",
+		},
+		{
+			desc: "typed-extension",
+			inCluster: &cluster.Cluster{TypedExtensionProtocolOptions: map[string]*any.Any{
+				"envoy.extensions.upstreams.http.v3.HttpProtocolOptions": {},
+			}},
+			expect: "?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveUpstreamProtocol(tt.inCluster); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintClusterProtocolOptions(t *testing.T) {
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name:                      "outbound|9080||reviews.default.svc.cluster.local",
+			Http2ProtocolOptions:      &core.Http2ProtocolOptions{},
+			ProtocolSelection:         cluster.Cluster_USE_DOWNSTREAM_PROTOCOL,
+			MaxRequestsPerConnection:  &wrappers.UInt32Value{Value: 1},
+			CommonHttpProtocolOptions: &core.HttpProtocolOptions{IdleTimeout: ptypes.DurationProto(30 * time.Second)},
+		},
+		{
+			Name:                 "xds-grpc",
+			Http2ProtocolOptions: &core.Http2ProtocolOptions{},
+		},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	if err := cw.PrintClusterProtocolOptions(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME                                                 PROTOCOL                MAX REQUESTS PER CONNECTION     IDLE TIMEOUT\n" +
+		"outbound|9080||reviews.default.svc.cluster.local     downstream-protocol     1                               30s\n" +
+		"xds-grpc                                             explicit-http2          -                               -\n"
+	if gotOut.String() != want {
+		t.Errorf("expect:\n%s\ngot:\n%s", want, gotOut.String())
+	}
+}
+
+func TestConfigWriter_PrintClusterProtocolOptionsTyped(t *testing.T) {
+	cd := `{
+  "configs": [
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+      "dynamic_active_clusters": [
+        {
+          "cluster": {
+            "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+            "name": "outbound|9080||reviews.default.svc.cluster.local",
+            "type": "EDS",
+            "typed_extension_protocol_options": {
+              "envoy.extensions.upstreams.http.v3.HttpProtocolOptions": {
+                "@type": "type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions",
+                "common_http_protocol_options": {"idle_timeout": "3600s"},
+                "explicit_http_config": {"http2_protocol_options": {}}
+              }
+            }
+          }
+        },
+        {
+          "cluster": {
+            "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+            "name": "outbound|9090||ratings.default.svc.cluster.local",
+            "type": "EDS",
+            "typed_extension_protocol_options": {
+              "envoy.extensions.upstreams.http.v3.HttpProtocolOptions": {
+                "@type": "type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions",
+                "auto_config": {"http2_protocol_options": {}}
+              }
+            }
+          }
+        },
+        {
+          "cluster": {
+            "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+            "name": "outbound|80||details.default.svc.cluster.local",
+            "type": "EDS",
+            "typed_extension_protocol_options": {
+              "envoy.extensions.upstreams.http.v3.HttpProtocolOptions": {
+                "@type": "type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions",
+                "use_downstream_protocol_config": {}
+              }
+            }
+          }
+        }
+      ],
+      "static_clusters": [
+        {
+          "cluster": {
+            "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+            "name": "xds-grpc",
+            "type": "STRICT_DNS",
+            "http2_protocol_options": {}
+          }
+        }
+      ]
+    }
+  ]
+}`
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime([]byte(cd)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintClusterProtocolOptions(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME                                                 PROTOCOL                MAX REQUESTS PER CONNECTION     IDLE TIMEOUT\n" +
+		"outbound|80||details.default.svc.cluster.local       downstream-protocol     -                               -\n" +
+		"outbound|9090||ratings.default.svc.cluster.local     auto                    -                               -\n" +
+		"outbound|9080||reviews.default.svc.cluster.local     explicit-http2          -                               1h0m0s\n" +
+		"xds-grpc                                             explicit-http2          -                               -\n"
+	if gotOut.String() != want {
+		t.Errorf("expect:\n%s\ngot:\n%s", want, gotOut.String())
+	}
+}
+
+func TestConfigWriter_PrintClusterOutlierDetection(t *testing.T) {
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range []*cluster.Cluster{
+		{
+			Name: "outbound|9080||reviews.default.svc.cluster.local",
+			OutlierDetection: &cluster.OutlierDetection{
+				Consecutive_5Xx:  &wrappers.UInt32Value{Value: 7},
+				BaseEjectionTime: ptypes.DurationProto(3 * time.Minute),
+			},
+		},
+		{Name: "xds-grpc"},
+	} {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, clusterDump)
+	if err := cw.PrintClusterOutlierDetection(ClusterFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME                                                 OUTLIER DETECTION     CONSECUTIVE 5XX     INTERVAL          " +
+		"BASE EJECTION TIME     MAX EJECTION %\n" +
+		"outbound|9080||reviews.default.svc.cluster.local     ENABLED               7                   10s (default)     " +
+		"3m0s                   10 (default)\n" +
+		"xds-grpc                                             DISABLED              -                   -                 " +
+		"-                      -\n"
+	if gotOut.String() != want {
+		t.Errorf("expect:\n%s\ngot:\n%s", want, gotOut.String())
+	}
+}
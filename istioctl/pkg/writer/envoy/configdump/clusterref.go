@@ -0,0 +1,220 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// Kinds of resources referencing clusters
+const (
+	clusterReferenceRoute    = "route"
+	clusterReferenceListener = "listener"
+)
+
+// ClusterReference is a cluster named by a route config or by a filter of a listener
+type ClusterReference struct {
+	Cluster string `json:"cluster"`
+	// Kind is route for route configs and listener for the TCP proxies and inline route configs of listeners
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Location is where the resource names the cluster, such as virtual host/route for route configs or the
+	// filter chain index for listeners
+	Location string `json:"location"`
+	// Field is the config field naming the cluster, such as route.weighted_clusters
+	Field string `json:"field"`
+}
+
+// ClusterReferenceReport lists the cluster references to clusters missing from the config dump and, when
+// requested, the dynamic clusters no route config or listener references
+type ClusterReferenceReport struct {
+	Dangling     []ClusterReference `json:"dangling"`
+	Unreferenced []string           `json:"unreferenced,omitempty"`
+}
+
+// CheckClusterReferences prints the clusters named by the routes of the route configs, including weighted and
+// mirror clusters, and by the TCP proxies and inline route configs of the listeners that are missing from the
+// cluster dump, which Envoy answers with 503s, as a table or, when the output format of the ConfigWriter is JSON
+// or YAML, as a ClusterReferenceReport. When showUnreferenced is set, the dynamic clusters nothing references are
+// printed too. Static clusters are left out, as the bootstrap references them, for instance for tracing.
+// An error is returned when dangling references are found, so captured config dumps can be checked in CI.
+// Empty sections are checked as empty, like the routes of a TCP-only proxy.
+func (c *ConfigWriter) CheckClusterReferences(showUnreferenced bool) error {
+	clusters, err := c.retrieveSortedClusterStates()
+	if err != nil && err != errNoClusters {
+		return err
+	}
+	routes, err := c.GetRoutes(RouteFilter{})
+	if err != nil && err != errNoRoutes {
+		return err
+	}
+	listeners, err := c.retrieveSortedListenerSlice()
+	if err != nil && err != errNoListeners {
+		return err
+	}
+	references := make([]ClusterReference, 0)
+	for _, r := range routes {
+		references = append(references, retrieveRouteConfigClusterReferences(r, clusterReferenceRoute, r.Name, "")...)
+	}
+	seen := map[string]bool{}
+	for _, l := range listeners {
+		// A listener is checked once, in its first state
+		if seen[l.Name] {
+			continue
+		}
+		seen[l.Name] = true
+		listenerReferences, err := retrieveListenerClusterReferences(l.Listener)
+		if err != nil {
+			return err
+		}
+		references = append(references, listenerReferences...)
+	}
+
+	exists := map[string]bool{}
+	for _, cs := range clusters {
+		exists[cs.Name] = true
+	}
+	report := ClusterReferenceReport{Dangling: make([]ClusterReference, 0)}
+	referenced := map[string]bool{}
+	for _, reference := range references {
+		referenced[reference.Cluster] = true
+		if !exists[reference.Cluster] {
+			report.Dangling = append(report.Dangling, reference)
+		}
+	}
+	sort.SliceStable(report.Dangling, func(i, j int) bool { return report.Dangling[i].Cluster < report.Dangling[j].Cluster })
+	if showUnreferenced {
+		report.Unreferenced = make([]string, 0)
+		for _, cs := range clusters {
+			// A cluster is reported once, in its first state
+			if cs.state != clusterStateStatic && !referenced[cs.Name] {
+				report.Unreferenced = append(report.Unreferenced, cs.Name)
+				referenced[cs.Name] = true
+			}
+		}
+	}
+
+	if err := c.printClusterReferenceReport(report, showUnreferenced); err != nil {
+		return err
+	}
+	if len(report.Dangling) > 0 {
+		return fmt.Errorf("found %d references to missing clusters", len(report.Dangling))
+	}
+	return nil
+}
+
+func (c *ConfigWriter) printClusterReferenceReport(report ClusterReferenceReport, showUnreferenced bool) error {
+	if c.OutputFormat != Table {
+		out, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal cluster references: %v", err)
+		}
+		return c.printJSON(out)
+	}
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	if len(report.Dangling) == 0 {
+		fmt.Fprintln(w, "No references to missing clusters found")
+	} else {
+		fmt.Fprintln(w, "MISSING CLUSTER\tREFERENCED BY\tLOCATION\tFIELD")
+		for _, reference := range report.Dangling {
+			fmt.Fprintf(w, "%v\t%v %v\t%v\t%v\n", reference.Cluster, reference.Kind, reference.Name, reference.Location, reference.Field)
+		}
+	}
+	if showUnreferenced {
+		fmt.Fprintln(w)
+		if len(report.Unreferenced) == 0 {
+			fmt.Fprintln(w, "No unreferenced clusters found")
+		} else {
+			fmt.Fprintln(w, "UNREFERENCED CLUSTER")
+			for _, name := range report.Unreferenced {
+				fmt.Fprintln(w, name)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// retrieveRouteConfigClusterReferences returns the clusters named by the routes of a route config, located by
+// virtual host and route name, or route index for unnamed routes, after the location prefix
+func retrieveRouteConfigClusterReferences(routeConfig *route.RouteConfiguration, kind, name, prefix string) []ClusterReference {
+	references := make([]ClusterReference, 0)
+	for _, virtualHost := range routeConfig.GetVirtualHosts() {
+		for i, r := range virtualHost.GetRoutes() {
+			routeName := r.GetName()
+			if routeName == "" {
+				routeName = strconv.Itoa(i)
+			}
+			location := prefix + virtualHost.GetName() + "/" + routeName
+			reference := func(cluster, field string) {
+				if cluster != "" {
+					references = append(references, ClusterReference{Cluster: cluster, Kind: kind, Name: name, Location: location, Field: field})
+				}
+			}
+			action := r.GetRoute()
+			reference(action.GetCluster(), "route.cluster")
+			for _, weightedCluster := range action.GetWeightedClusters().GetClusters() {
+				reference(weightedCluster.GetName(), "route.weighted_clusters")
+			}
+			for _, mirrorPolicy := range action.GetRequestMirrorPolicies() {
+				reference(mirrorPolicy.GetCluster(), "route.request_mirror_policies")
+			}
+		}
+	}
+	return references
+}
+
+// retrieveListenerClusterReferences returns the clusters named by the TCP proxies and the inline route configs of
+// the HTTP connection managers of a listener, located by filter chain index
+func retrieveListenerClusterReferences(l *listener.Listener) ([]ClusterReference, error) {
+	references := make([]ClusterReference, 0)
+	for i, filterChain := range l.GetFilterChains() {
+		location := "chain " + strconv.Itoa(i)
+		for _, filter := range filterChain.GetFilters() {
+			switch {
+			case isHTTPConnectionManager(filter):
+				httpConnectionManager, err := retrieveHTTPConnectionManager(filter)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the HTTP connection manager of listener %s: %v", l.Name, err)
+				}
+				references = append(references, retrieveRouteConfigClusterReferences(httpConnectionManager.GetRouteConfig(),
+					clusterReferenceListener, l.Name, location+", ")...)
+			case isTCPProxy(filter):
+				tcpProxy, err := retrieveTCPProxy(filter)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the TCP proxy of listener %s: %v", l.Name, err)
+				}
+				if cluster := tcpProxy.GetCluster(); cluster != "" {
+					references = append(references, ClusterReference{
+						Cluster: cluster, Kind: clusterReferenceListener, Name: l.Name, Location: location, Field: "tcp_proxy.cluster",
+					})
+				}
+				for _, weightedCluster := range tcpProxy.GetWeightedClusters().GetClusters() {
+					references = append(references, ClusterReference{
+						Cluster: weightedCluster.GetName(), Kind: clusterReferenceListener, Name: l.Name, Location: location,
+						Field: "tcp_proxy.weighted_clusters",
+					})
+				}
+			}
+		}
+	}
+	return references, nil
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+
+	"istio.io/istio/pilot/test/util"
+)
+
+func newClusterReferenceConfigWriter(t *testing.T, out *bytes.Buffer, clusterNames ...string) *ConfigWriter {
+	t.Helper()
+	newRoute := func(name, clusterName string, mirrorClusterNames ...string) *route.Route {
+		action := &route.RouteAction{ClusterSpecifier: &route.RouteAction_Cluster{Cluster: clusterName}}
+		for _, mirrorClusterName := range mirrorClusterNames {
+			action.RequestMirrorPolicies = append(action.RequestMirrorPolicies, &route.RouteAction_RequestMirrorPolicy{Cluster: mirrorClusterName})
+		}
+		return &route.Route{Name: name, Action: &route.Route_Route{Route: action}}
+	}
+	routes := []*route.RouteConfiguration{
+		{
+			Name: "9080",
+			VirtualHosts: []*route.VirtualHost{{
+				Name: "reviews.default.svc.cluster.local:9080",
+				Routes: []*route.Route{newRoute("default", "outbound|9080||reviews.default.svc.cluster.local",
+					"outbound|9080||reviews-mirror.default.svc.cluster.local")},
+			}},
+		},
+		{
+			Name: "9081",
+			VirtualHosts: []*route.VirtualHost{{
+				Name:   "details.default.svc.cluster.local:9080",
+				Routes: []*route.Route{newRoute("", "outbound|9080||details.default.svc.cluster.local")},
+			}},
+		},
+	}
+
+	tcpListener := newSocketListener("0.0.0.0", 3306)
+	tcpListener.Name = "0.0.0.0_3306"
+	tcpListener.FilterChains = []*listener.FilterChain{
+		{Filters: []*listener.Filter{newTypedFilter(t, "envoy.filters.network.tcp_proxy", &tcp.TcpProxy{
+			ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "outbound|3306||mysql.default.svc.cluster.local"},
+		})}},
+		{Filters: []*listener.Filter{newTypedFilter(t, "envoy.filters.network.tcp_proxy", &tcp.TcpProxy{
+			ClusterSpecifier: &tcp.TcpProxy_WeightedClusters{WeightedClusters: &tcp.TcpProxy_WeightedCluster{
+				Clusters: []*tcp.TcpProxy_WeightedCluster_ClusterWeight{
+					{Name: "outbound|3306||mysql.default.svc.cluster.local", Weight: 90},
+					{Name: "outbound|3306||mysql-replica.default.svc.cluster.local", Weight: 10},
+				},
+			}},
+		})}},
+	}
+	// Inbound clusters are referenced by the inline route configs of the inbound listener
+	inboundListener := newSocketListener("0.0.0.0", 15006)
+	inboundListener.Name = "virtualInbound"
+	inboundListener.FilterChains = []*listener.FilterChain{{
+		Filters: []*listener.Filter{newTypedFilter(t, "envoy.filters.network.http_connection_manager", &hcm.HttpConnectionManager{
+			RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{RouteConfig: &route.RouteConfiguration{
+				Name: "inbound|9080||",
+				VirtualHosts: []*route.VirtualHost{{
+					Name:   "inbound|http|9080",
+					Routes: []*route.Route{newRoute("default", "inbound|9080||")},
+				}},
+			}},
+		})},
+	}}
+
+	clusters := make([]*cluster.Cluster, 0, len(clusterNames))
+	for _, name := range clusterNames {
+		clusters = append(clusters, &cluster.Cluster{Name: name})
+	}
+	return newConfigDumpWriter(t, out, []*listener.Listener{tcpListener, inboundListener}, clusters, routes)
+}
+
+func TestConfigWriter_CheckClusterReferences(t *testing.T) {
+	gotOut := &bytes.Buffer{}
+	cw := newClusterReferenceConfigWriter(t, gotOut,
+		"inbound|9080||",
+		"outbound|3306||mysql.default.svc.cluster.local",
+		"outbound|9080||ratings.default.svc.cluster.local",
+		"outbound|9080||reviews.default.svc.cluster.local")
+	if err := cw.CheckClusterReferences(true); err == nil {
+		t.Error("expected an error for the references to missing clusters")
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/clusterreferences.txt", t)
+
+	gotOut.Reset()
+	cw.OutputFormat = JSON
+	if err := cw.CheckClusterReferences(false); err == nil {
+		t.Error("expected an error for the references to missing clusters")
+	}
+	var report ClusterReferenceReport
+	if err := json.Unmarshal(gotOut.Bytes(), &report); err != nil {
+		t.Fatalf("report is not JSON: %v\n%s", err, gotOut.String())
+	}
+	want := ClusterReference{
+		Cluster:  "outbound|3306||mysql-replica.default.svc.cluster.local",
+		Kind:     clusterReferenceListener,
+		Name:     "0.0.0.0_3306",
+		Location: "chain 1",
+		Field:    "tcp_proxy.weighted_clusters",
+	}
+	if len(report.Dangling) != 3 || !reflect.DeepEqual(report.Dangling[0], want) {
+		t.Errorf("expected 3 dangling references starting with %+v, got %+v", want, report.Dangling)
+	}
+	if report.Unreferenced != nil {
+		t.Errorf("expected the unreferenced clusters to be left out, got %v", report.Unreferenced)
+	}
+
+	gotOut.Reset()
+	cw = newClusterReferenceConfigWriter(t, gotOut,
+		"inbound|9080||",
+		"outbound|3306||mysql.default.svc.cluster.local",
+		"outbound|3306||mysql-replica.default.svc.cluster.local",
+		"outbound|9080||details.default.svc.cluster.local",
+		"outbound|9080||reviews.default.svc.cluster.local",
+		"outbound|9080||reviews-mirror.default.svc.cluster.local")
+	if err := cw.CheckClusterReferences(true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "No references to missing clusters found\n\nNo unreferenced clusters found\n"; gotOut.String() != want {
+		t.Errorf("expect %q got %q", want, gotOut.String())
+	}
+}
+
+func TestConfigWriter_CheckClusterReferencesTCPOnly(t *testing.T) {
+	tcpListener := newSocketListener("0.0.0.0", 3306)
+	tcpListener.Name = "0.0.0.0_3306"
+	tcpListener.FilterChains = []*listener.FilterChain{{
+		Filters: []*listener.Filter{newTypedFilter(t, "envoy.filters.network.tcp_proxy", &tcp.TcpProxy{
+			ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "outbound|3306||mysql.default.svc.cluster.local"},
+		})},
+	}}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigDumpWriter(t, gotOut, []*listener.Listener{tcpListener}, nil, nil)
+	if err := cw.CheckClusterReferences(false); err == nil {
+		t.Error("expected an error for the reference to the missing cluster")
+	}
+	if want := "outbound|3306||mysql.default.svc.cluster.local"; !strings.Contains(gotOut.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, gotOut.String())
+	}
+}
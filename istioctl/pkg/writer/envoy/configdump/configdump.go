@@ -15,83 +15,248 @@
 package configdump
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
 
+	"istio.io/istio/istioctl/pkg/util/clusters"
 	"istio.io/istio/istioctl/pkg/util/configdump"
-	sdscompare "istio.io/istio/istioctl/pkg/writer/compare/sds"
+	protio "istio.io/istio/istioctl/pkg/util/proto"
+)
+
+// Format is the output format used by the ConfigWriter print functions
+type Format int
+
+const (
+	// Table prints summaries as tables and dumps as indented JSON
+	Table Format = iota
+	// JSON prints summaries and dumps as indented JSON
+	JSON
+	// YAML prints summaries and dumps as YAML
+	YAML
+	// JSONPath prints the JSONPath template of the ConfigWriter applied to the JSON of summaries and dumps,
+	// with the syntax of kubectl -o jsonpath
+	JSONPath
 )
 
 // ConfigWriter is a writer for processing responses from the Envoy Admin config_dump endpoint
 type ConfigWriter struct {
-	Stdout     io.Writer
+	Stdout io.Writer
+	// Stderr receives the warnings of the print functions, os.Stderr when nil
+	Stderr       io.Writer
+	OutputFormat Format
+	// JSONPathTemplate is the template printed by the JSONPath output format, such as {.[*].name}
+	JSONPathTemplate string
+	// Color highlights added and removed lines of diffs and expired certificates with ANSI colors
+	Color bool
+	// Highlight colors the keys, strings and numbers of the JSON and YAML output with ANSI colors
+	Highlight  bool
 	configDump *configdump.Wrapper
+	// ecdsDump is the ECDS section of the config dump, see ecdsConfigDump
+	ecdsDump *ecdsConfigDump
+	// httpProtocolOptions are the HTTP protocol options typed extensions of the clusters by name, see
+	// parseHTTPProtocolOptions
+	httpProtocolOptions map[string]*httpProtocolOptions
+	// listenerAdditionalAddresses are the additional addresses of the multi-address listeners by name, see
+	// parseListenerAdditionalAddresses
+	listenerAdditionalAddresses map[string][]*core.Address
+	// edsLoadAssignments are the load assignments of the EDS section of the config dump by cluster name, see
+	// parseEndpointsConfigDump
+	edsLoadAssignments map[string]*endpoint.ClusterLoadAssignment
+	// clusterStatuses are the clusters of the clusters admin output by name, see PrimeClusters
+	clusterStatuses map[string]*adminapi.ClusterStatus
+}
+
+// gzipMagic are the first bytes of gzip compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns the data uncompressed when it is gzip compressed, like config dumps stored with gzip, and
+// as it is otherwise. Compression is told by content rather than file extension, so it works with stdin too.
+func decompress(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, gzipMagic) {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
 }
 
-// Prime loads the config dump into the writer ready for printing
+// Prime loads the config dump into the writer ready for printing. The config dump may be gzip compressed.
 func (c *ConfigWriter) Prime(b []byte) error {
+	b, err := decompress(b)
+	if err != nil {
+		return fmt.Errorf("error decompressing config dump: %v", err)
+	}
 	cd := configdump.Wrapper{}
 	// TODO(fisherxu): migrate this to jsonpb when issue fixed in golang
 	// Issue to track -> https://github.com/golang/protobuf/issues/632
-	err := json.Unmarshal(b, &cd)
+	err = json.Unmarshal(b, &cd)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling config dump response from Envoy: %v", err)
+	}
+	sections, err := splitConfigDumpSections(b)
 	if err != nil {
 		return fmt.Errorf("error unmarshalling config dump response from Envoy: %v", err)
 	}
+	ecdsDump, err := parseEcdsConfigDump(sections[ecdsConfigDumpTypeURL])
+	if err != nil {
+		return fmt.Errorf("error unmarshalling the ECDS config dump response from Envoy: %v", err)
+	}
+	httpProtocolOptions, err := parseHTTPProtocolOptions(sections[clustersConfigDumpTypeURL])
+	if err != nil {
+		return fmt.Errorf("error unmarshalling the cluster HTTP protocol options of the config dump response from Envoy: %v", err)
+	}
+	listenerAdditionalAddresses, err := parseListenerAdditionalAddresses(sections[listenersConfigDumpTypeURL])
+	if err != nil {
+		return fmt.Errorf("error unmarshalling the listener additional addresses of the config dump response from Envoy: %v", err)
+	}
+	edsLoadAssignments, err := parseEndpointsConfigDump(sections[endpointsConfigDumpTypeURL])
+	if err != nil {
+		return fmt.Errorf("error unmarshalling the EDS config dump response from Envoy: %v", err)
+	}
 	c.configDump = &cd
+	c.ecdsDump = ecdsDump
+	c.httpProtocolOptions = httpProtocolOptions
+	c.listenerAdditionalAddresses = listenerAdditionalAddresses
+	c.edsLoadAssignments = edsLoadAssignments
 	return nil
 }
 
-// PrintBootstrapDump prints just the bootstrap config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintBootstrapDump() error {
-	if c.configDump == nil {
-		return fmt.Errorf("config writer has not been primed")
+// splitConfigDumpSections returns the sections of the configs of the config dump JSON by type, as JSON for the
+// decoders of the sections and fields the wrapper drops. A type found more than once gets its first section.
+// TODO: read the ECDS and EDS sections, the typed extension protocol options of clusters and the additional addresses
+// and internal_listener of listeners from the wrapper once the vendored go-control-plane has them, and drop their
+// decoders; until then the wrapper drops them as unknown types and fields, so they are read from the JSON.
+func splitConfigDumpSections(b []byte) (map[string]json.RawMessage, error) {
+	configDump := struct {
+		Configs []json.RawMessage `json:"configs"`
+	}{}
+	if err := json.Unmarshal(b, &configDump); err != nil {
+		return nil, err
 	}
-	bootstrapDump, err := c.configDump.GetBootstrapConfigDump()
+	sections := make(map[string]json.RawMessage, len(configDump.Configs))
+	for _, config := range configDump.Configs {
+		section := struct {
+			TypeURL string `json:"@type"`
+		}{}
+		if err := json.Unmarshal(config, &section); err != nil {
+			continue
+		}
+		if _, ok := sections[section.TypeURL]; !ok {
+			sections[section.TypeURL] = config
+		}
+	}
+	return sections, nil
+}
+
+// PrimeClusters loads the output of the Envoy Admin clusters?format=json endpoint into the writer alongside the
+// config dump, so the cluster summary counts the endpoints Envoy discovered over EDS. Like the config dump, the
+// output may be gzip compressed.
+func (c *ConfigWriter) PrimeClusters(b []byte) error {
+	b, err := decompress(b)
 	if err != nil {
-		return err
+		return fmt.Errorf("error decompressing clusters response: %v", err)
 	}
-	jsonm := &jsonpb.Marshaler{Indent: "    "}
-	if err := jsonm.Marshal(c.Stdout, bootstrapDump); err != nil {
-		return fmt.Errorf("unable to marshal bootstrap in Envoy config dump")
+	cd := clusters.Wrapper{}
+	if err := json.Unmarshal(b, &cd); err != nil {
+		return fmt.Errorf("error unmarshalling clusters response from Envoy: %v", err)
+	}
+	c.clusterStatuses = make(map[string]*adminapi.ClusterStatus, len(cd.GetClusterStatuses()))
+	for _, clusterStatus := range cd.GetClusterStatuses() {
+		c.clusterStatuses[clusterStatus.GetName()] = clusterStatus
 	}
 	return nil
 }
 
-// PrintSecretDump prints just the secret config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintSecretDump() error {
-	if c.configDump == nil {
-		return fmt.Errorf("config writer has not been primed")
-	}
-	secretDump, err := c.configDump.GetSecretConfigDump()
+// unmarshalAnyAs unmarshals the value of the Any into the message as the type of the type URL rather than its own.
+// This supports both v2 and v3 resources in the config dump, whose messages are wire compatible, without changing
+// the Any of the config dump. See ads.go:RequestedTypes for more info.
+func unmarshalAnyAs(typeURL string, a *any.Any, msg proto.Message) error {
+	return ptypes.UnmarshalAny(&any.Any{TypeUrl: typeURL, Value: a.GetValue()}, msg)
+}
+
+// printMessages marshals the passed resources to the ConfigWriter stdout in the configured output format
+func (c *ConfigWriter) printMessages(messages protio.MessageSlice) error {
+	out, err := json.MarshalIndent(messages, "", "    ")
 	if err != nil {
-		return fmt.Errorf("sidecar doesn't support secrets: %v", err)
+		return err
 	}
+	return c.printJSON(out)
+}
+
+// printMessage marshals the passed resource to the ConfigWriter stdout in the configured output format
+func (c *ConfigWriter) printMessage(message proto.Message) error {
 	jsonm := &jsonpb.Marshaler{Indent: "    "}
-	if err := jsonm.Marshal(c.Stdout, secretDump); err != nil {
-		return fmt.Errorf("unable to marshal secrets in Envoy config dump")
+	out, err := jsonm.MarshalToString(message)
+	if err != nil {
+		return err
+	}
+	return c.printJSON([]byte(out))
+}
+
+func (c *ConfigWriter) printJSON(out []byte) error {
+	var err error
+	if c.OutputFormat == JSONPath {
+		return c.printJSONPath(out)
+	}
+	if c.OutputFormat == YAML {
+		if out, err = yaml.JSONToYAML(out); err != nil {
+			return err
+		}
+		if c.Highlight {
+			out = highlightYAML(out)
+		}
+	} else if c.Highlight {
+		out = highlightJSON(out)
 	}
+	fmt.Fprintln(c.Stdout, string(out))
 	return nil
 }
 
-// PrintSecretSummary prints a summary of dynamic active secrets from the config dump
-func (c *ConfigWriter) PrintSecretSummary() error {
-	secretDump, err := c.configDump.GetSecretConfigDump()
-	if err != nil {
+// printJSONPath applies the JSONPath template of the ConfigWriter to the JSON and prints the result to the
+// ConfigWriter stdout. Like kubectl, fields missing from the resources are printed as empty and no newline is added.
+func (c *ConfigWriter) printJSONPath(out []byte) error {
+	parser := jsonpath.New("output").AllowMissingKeys(true)
+	if err := parser.Parse(c.JSONPathTemplate); err != nil {
+		return fmt.Errorf("error parsing JSONPath template %q: %v", c.JSONPathTemplate, err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(out, &data); err != nil {
 		return err
 	}
-	if len(secretDump.DynamicActiveSecrets) == 0 &&
-		len(secretDump.DynamicWarmingSecrets) == 0 {
-		fmt.Fprintln(c.Stdout, "No active or warming secrets found.")
-		return nil
+	if err := parser.Execute(c.Stdout, data); err != nil {
+		return fmt.Errorf("error executing JSONPath template %q: %v", c.JSONPathTemplate, err)
 	}
-	secretItems, err := sdscompare.GetEnvoySecrets(c.configDump)
+	return nil
+}
+
+// PrintBootstrapDump prints just the bootstrap config dump to the ConfigWriter stdout
+func (c *ConfigWriter) PrintBootstrapDump() error {
+	if c.configDump == nil {
+		return fmt.Errorf("config writer has not been primed")
+	}
+	bootstrapDump, err := c.configDump.GetBootstrapConfigDump()
 	if err != nil {
 		return err
 	}
-
-	secretWriter := sdscompare.NewSDSWriter(c.Stdout, sdscompare.TABULAR)
-	return secretWriter.PrintSecretItems(secretItems)
+	if err := c.printMessage(bootstrapDump); err != nil {
+		return fmt.Errorf("unable to marshal bootstrap in Envoy config dump")
+	}
+	return nil
 }
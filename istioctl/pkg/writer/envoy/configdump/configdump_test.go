@@ -16,11 +16,14 @@ package configdump
 
 import (
 	"bytes"
+	"compress/gzip"
 	"io/ioutil"
 	"testing"
 
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	"github.com/stretchr/testify/assert"
 
+	protio "istio.io/istio/istioctl/pkg/util/proto"
 	"istio.io/istio/pilot/test/util"
 )
 
@@ -59,6 +62,44 @@ func TestConfigWriter_Prime(t *testing.T) {
 	}
 }
 
+func TestConfigWriter_PrimeGzip(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: want}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &bytes.Buffer{}
+	cw = &ConfigWriter{Stdout: got}
+	if err := cw.Prime(compressed.Bytes()); err != nil {
+		t.Fatalf("failed to prime a gzip compressed config dump: %v", err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, want.String(), got.String())
+
+	// Data that only starts like gzip is reported rather than parsed as JSON.
+	cw = &ConfigWriter{}
+	assert.Error(t, cw.Prime(compressed.Bytes()[:4]))
+}
+
 func TestConfigWriter_PrintBootstrapDump(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -97,3 +138,59 @@ func TestConfigWriter_PrintBootstrapDump(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigWriter_printMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:   "json",
+			format: JSON,
+			want:   "[\n    {\n        \"name\": \"foo\"\n    }\n]\n",
+		},
+		{
+			name:   "yaml",
+			format: YAML,
+			want:   "- name: foo\n\n",
+		},
+		{
+			name:     "jsonpath",
+			format:   JSONPath,
+			template: `{range .[*]}{.name}{"\n"}{end}`,
+			want:     "foo\nbar\n",
+		},
+		{
+			name:     "jsonpath-missing-field",
+			format:   JSONPath,
+			template: "{.[*].address}",
+			want:     "",
+		},
+		{
+			name:     "jsonpath-invalid",
+			format:   JSONPath,
+			template: "{.[*].name",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut, OutputFormat: tt.format, JSONPathTemplate: tt.template}
+			messages := protio.MessageSlice{&listener.Listener{Name: "foo"}}
+			if tt.format == JSONPath {
+				messages = append(messages, &listener.Listener{Name: "bar"})
+			}
+			err := cw.printMessages(messages)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, gotOut.String())
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/golang/protobuf/proto"
+)
+
+// Kinds of conflicts between the filter chain matches of a listener
+const (
+	conflictDuplicate   = "duplicate"
+	conflictOverlapping = "overlapping"
+)
+
+// ListenerConflict describes two filter chains of a listener whose matches conflict
+type ListenerConflict struct {
+	Listener string `json:"listener"`
+	// Chains are the indices of the conflicting filter chains, in listener order
+	Chains [2]int `json:"chains"`
+	// Conflict is duplicate, or overlapping with the criteria the chains share
+	Conflict string `json:"conflict"`
+	// Match describes the match criteria of the later chain
+	Match string `json:"match"`
+}
+
+// CheckListenerConflicts prints the filter chains of the relevant listeners whose match criteria are exact
+// duplicates, which Envoy rejects, or that share some of the values of criteria they are equally specific on,
+// as a table or, when the output format of the ConfigWriter is JSON or YAML, as a list of ListenerConflict.
+// Envoy picks the most specific match whatever the order of the chains, so a catch-all chain is no conflict.
+// An error is returned when conflicts are found, so captured config dumps can be checked in CI.
+func (c *ConfigWriter) CheckListenerConflicts(filter ListenerFilter) error {
+	w, listeners, err := c.setupListenerConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	conflicts := make([]ListenerConflict, 0)
+	seen := map[string]bool{}
+	for _, l := range listeners {
+		// A listener is checked once, in its first state
+		if seen[l.Name] {
+			continue
+		}
+		seen[l.Name] = true
+		conflicts = append(conflicts, retrieveListenerConflicts(l.Listener)...)
+	}
+
+	if c.OutputFormat != Table {
+		out, err := json.MarshalIndent(conflicts, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal listener conflicts: %v", err)
+		}
+		if err := c.printJSON(out); err != nil {
+			return err
+		}
+	} else if len(conflicts) == 0 {
+		fmt.Fprintln(c.Stdout, "No listener filter chain conflicts found")
+	} else {
+		fmt.Fprintln(w, "LISTENER\tCHAINS\tCONFLICT\tMATCH")
+		for _, conflict := range conflicts {
+			fmt.Fprintf(w, "%v\t%d,%d\t%v\t%v\n", conflict.Listener, conflict.Chains[0], conflict.Chains[1], conflict.Conflict, conflict.Match)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("found %d listener filter chain conflicts", len(conflicts))
+	}
+	return nil
+}
+
+// retrieveListenerConflicts compares every pair of filter chains of a listener
+func retrieveListenerConflicts(l *listener.Listener) []ListenerConflict {
+	matches := make([]*listener.FilterChainMatch, 0, len(l.GetFilterChains()))
+	for _, filterChain := range l.GetFilterChains() {
+		matches = append(matches, normalizeFilterChainMatch(filterChain.GetFilterChainMatch()))
+	}
+	conflicts := make([]ListenerConflict, 0)
+	for j := range matches {
+		for i := 0; i < j; i++ {
+			conflict := describeFilterChainMatchConflict(matches[i], matches[j])
+			if conflict == "" {
+				continue
+			}
+			conflicts = append(conflicts, ListenerConflict{
+				Listener: l.Name,
+				Chains:   [2]int{i, j},
+				Conflict: conflict,
+				Match:    describeFilterChainMatch(matches[j]),
+			})
+		}
+	}
+	return conflicts
+}
+
+// describeFilterChainMatchConflict returns how a filter chain match conflicts with the match of an earlier chain,
+// or "" when they select different connections. Envoy matches each criterion in turn and prefers a chain listing
+// values for it over one that does not, so two matches only conflict when they have the same single values, like
+// the transport protocol, and share a value of each list of values, or both leave the list empty.
+func describeFilterChainMatchConflict(earlier, later *listener.FilterChainMatch) string {
+	if proto.Equal(earlier, later) {
+		return conflictDuplicate
+	}
+	if !proto.Equal(withoutListCriteria(earlier), withoutListCriteria(later)) {
+		return ""
+	}
+	criteria := []struct {
+		name           string
+		earlier, later []string
+	}{
+		{"destination prefix ranges", formatCidrRanges(earlier.GetPrefixRanges()), formatCidrRanges(later.GetPrefixRanges())},
+		{"server names", earlier.GetServerNames(), later.GetServerNames()},
+		{"application protocols", earlier.GetApplicationProtocols(), later.GetApplicationProtocols()},
+		{"source prefix ranges", formatCidrRanges(earlier.GetSourcePrefixRanges()), formatCidrRanges(later.GetSourcePrefixRanges())},
+		{"source ports", formatPorts(earlier.GetSourcePorts()), formatPorts(later.GetSourcePorts())},
+	}
+	overlapping, shared := make([]string, 0), make([]string, 0)
+	for _, criterion := range criteria {
+		if len(criterion.earlier) == 0 && len(criterion.later) == 0 {
+			continue
+		}
+		values := sharedValues(criterion.earlier, criterion.later)
+		if len(values) == 0 {
+			return ""
+		}
+		if len(values) < len(criterion.earlier) || len(values) < len(criterion.later) {
+			overlapping = append(overlapping, criterion.name)
+			shared = append(shared, strings.Join(values, ","))
+		}
+	}
+	if len(overlapping) == 0 {
+		return conflictDuplicate
+	}
+	return fmt.Sprintf("%s %s (%s)", conflictOverlapping, strings.Join(overlapping, " and "), strings.Join(shared, "; "))
+}
+
+// withoutListCriteria returns a copy of the match without the criteria listing values
+func withoutListCriteria(match *listener.FilterChainMatch) *listener.FilterChainMatch {
+	match = proto.Clone(match).(*listener.FilterChainMatch)
+	match.PrefixRanges = nil
+	match.ServerNames = nil
+	match.ApplicationProtocols = nil
+	match.SourcePrefixRanges = nil
+	match.SourcePorts = nil
+	return match
+}
+
+// normalizeFilterChainMatch returns a copy of the match with its unordered criteria sorted, so matches
+// listing the same criteria in a different order compare equal
+func normalizeFilterChainMatch(match *listener.FilterChainMatch) *listener.FilterChainMatch {
+	if match == nil {
+		return &listener.FilterChainMatch{}
+	}
+	match = proto.Clone(match).(*listener.FilterChainMatch)
+	sortCidrRanges(match.PrefixRanges)
+	sortCidrRanges(match.SourcePrefixRanges)
+	sort.Strings(match.ServerNames)
+	sort.Strings(match.ApplicationProtocols)
+	sort.Slice(match.SourcePorts, func(i, j int) bool { return match.SourcePorts[i] < match.SourcePorts[j] })
+	return match
+}
+
+func sortCidrRanges(prefixRanges []*core.CidrRange) {
+	sort.Slice(prefixRanges, func(i, j int) bool {
+		iPrefix, jPrefix := prefixRanges[i], prefixRanges[j]
+		if iPrefix.GetAddressPrefix() == jPrefix.GetAddressPrefix() {
+			return iPrefix.GetPrefixLen().GetValue() < jPrefix.GetPrefixLen().GetValue()
+		}
+		return iPrefix.GetAddressPrefix() < jPrefix.GetAddressPrefix()
+	})
+}
+
+func formatCidrRanges(prefixRanges []*core.CidrRange) []string {
+	prefixes := make([]string, 0, len(prefixRanges))
+	for _, prefix := range prefixRanges {
+		prefixes = append(prefixes, formatPrefixRanges([]*core.CidrRange{prefix}))
+	}
+	return prefixes
+}
+
+func formatPorts(ports []uint32) []string {
+	formatted := make([]string, 0, len(ports))
+	for _, port := range ports {
+		formatted = append(formatted, strconv.Itoa(int(port)))
+	}
+	return formatted
+}
+
+func sharedValues(a, b []string) []string {
+	values := map[string]bool{}
+	for _, value := range a {
+		values[value] = true
+	}
+	shared := make([]string, 0)
+	for _, value := range b {
+		if values[value] {
+			shared = append(shared, value)
+			// A value listed twice is shared once
+			delete(values, value)
+		}
+	}
+	return shared
+}
@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func newConflictingListener(name string, port uint32, matches ...*listener.FilterChainMatch) *listener.Listener {
+	l := newSocketListener("0.0.0.0", port)
+	l.Name = name
+	for _, match := range matches {
+		l.FilterChains = append(l.FilterChains, &listener.FilterChain{FilterChainMatch: match})
+	}
+	return l
+}
+
+func TestDescribeFilterChainMatchConflict(t *testing.T) {
+	tests := []struct {
+		desc    string
+		earlier *listener.FilterChainMatch
+		later   *listener.FilterChainMatch
+		want    string
+	}{
+		{
+			desc:    "duplicate-server-names-in-any-order",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"a.example.com", "b.example.com"}},
+			later:   &listener.FilterChainMatch{ServerNames: []string{"b.example.com", "a.example.com"}},
+			want:    conflictDuplicate,
+		},
+		{
+			desc:    "duplicate-catch-all",
+			earlier: nil,
+			later:   &listener.FilterChainMatch{},
+			want:    conflictDuplicate,
+		},
+		{
+			desc:    "overlapping-server-names",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"a.example.com", "b.example.com"}, TransportProtocol: "tls"},
+			later:   &listener.FilterChainMatch{ServerNames: []string{"b.example.com"}, TransportProtocol: "tls"},
+			want:    "overlapping server names (b.example.com)",
+		},
+		{
+			desc:    "shared-server-names-different-transport",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"a.example.com"}, TransportProtocol: "tls"},
+			later:   &listener.FilterChainMatch{ServerNames: []string{"a.example.com"}},
+		},
+		{
+			desc:    "catch-all-before-specific",
+			earlier: nil,
+			later:   &listener.FilterChainMatch{TransportProtocol: "tls"},
+		},
+		{
+			desc:    "catch-all-after-specific",
+			earlier: &listener.FilterChainMatch{TransportProtocol: "tls"},
+			later:   nil,
+		},
+		{
+			desc:    "server-names-before-any-server-name",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"a.example.com"}},
+			later:   &listener.FilterChainMatch{},
+		},
+		{
+			desc:    "wildcard-and-exact-server-names",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"*.example.com"}},
+			later:   &listener.FilterChainMatch{ServerNames: []string{"a.example.com"}},
+		},
+		{
+			desc: "duplicate-prefix-ranges-in-any-order",
+			earlier: &listener.FilterChainMatch{PrefixRanges: []*core.CidrRange{
+				{AddressPrefix: "10.0.0.0", PrefixLen: &wrappers.UInt32Value{Value: 8}},
+				{AddressPrefix: "192.168.0.0", PrefixLen: &wrappers.UInt32Value{Value: 16}},
+			}},
+			later: &listener.FilterChainMatch{PrefixRanges: []*core.CidrRange{
+				{AddressPrefix: "192.168.0.0", PrefixLen: &wrappers.UInt32Value{Value: 16}},
+				{AddressPrefix: "10.0.0.0", PrefixLen: &wrappers.UInt32Value{Value: 8}},
+			}},
+			want: conflictDuplicate,
+		},
+		{
+			desc: "nested-prefix-ranges",
+			earlier: &listener.FilterChainMatch{PrefixRanges: []*core.CidrRange{
+				{AddressPrefix: "10.0.0.0", PrefixLen: &wrappers.UInt32Value{Value: 8}},
+			}},
+			later: &listener.FilterChainMatch{PrefixRanges: []*core.CidrRange{
+				{AddressPrefix: "10.1.0.0", PrefixLen: &wrappers.UInt32Value{Value: 16}},
+			}},
+		},
+		{
+			desc:    "overlapping-source-ports",
+			earlier: &listener.FilterChainMatch{SourcePorts: []uint32{8080, 9080}, ApplicationProtocols: []string{"h2", "http/1.1"}},
+			later:   &listener.FilterChainMatch{SourcePorts: []uint32{9080}, ApplicationProtocols: []string{"http/1.1", "h2"}},
+			want:    "overlapping source ports (9080)",
+		},
+		{
+			desc:    "shared-server-names-different-application-protocols",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"a.example.com"}, ApplicationProtocols: []string{"h2"}},
+			later:   &listener.FilterChainMatch{ServerNames: []string{"a.example.com", "b.example.com"}, ApplicationProtocols: []string{"http/1.1"}},
+		},
+		{
+			desc:    "different-server-names",
+			earlier: &listener.FilterChainMatch{ServerNames: []string{"a.example.com"}},
+			later:   &listener.FilterChainMatch{ServerNames: []string{"b.example.com"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := describeFilterChainMatchConflict(normalizeFilterChainMatch(tt.earlier), normalizeFilterChainMatch(tt.later))
+			if got != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_CheckListenerConflicts(t *testing.T) {
+	dump := &adminapi.ListenersConfigDump{}
+	for _, l := range []*listener.Listener{
+		newConflictingListener("0.0.0.0_443", 443,
+			&listener.FilterChainMatch{ServerNames: []string{"a.example.com"}},
+			&listener.FilterChainMatch{ServerNames: []string{"a.example.com"}},
+			&listener.FilterChainMatch{ServerNames: []string{"c.example.com", "b.example.com"}},
+			&listener.FilterChainMatch{ServerNames: []string{"c.example.com"}}),
+		newConflictingListener("0.0.0.0_8080", 8080,
+			&listener.FilterChainMatch{TransportProtocol: "tls", ApplicationProtocols: []string{"h2", "http/1.1"}},
+			&listener.FilterChainMatch{TransportProtocol: "tls", ApplicationProtocols: []string{"h2"}}),
+		newConflictingListener("0.0.0.0_9443", 9443, nil, &listener.FilterChainMatch{TransportProtocol: "tls"}),
+	} {
+		dump.DynamicListeners = append(dump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, l)},
+		})
+	}
+
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, dump)
+	err := cw.CheckListenerConflicts(ListenerFilter{})
+	if err == nil || !strings.Contains(err.Error(), "found 3 listener filter chain conflicts") {
+		t.Errorf("expected an error reporting 3 conflicts, got %v", err)
+	}
+	want := "LISTENER         CHAINS     CONFLICT                                     MATCH\n" +
+		"0.0.0.0_443      0,1        duplicate                                    SNI: a.example.com\n" +
+		"0.0.0.0_443      2,3        overlapping server names (c.example.com)     SNI: c.example.com\n" +
+		"0.0.0.0_8080     0,1        overlapping application protocols (h2)       Trans: tls; App: h2\n"
+	if gotOut.String() != want {
+		t.Errorf("expect %q got %q", want, gotOut.String())
+	}
+
+	gotOut.Reset()
+	cw.OutputFormat = JSON
+	if err := cw.CheckListenerConflicts(ListenerFilter{Port: 8080}); err == nil {
+		t.Errorf("expected an error for the overlapping filter chains")
+	}
+	var conflicts []ListenerConflict
+	if err := json.Unmarshal(gotOut.Bytes(), &conflicts); err != nil {
+		t.Fatal(err)
+	}
+	wantConflicts := []ListenerConflict{{Listener: "0.0.0.0_8080", Chains: [2]int{0, 1}, Conflict: "overlapping application protocols (h2)",
+		Match: "Trans: tls; App: h2"}}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("expect %v got %v", wantConflicts, conflicts)
+	}
+
+	gotOut.Reset()
+	cw.OutputFormat = Table
+	if err := cw.CheckListenerConflicts(ListenerFilter{Port: 9443}); err != nil {
+		t.Errorf("unexpected error for a catch-all before a specific filter chain: %v", err)
+	}
+	if want := "No listener filter chain conflicts found\n"; gotOut.String() != want {
+		t.Errorf("expect %q got %q", want, gotOut.String())
+	}
+}
+
+func TestConfigWriter_CheckListenerConflictsConfigDump(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.CheckListenerConflicts(ListenerFilter{}); err != nil {
+		t.Errorf("unexpected conflicts in config dump: %v\n%s", err, gotOut.String())
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Changes of a resource between two config dumps
+const (
+	resourceAdded   = "added"
+	resourceRemoved = "removed"
+	resourceChanged = "changed"
+)
+
+// ANSI colors of added and removed lines in diffs
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+// diffKinds are the kinds of resources compared by Diff, in the order they are printed
+var diffKinds = []struct {
+	kind string
+	// retrieve returns the resources of the kind in a config dump by name
+	retrieve func(c *ConfigWriter) (map[string]proto.Message, error)
+}{
+	{"listener", func(c *ConfigWriter) (map[string]proto.Message, error) {
+		return c.retrieveListenersByName(ListenerFilter{})
+	}},
+	{"cluster", func(c *ConfigWriter) (map[string]proto.Message, error) {
+		return c.retrieveClustersByName(ClusterFilter{})
+	}},
+	{"route", func(c *ConfigWriter) (map[string]proto.Message, error) {
+		return c.retrieveRoutesByName(RouteFilter{})
+	}},
+	{"endpoint", func(c *ConfigWriter) (map[string]proto.Message, error) {
+		return c.retrieveLoadAssignmentsByName(ClusterFilter{})
+	}},
+}
+
+// ResourceDiff describes how a named resource differs between two config dumps
+type ResourceDiff struct {
+	Name string `json:"name"`
+	// Change is added, removed or changed
+	Change string `json:"change"`
+	// Diff is a unified diff of the JSON of a changed resource
+	Diff string `json:"diff,omitempty"`
+}
+
+// diffResources compares resources by name, reporting the resources of to missing from from as added,
+// the resources of from missing from to as removed, and the resources whose JSON differs as changed.
+// Differences are sorted by name.
+func diffResources(from, to map[string]proto.Message) ([]ResourceDiff, error) {
+	names := make([]string, 0, len(from)+len(to))
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diffs := make([]ResourceDiff, 0)
+	for _, name := range names {
+		fromResource, inFrom := from[name]
+		toResource, inTo := to[name]
+		switch {
+		case !inFrom:
+			diffs = append(diffs, ResourceDiff{Name: name, Change: resourceAdded})
+		case !inTo:
+			diffs = append(diffs, ResourceDiff{Name: name, Change: resourceRemoved})
+		default:
+			diff, err := diffResourceJSON(name, fromResource, toResource)
+			if err != nil {
+				return nil, err
+			}
+			if diff != "" {
+				diffs = append(diffs, ResourceDiff{Name: name, Change: resourceChanged, Diff: diff})
+			}
+		}
+	}
+	return diffs, nil
+}
+
+func diffResourceJSON(name string, from, to proto.Message) (string, error) {
+	jsonm := &jsonpb.Marshaler{Indent: "    "}
+	fromJSON, err := jsonm.MarshalToString(from)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %v", name, err)
+	}
+	toJSON, err := jsonm.MarshalToString(to)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %v", name, err)
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		FromFile: name,
+		A:        difflib.SplitLines(fromJSON),
+		ToFile:   name,
+		B:        difflib.SplitLines(toJSON),
+		Context:  3,
+	})
+}
+
+// printResourceDiffs prints the differences to the ConfigWriter stdout, as +, - and ~ prefixed names followed
+// by the diffs of changed resources or, when the output format of the ConfigWriter is JSON or YAML, as a list
+// of ResourceDiff
+func (c *ConfigWriter) printResourceDiffs(kind string, diffs []ResourceDiff) error {
+	if c.OutputFormat != Table {
+		out, err := json.MarshalIndent(diffs, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s diff: %v", kind, err)
+		}
+		return c.printJSON(out)
+	}
+	c.printResourceDiffTable(kind, diffs)
+	return nil
+}
+
+func (c *ConfigWriter) printResourceDiffTable(kind string, diffs []ResourceDiff) {
+	if len(diffs) == 0 {
+		fmt.Fprintf(c.Stdout, "No %s differences\n", kind)
+		return
+	}
+	for _, diff := range diffs {
+		switch diff.Change {
+		case resourceAdded:
+			fmt.Fprintln(c.Stdout, c.colorize(colorGreen, "+ "+diff.Name))
+		case resourceRemoved:
+			fmt.Fprintln(c.Stdout, c.colorize(colorRed, "- "+diff.Name))
+		case resourceChanged:
+			fmt.Fprintln(c.Stdout, "~ "+diff.Name)
+			for _, line := range strings.SplitAfter(diff.Diff, "\n") {
+				switch {
+				case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+					fmt.Fprint(c.Stdout, c.colorize(colorGreen, line))
+				case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+					fmt.Fprint(c.Stdout, c.colorize(colorRed, line))
+				default:
+					fmt.Fprint(c.Stdout, line)
+				}
+			}
+		}
+	}
+}
+
+// colorize wraps the text in the ANSI color when the ConfigWriter prints in color, keeping a trailing
+// newline outside of the color
+func (c *ConfigWriter) colorize(color, text string) string {
+	if !c.Color || text == "" {
+		return text
+	}
+	trimmed := strings.TrimSuffix(text, "\n")
+	return color + trimmed + colorReset + text[len(trimmed):]
+}
+
+// Diff prints the listeners, clusters, routes and endpoints added, removed or changed in the config dump of the
+// other ConfigWriter compared to the config dump of this one to the ConfigWriter stdout, one section per kind.
+// Endpoints are the load assignments of clusters by cluster name, so the endpoints of EDS clusters are compared when
+// the config dumps have the EDS section, as config dumps requested with config_dump?include_eds do.
+// When the output format is JSON or YAML, the differences are printed as lists of ResourceDiff keyed by kind.
+func (c *ConfigWriter) Diff(other *ConfigWriter) error {
+	all := make(map[string][]ResourceDiff, len(diffKinds))
+	for _, k := range diffKinds {
+		from, err := k.retrieve(c)
+		if err != nil {
+			return err
+		}
+		to, err := k.retrieve(other)
+		if err != nil {
+			return err
+		}
+		diffs, err := diffResources(from, to)
+		if err != nil {
+			return err
+		}
+		all[k.kind] = diffs
+	}
+	if c.OutputFormat != Table {
+		out, err := json.MarshalIndent(all, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal config dump diff: %v", err)
+		}
+		return c.printJSON(out)
+	}
+	for i, k := range diffKinds {
+		if i > 0 {
+			fmt.Fprintln(c.Stdout)
+		}
+		fmt.Fprintf(c.Stdout, "%ss:\n", strings.Title(k.kind))
+		c.printResourceDiffTable(k.kind, all[k.kind])
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/proto"
+)
+
+// newConfigDumpWriter returns a ConfigWriter of a config dump with the listeners and clusters as active, the route
+// configs as dynamic and the other sections as they are, see newConfigWriter
+func newConfigDumpWriter(t testing.TB, out io.Writer, listeners []*listener.Listener, clusters []*cluster.Cluster,
+	routes []*route.RouteConfiguration, sections ...proto.Message) *ConfigWriter {
+	t.Helper()
+	listenerDump := &adminapi.ListenersConfigDump{}
+	for _, l := range listeners {
+		listenerDump.DynamicListeners = append(listenerDump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, l)},
+		})
+	}
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range clusters {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustMarshalAny(t, c),
+		})
+	}
+	routeDump := &adminapi.RoutesConfigDump{}
+	for _, r := range routes {
+		routeDump.DynamicRouteConfigs = append(routeDump.DynamicRouteConfigs, &adminapi.RoutesConfigDump_DynamicRouteConfig{
+			RouteConfig: mustMarshalAny(t, r),
+		})
+	}
+	return newConfigWriter(t, out, append([]proto.Message{listenerDump, clusterDump, routeDump}, sections...)...)
+}
+
+func TestConfigWriter_printResourceDiffs(t *testing.T) {
+	diffs := []ResourceDiff{
+		{Name: "a", Change: resourceAdded},
+		{Name: "b", Change: resourceRemoved},
+		{Name: "c", Change: resourceChanged, Diff: "--- c\n+++ c\n@@ -1 +1 @@\n-old\n+new\n"},
+	}
+	tests := []struct {
+		name  string
+		color bool
+		want  string
+	}{
+		{
+			name: "plain",
+			want: "+ a\n- b\n~ c\n--- c\n+++ c\n@@ -1 +1 @@\n-old\n+new\n",
+		},
+		{
+			name:  "color",
+			color: true,
+			want: "\033[32m+ a\033[0m\n\033[31m- b\033[0m\n~ c\n--- c\n+++ c\n@@ -1 +1 @@\n" +
+				"\033[31m-old\033[0m\n\033[32m+new\033[0m\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut, Color: tt.color}
+			if err := cw.printResourceDiffs("listener", diffs); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("expect %q got %q", tt.want, gotOut.String())
+			}
+		})
+	}
+
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.printResourceDiffs("listener", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "No listener differences\n"; gotOut.String() != want {
+		t.Errorf("expect %q got %q", want, gotOut.String())
+	}
+}
+
+func TestConfigWriter_Diff(t *testing.T) {
+	newListener := func(port uint32) *listener.Listener {
+		l := newSocketListener("0.0.0.0", port)
+		l.Name = fmt.Sprintf("0.0.0.0_%d", port)
+		return l
+	}
+	newRoute := func(name string, domains ...string) *route.RouteConfiguration {
+		return &route.RouteConfiguration{Name: name, VirtualHosts: []*route.VirtualHost{{Name: name, Domains: domains}}}
+	}
+	gotOut := &bytes.Buffer{}
+	before := newConfigDumpWriter(t, gotOut,
+		[]*listener.Listener{newListener(80), newListener(9080)},
+		[]*cluster.Cluster{
+			{Name: "outbound|80||httpbin.org", LoadAssignment: newLoadAssignment(core.HealthStatus_HEALTHY)},
+			{Name: "outbound|9080||reviews.default.svc.cluster.local"},
+		},
+		[]*route.RouteConfiguration{newRoute("80", "httpbin.org"), newRoute("9080", "reviews")})
+	after := newConfigDumpWriter(t, nil,
+		[]*listener.Listener{newListener(15001), newListener(80)},
+		[]*cluster.Cluster{
+			{Name: "outbound|80||httpbin.org", LoadAssignment: newLoadAssignment(core.HealthStatus_HEALTHY, core.HealthStatus_UNHEALTHY)},
+			{Name: "outbound|9080||reviews.default.svc.cluster.local"},
+		},
+		[]*route.RouteConfiguration{newRoute("80", "httpbin.org", "httpbin.org:80"), newRoute("9080", "reviews")})
+
+	if err := before.Diff(before); err != nil {
+		t.Fatal(err)
+	}
+	want := "Listeners:\nNo listener differences\n\nClusters:\nNo cluster differences\n\n" +
+		"Routes:\nNo route differences\n\nEndpoints:\nNo endpoint differences\n"
+	if gotOut.String() != want {
+		t.Errorf("expect %q got %q", want, gotOut.String())
+	}
+
+	gotOut.Reset()
+	if err := before.Diff(after); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Listeners:\n+ 0.0.0.0_15001\n- 0.0.0.0_9080\n\nClusters:\n~ outbound|80||httpbin.org\n",
+		"\nRoutes:\n~ 80\n",
+		`+` + strings.Repeat(" ", 16) + `"httpbin.org:80"`,
+		"\nEndpoints:\n~ outbound|80||httpbin.org\n",
+		`"healthStatus": "UNHEALTHY"`,
+	} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, gotOut.String())
+		}
+	}
+	if strings.Contains(gotOut.String(), "reviews") {
+		t.Errorf("expected the unchanged resources to be left out of:\n%s", gotOut.String())
+	}
+
+	gotOut.Reset()
+	before.OutputFormat = JSON
+	if err := before.Diff(after); err != nil {
+		t.Fatal(err)
+	}
+	var diffs map[string][]ResourceDiff
+	if err := json.Unmarshal(gotOut.Bytes(), &diffs); err != nil {
+		t.Fatalf("diff is not a JSON object: %v\n%s", err, gotOut.String())
+	}
+	if len(diffs) != len(diffKinds) {
+		t.Errorf("expected a list of differences for each of the %d kinds, got %v", len(diffKinds), diffs)
+	}
+	wantListeners := []ResourceDiff{{Name: "0.0.0.0_15001", Change: resourceAdded}, {Name: "0.0.0.0_9080", Change: resourceRemoved}}
+	if !reflect.DeepEqual(diffs["listener"], wantListeners) {
+		t.Errorf("expect %+v got %+v", wantListeners, diffs["listener"])
+	}
+}
+
+func TestConfigWriter_DiffEDSEndpoints(t *testing.T) {
+	reviews := []*cluster.Cluster{{
+		Name:                 "outbound|9080||reviews.default.svc.cluster.local",
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+	}}
+	listeners := []*listener.Listener{newSocketListener("0.0.0.0", 9080)}
+	routes := []*route.RouteConfiguration{{Name: "9080"}}
+	gotOut := &bytes.Buffer{}
+	before := newConfigDumpWriter(t, gotOut, listeners, reviews, routes)
+	before.edsLoadAssignments = map[string]*endpoint.ClusterLoadAssignment{
+		"outbound|9080||reviews.default.svc.cluster.local": newLoadAssignment(core.HealthStatus_HEALTHY),
+	}
+	after := newConfigDumpWriter(t, nil, listeners, reviews, routes)
+	after.edsLoadAssignments = map[string]*endpoint.ClusterLoadAssignment{
+		"outbound|9080||reviews.default.svc.cluster.local": newLoadAssignment(core.HealthStatus_HEALTHY, core.HealthStatus_DRAINING),
+	}
+	if err := before.Diff(after); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\nEndpoints:\n~ outbound|9080||reviews.default.svc.cluster.local\n",
+		`"healthStatus": "DRAINING"`,
+	} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, gotOut.String())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// ecdsConfigDumpTypeURL is the type of the ECDS section of the config dump
+const ecdsConfigDumpTypeURL = "type.googleapis.com/envoy.admin.v3.EcdsConfigDump"
+
+// ecdsConfigDump is the ECDS section of the config dump, holding the extension configs, like Wasm or ext_authz
+// filters, Envoy received over the extension config discovery service.
+type ecdsConfigDump struct {
+	EcdsFilters []ecdsFilter `json:"ecds_filters"`
+}
+
+type ecdsFilter struct {
+	VersionInfo string `json:"version_info"`
+	// EcdsFilter is the TypedExtensionConfig of the extension, kept as JSON so it can be dumped as received
+	EcdsFilter  json.RawMessage `json:"ecds_filter"`
+	LastUpdated *time.Time      `json:"last_updated"`
+}
+
+// typedExtensionConfig is the TypedExtensionConfig of an ECDS filter, with the type of its typed config
+type typedExtensionConfig struct {
+	Name        string `json:"name"`
+	TypedConfig struct {
+		TypeURL string `json:"@type"`
+	} `json:"typed_config"`
+}
+
+// ExtensionConfigFilter is used to pass filter information into the extension config print functions
+type ExtensionConfigFilter struct {
+	Name string
+}
+
+// Verify returns true if the passed extension config name matches the filter fields
+func (f *ExtensionConfigFilter) Verify(name string) bool {
+	return f.Name == "" || f.Name == name
+}
+
+// parseEcdsConfigDump returns the ECDS section of the config dump, or nil when there is none
+func parseEcdsConfigDump(section json.RawMessage) (*ecdsConfigDump, error) {
+	if section == nil {
+		return nil, nil
+	}
+	ecdsDump := &ecdsConfigDump{}
+	if err := json.Unmarshal(section, ecdsDump); err != nil {
+		return nil, err
+	}
+	return ecdsDump, nil
+}
+
+// PrintExtensionConfigSummary prints a summary of the relevant ECDS extension configs in the config dump to the
+// ConfigWriter stdout, with the type of the typed config of each extension
+func (c *ConfigWriter) PrintExtensionConfigSummary(filter ExtensionConfigFilter) error {
+	filters, err := c.retrieveFilteredEcdsFilters(filter)
+	if err != nil {
+		return err
+	}
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tVERSION\tLAST UPDATED")
+	now := time.Now()
+	for _, f := range filters {
+		config, err := f.typedExtensionConfig()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", config.Name, orDash(config.TypedConfig.TypeURL), orDash(f.VersionInfo),
+			formatLastUpdated(f.LastUpdated, now))
+	}
+	return w.Flush()
+}
+
+// PrintExtensionConfigDump prints the typed extension configs of the relevant ECDS extension configs in the config
+// dump to the ConfigWriter stdout, as JSON or YAML depending on the output format
+func (c *ConfigWriter) PrintExtensionConfigDump(filter ExtensionConfigFilter) error {
+	filters, err := c.retrieveFilteredEcdsFilters(filter)
+	if err != nil {
+		return err
+	}
+	configs := make([]json.RawMessage, 0, len(filters))
+	for _, f := range filters {
+		configs = append(configs, f.EcdsFilter)
+	}
+	out, err := json.MarshalIndent(configs, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal extension configs: %v", err)
+	}
+	return c.printJSON(out)
+}
+
+func (c *ConfigWriter) retrieveFilteredEcdsFilters(filter ExtensionConfigFilter) ([]ecdsFilter, error) {
+	if c.configDump == nil {
+		return nil, fmt.Errorf("config writer has not been primed")
+	}
+	if c.ecdsDump == nil {
+		return nil, fmt.Errorf("config dump has no configuration type %s", ecdsConfigDumpTypeURL)
+	}
+	filtered := make([]ecdsFilter, 0, len(c.ecdsDump.EcdsFilters))
+	for _, f := range c.ecdsDump.EcdsFilters {
+		config, err := f.typedExtensionConfig()
+		if err != nil {
+			return nil, err
+		}
+		if filter.Verify(config.Name) {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered, nil
+}
+
+func (f ecdsFilter) typedExtensionConfig() (*typedExtensionConfig, error) {
+	config := &typedExtensionConfig{}
+	if err := json.Unmarshal(f.EcdsFilter, config); err != nil {
+		return nil, fmt.Errorf("failed to read ECDS extension config: %v", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pilot/test/util"
+)
+
+func TestConfigWriter_PrintExtensionConfigSummary(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/ecds.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc   string
+		filter ExtensionConfigFilter
+		want   []string
+		absent []string
+	}{
+		{
+			desc: "all",
+			want: []string{
+				"NAME                     TYPE                                                                        VERSION",
+				"default.stats-filter     type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm",
+				"default.ext-authz        type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz     " +
+					"2020-06-02T09:12:55Z/5     -\n",
+			},
+		},
+		{
+			desc:   "name",
+			filter: ExtensionConfigFilter{Name: "default.ext-authz"},
+			want:   []string{"default.ext-authz"},
+			absent: []string{"default.stats-filter"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut}
+			if err := cw.Prime(cd); err != nil {
+				t.Fatal(err)
+			}
+			if err := cw.PrintExtensionConfigSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(gotOut.String(), want) {
+					t.Errorf("%s: expected %q in:\n%s", tt.desc, want, gotOut.String())
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(gotOut.String(), absent) {
+					t.Errorf("%s: unexpected %q in:\n%s", tt.desc, absent, gotOut.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintExtensionConfigDump(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/ecds.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintExtensionConfigDump(ExtensionConfigFilter{Name: "default.stats-filter"}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/ecdsdump.json", t)
+
+	noEcds, err := ioutil.ReadFile("testdata/clusters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Prime(noEcds); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintExtensionConfigDump(ExtensionConfigFilter{}); err == nil {
+		t.Errorf("expected an error for a config dump without an ECDS section")
+	}
+}
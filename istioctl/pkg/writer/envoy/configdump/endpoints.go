@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+// endpointsConfigDumpTypeURL is the type of the EDS section of the config dump, which Envoy adds to config dumps
+// requested with config_dump?include_eds
+const endpointsConfigDumpTypeURL = "type.googleapis.com/envoy.admin.v3.EndpointsConfigDump"
+
+// parseEndpointsConfigDump returns the load assignments of the EDS section of the config dump by cluster name,
+// or nil when there is none. A cluster both static and dynamic gets the dynamic load assignment.
+func parseEndpointsConfigDump(section json.RawMessage) (map[string]*endpoint.ClusterLoadAssignment, error) {
+	if section == nil {
+		return nil, nil
+	}
+	type endpointConfig struct {
+		EndpointConfig json.RawMessage `json:"endpoint_config"`
+	}
+	endpointsDump := struct {
+		StaticEndpointConfigs  []endpointConfig `json:"static_endpoint_configs"`
+		DynamicEndpointConfigs []endpointConfig `json:"dynamic_endpoint_configs"`
+	}{}
+	if err := json.Unmarshal(section, &endpointsDump); err != nil {
+		return nil, err
+	}
+	loadAssignments := map[string]*endpoint.ClusterLoadAssignment{}
+	for _, c := range append(endpointsDump.StaticEndpointConfigs, endpointsDump.DynamicEndpointConfigs...) {
+		if c.EndpointConfig == nil {
+			continue
+		}
+		// The @type of the load assignment is skipped as an unknown field, so both v2 and v3 are read
+		loadAssignment := &endpoint.ClusterLoadAssignment{}
+		if err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(c.EndpointConfig), loadAssignment); err != nil {
+			return nil, err
+		}
+		loadAssignments[loadAssignment.GetClusterName()] = loadAssignment
+	}
+	return loadAssignments, nil
+}
+
+// retrieveEDSLoadAssignment returns the load assignment of the EDS cluster in the EDS section of the config dump,
+// looked up by the EDS service name of the cluster or else by its name, like Envoy does, or nil when there is none
+func (c *ConfigWriter) retrieveEDSLoadAssignment(cl *cluster.Cluster) *endpoint.ClusterLoadAssignment {
+	if cl.GetType() != cluster.Cluster_EDS {
+		return nil
+	}
+	if serviceName := cl.GetEdsClusterConfig().GetServiceName(); serviceName != "" {
+		return c.edsLoadAssignments[serviceName]
+	}
+	return c.edsLoadAssignments[cl.Name]
+}
+
+// retrieveClusterLoadAssignment returns the load assignment of the EDS section of the config dump for EDS clusters,
+// see retrieveEDSLoadAssignment, or else the load assignment of the cluster, or nil when neither has one
+func (c *ConfigWriter) retrieveClusterLoadAssignment(cl *cluster.Cluster) *endpoint.ClusterLoadAssignment {
+	if loadAssignment := c.retrieveEDSLoadAssignment(cl); loadAssignment != nil {
+		return loadAssignment
+	}
+	return cl.GetLoadAssignment()
+}
+
+// GetClusterLoadAssignments returns the load assignments of the clusters in the config dump matching the filter by
+// cluster name, see retrieveClusterLoadAssignment. The load assignments of EDS clusters are only known when the
+// config dump was requested with config_dump?include_eds.
+func (c *ConfigWriter) GetClusterLoadAssignments(filter ClusterFilter) (map[string]*endpoint.ClusterLoadAssignment, error) {
+	clusters, err := c.retrieveClustersByName(filter)
+	if err != nil {
+		return nil, err
+	}
+	loadAssignments := make(map[string]*endpoint.ClusterLoadAssignment, len(clusters))
+	for name, resource := range clusters {
+		if loadAssignment := c.retrieveClusterLoadAssignment(resource.(*cluster.Cluster)); loadAssignment != nil {
+			loadAssignments[name] = loadAssignment
+		}
+	}
+	return loadAssignments, nil
+}
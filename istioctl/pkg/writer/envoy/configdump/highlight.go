@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ANSI colors of the keys, strings and numbers of highlighted JSON and YAML
+const (
+	colorKey    = "\033[36m"
+	colorString = colorGreen
+	colorNumber = "\033[33m"
+)
+
+// yamlBlockScalarIndicators start the multi-line strings of YAML, whose lines are highlighted as strings
+var yamlBlockScalarIndicators = map[string]bool{"|": true, "|-": true, "|+": true, ">": true, ">-": true, ">+": true}
+
+// highlightJSON colors the keys, strings and numbers of valid JSON
+func highlightJSON(out []byte) []byte {
+	highlighted := &bytes.Buffer{}
+	for i := 0; i < len(out); {
+		switch ch := out[i]; {
+		case ch == '"':
+			end := endOfJSONString(out, i)
+			color := colorString
+			if isJSONKey(out, end) {
+				color = colorKey
+			}
+			writeColored(highlighted, color, string(out[i:end]))
+			i = end
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			end := i + 1
+			for end < len(out) && strings.IndexByte("0123456789.eE+-", out[end]) >= 0 {
+				end++
+			}
+			writeColored(highlighted, colorNumber, string(out[i:end]))
+			i = end
+		default:
+			highlighted.WriteByte(ch)
+			i++
+		}
+	}
+	return highlighted.Bytes()
+}
+
+// endOfJSONString returns the index following the closing quote of the JSON string starting at start
+func endOfJSONString(out []byte, start int) int {
+	for i := start + 1; i < len(out); i++ {
+		switch out[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(out)
+}
+
+// isJSONKey returns true if the JSON string ending before end is followed by a colon, as object keys are
+func isJSONKey(out []byte, end int) bool {
+	rest := bytes.TrimLeft(out[end:], " \t\r\n")
+	return len(rest) > 0 && rest[0] == ':'
+}
+
+// highlightYAML colors the keys, strings and numbers of YAML, line by line as sigs.k8s.io/yaml writes it: one
+// key or list item per line, with multi-line strings written as block scalars
+func highlightYAML(out []byte) []byte {
+	lines := strings.SplitAfter(string(out), "\n")
+	highlighted := &bytes.Buffer{}
+	// blockIndent is the indent of the key of the block scalar being highlighted, or -1 outside block scalars
+	blockIndent := -1
+	for _, line := range lines {
+		content := strings.TrimRight(line, "\n")
+		newline := line[len(content):]
+		body := strings.TrimLeft(content, " ")
+		indent := len(content) - len(body)
+		if blockIndent >= 0 {
+			if body == "" || indent > blockIndent {
+				highlighted.WriteString(content[:indent])
+				writeColored(highlighted, colorString, body)
+				highlighted.WriteString(newline)
+				continue
+			}
+			blockIndent = -1
+		}
+		highlighted.WriteString(content[:indent])
+		lineIndent := indent
+		// List items like "- - name: foo" nest their keys after the dashes
+		for strings.HasPrefix(body, "- ") || body == "-" {
+			highlighted.WriteString(body[:1])
+			body = body[1:]
+			indent++
+			trimmed := strings.TrimLeft(body, " ")
+			highlighted.WriteString(body[:len(body)-len(trimmed)])
+			indent += len(body) - len(trimmed)
+			body = trimmed
+		}
+		key, value := splitYAMLKey(body)
+		if key != "" {
+			writeColored(highlighted, colorKey, key)
+			highlighted.WriteString(":")
+			if value != "" {
+				highlighted.WriteString(" ")
+			}
+		}
+		if yamlBlockScalarIndicators[value] {
+			highlighted.WriteString(value)
+			// The lines of a block scalar are indented past its key, or past the dash of a list item without one
+			blockIndent = indent
+			if key == "" {
+				blockIndent = lineIndent
+			}
+		} else {
+			writeYAMLScalar(highlighted, value)
+		}
+		highlighted.WriteString(newline)
+	}
+	return highlighted.Bytes()
+}
+
+// splitYAMLKey splits a YAML line into its key and value, returning no key for list items with a scalar value
+func splitYAMLKey(body string) (string, string) {
+	end := 0
+	if strings.HasPrefix(body, `"`) || strings.HasPrefix(body, "'") {
+		end = endOfYAMLQuotedString(body)
+	}
+	for i := end; i < len(body); i++ {
+		if body[i] == ':' && (i+1 == len(body) || body[i+1] == ' ') {
+			return body[:i], strings.TrimLeft(body[i+1:], " ")
+		}
+	}
+	return "", body
+}
+
+// endOfYAMLQuotedString returns the index following the closing quote of the quoted YAML string at the start of body
+func endOfYAMLQuotedString(body string) int {
+	quote := body[0]
+	for i := 1; i < len(body); i++ {
+		switch {
+		case quote == '"' && body[i] == '\\':
+			i++
+		case body[i] == quote && quote == '\'' && i+1 < len(body) && body[i+1] == '\'':
+			// Single quotes are escaped by doubling them
+			i++
+		case body[i] == quote:
+			return i + 1
+		}
+	}
+	return len(body)
+}
+
+// writeYAMLScalar writes a YAML scalar value, colored as a number or a string. Booleans, nulls and the empty
+// collections {} and [] are left as they are.
+func writeYAMLScalar(highlighted *bytes.Buffer, value string) {
+	switch value {
+	case "", "true", "false", "null", "{}", "[]":
+		highlighted.WriteString(value)
+		return
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		writeColored(highlighted, colorNumber, value)
+		return
+	}
+	writeColored(highlighted, colorString, value)
+}
+
+func writeColored(highlighted *bytes.Buffer, color, text string) {
+	if text == "" {
+		return
+	}
+	highlighted.WriteString(color)
+	highlighted.WriteString(text)
+	highlighted.WriteString(colorReset)
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"testing"
+)
+
+func highlightedKey(text string) string {
+	return colorKey + text + colorReset
+}
+
+func highlightedString(text string) string {
+	return colorString + text + colorReset
+}
+
+func highlightedNumber(text string) string {
+	return colorNumber + text + colorReset
+}
+
+func TestHighlightJSON(t *testing.T) {
+	in := `{
+    "name": "foo",
+    "port": 8080,
+    "weight": -1.5,
+    "enabled": true,
+    "tags": ["a\"b: c"]
+}`
+	want := "{\n    " +
+		highlightedKey(`"name"`) + ": " + highlightedString(`"foo"`) + ",\n    " +
+		highlightedKey(`"port"`) + ": " + highlightedNumber("8080") + ",\n    " +
+		highlightedKey(`"weight"`) + ": " + highlightedNumber("-1.5") + ",\n    " +
+		highlightedKey(`"enabled"`) + ": true,\n    " +
+		highlightedKey(`"tags"`) + ": [" + highlightedString(`"a\"b: c"`) + "]\n}"
+	if got := string(highlightJSON([]byte(in))); got != want {
+		t.Errorf("expect:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestHighlightYAML(t *testing.T) {
+	in := `name: foo
+port: 8080
+enabled: true
+url: http://example.com
+filters:
+- name: envoy.router
+  typed_config: {}
+- "quoted: key": 1
+script: |
+  line: one
+  two
+tags:
+- a
+after: 'it''s'
+`
+	want := highlightedKey("name") + ": " + highlightedString("foo") + "\n" +
+		highlightedKey("port") + ": " + highlightedNumber("8080") + "\n" +
+		highlightedKey("enabled") + ": true\n" +
+		highlightedKey("url") + ": " + highlightedString("http://example.com") + "\n" +
+		highlightedKey("filters") + ":\n" +
+		"- " + highlightedKey("name") + ": " + highlightedString("envoy.router") + "\n" +
+		"  " + highlightedKey("typed_config") + ": {}\n" +
+		"- " + highlightedKey(`"quoted: key"`) + ": " + highlightedNumber("1") + "\n" +
+		highlightedKey("script") + ": |\n" +
+		"  " + highlightedString("line: one") + "\n" +
+		"  " + highlightedString("two") + "\n" +
+		highlightedKey("tags") + ":\n" +
+		"- " + highlightedString("a") + "\n" +
+		highlightedKey("after") + ": " + highlightedString(`'it''s'`) + "\n"
+	if got := string(highlightYAML([]byte(in))); got != want {
+		t.Errorf("expect:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestConfigWriter_printJSONHighlight(t *testing.T) {
+	tests := []struct {
+		desc      string
+		format    Format
+		highlight bool
+		want      string
+	}{
+		{
+			desc: "off-by-default",
+			want: "{\"port\": 80}\n",
+		},
+		{
+			desc:      "json",
+			highlight: true,
+			want:      "{" + highlightedKey(`"port"`) + ": " + highlightedNumber("80") + "}\n",
+		},
+		{
+			desc:      "yaml",
+			format:    YAML,
+			highlight: true,
+			want:      highlightedKey("port") + ": " + highlightedNumber("80") + "\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut, OutputFormat: tt.format, Highlight: tt.highlight}
+			if err := cw.printJSON([]byte(`{"port": 80}`)); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
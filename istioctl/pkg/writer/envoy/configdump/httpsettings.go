@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// defaultHTTPSetting is shown for HTTP connection manager settings left to the Envoy default
+const defaultHTTPSetting = "(default)"
+
+// PrintListenerHTTPSettings prints the HTTP connection manager settings that commonly break gateways, such as the
+// number of trusted X-Forwarded-For hops, of each HTTP filter chain of the relevant listeners to the ConfigWriter
+// stdout. Settings the config leaves unset are shown as (default).
+func (c *ConfigWriter) PrintListenerHTTPSettings(filter ListenerFilter) error {
+	w, listeners, err := c.setupListenerConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "LISTENER\tCHAIN\tMATCH\tXFF TRUSTED HOPS\tUSE REMOTE ADDRESS\tNORMALIZE PATH\tIDLE TIMEOUT\tREQUEST TIMEOUT")
+	seen := map[string]bool{}
+	for _, l := range listeners {
+		// A listener is printed once, in its first state
+		if seen[l.Name] {
+			continue
+		}
+		seen[l.Name] = true
+		for i, filterChain := range l.GetFilterChains() {
+			for _, networkFilter := range filterChain.GetFilters() {
+				if !isHTTPConnectionManager(networkFilter) {
+					continue
+				}
+				httpConnectionManager, err := retrieveHTTPConnectionManager(networkFilter)
+				if err != nil {
+					return fmt.Errorf("failed to read the HTTP connection manager of listener %s: %v", l.Name, err)
+				}
+				xffNumTrustedHops := defaultHTTPSetting
+				if httpConnectionManager.GetXffNumTrustedHops() != 0 {
+					xffNumTrustedHops = strconv.Itoa(int(httpConnectionManager.GetXffNumTrustedHops()))
+				}
+				fmt.Fprintf(w, "%v\t%d\t%v\t%v\t%v\t%v\t%v\t%v\n", l.Name, i, describeFilterChainMatch(filterChain.GetFilterChainMatch()),
+					xffNumTrustedHops,
+					formatBoolSetting(httpConnectionManager.GetUseRemoteAddress()),
+					formatBoolSetting(httpConnectionManager.GetNormalizePath()),
+					formatDurationSetting(httpConnectionManager.GetCommonHttpProtocolOptions().GetIdleTimeout()),
+					formatDurationSetting(httpConnectionManager.GetRequestTimeout()))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func formatBoolSetting(value *wrappers.BoolValue) string {
+	if value == nil {
+		return defaultHTTPSetting
+	}
+	return strconv.FormatBool(value.GetValue())
+}
+
+func formatDurationSetting(value *duration.Duration) string {
+	if value == nil {
+		return defaultHTTPSetting
+	}
+	d, err := ptypes.Duration(value)
+	if err != nil {
+		return defaultHTTPSetting
+	}
+	return d.String()
+}
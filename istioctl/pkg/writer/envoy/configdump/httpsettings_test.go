@@ -0,0 +1,40 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"istio.io/istio/pilot/test/util"
+)
+
+func TestConfigWriter_PrintListenerHTTPSettings(t *testing.T) {
+	// The gateway trusts two proxies in front of it, as set by the gatewayTopology.numTrustedProxies mesh config
+	cd, err := ioutil.ReadFile("testdata/listenersgateway.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerHTTPSettings(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/listenerhttpsettings.txt", t)
+}
@@ -15,127 +15,1743 @@
 package configdump
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacconfig "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	fileaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	grpcaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	jwtauthn "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/jwt_authn/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	rbactcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"k8s.io/apimachinery/pkg/util/duration"
 
-	protio "istio.io/istio/istioctl/pkg/util/proto"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
 	"istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/proxy/envoy/v3"
+	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/util/strcase"
 )
 
+// listenerTypeInternal is the type filter selecting the internal listeners Envoy hands tunneled connections, like
+// HBONE, to, see isInternalListener
+const listenerTypeInternal = "INTERNAL"
+
 const (
 	// HTTPListener identifies a listener as being of HTTP type by the presence of an HTTP connection manager filter
 	HTTPListener = "envoy.http_connection_manager"
 
-	// TCPListener identifies a listener as being of TCP type by the presence of TCP proxy filter
-	TCPListener = "envoy.tcp_proxy"
-)
+	// HTTPListenerCanonical is the canonical name of the HTTP connection manager filter in newer Envoy versions
+	HTTPListenerCanonical = "envoy.filters.network.http_connection_manager"
+
+	// TCPListener identifies a listener as being of TCP type by the presence of TCP proxy filter
+	TCPListener = "envoy.tcp_proxy"
+
+	// TCPListenerCanonical is the canonical name of the TCP proxy filter in newer Envoy versions
+	TCPListenerCanonical = "envoy.filters.network.tcp_proxy"
+
+	// UDPListener identifies a listener as being of UDP type by the presence of the UDP proxy listener filter
+	UDPListener = "envoy.filters.udp_listener.udp_proxy"
+
+	// quicListener is the UDP listener implementation terminating QUIC connections for HTTP/3
+	quicListener = "quiche_quic_listener"
+
+	// quicTransportSocket is the transport socket of filter chains accepting QUIC connections
+	quicTransportSocket = "envoy.transport_sockets.quic"
+
+	// originalDstListenerFilters hand connections off to the listener matching their original destination
+	originalDstListenerFilter          = "envoy.listener.original_dst"
+	originalDstListenerFilterCanonical = "envoy.filters.listener.original_dst"
+
+	// httpConnectionManagerTypeURL is the v3 type of the HTTP connection manager filter config
+	httpConnectionManagerTypeURL = "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager"
+
+	// tcpProxyTypeURL is the v3 type of the TCP proxy filter config
+	tcpProxyTypeURL = "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy"
+
+	// v2 types of the same filter configs, still sent by older control planes
+	httpConnectionManagerV2TypeURL = "type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager"
+	tcpProxyV2TypeURL              = "type.googleapis.com/envoy.config.filter.network.tcp_proxy.v2.TcpProxy"
+
+	// downstreamTLSContextTypeURL is the v3 type of the TLS transport socket config of a filter chain
+	downstreamTLSContextTypeURL   = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext"
+	downstreamTLSContextV2TypeURL = "type.googleapis.com/envoy.api.v2.auth.DownstreamTlsContext"
+
+	// v3 types of the file and gRPC access log sink configs
+	fileAccessLogTypeURL     = "type.googleapis.com/envoy.extensions.access_loggers.file.v3.FileAccessLog"
+	httpGrpcAccessLogTypeURL = "type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig"
+	tcpGrpcAccessLogTypeURL  = "type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.TcpGrpcAccessLogConfig"
+
+	// v3 types of the request authentication and authorization filter configs
+	jwtAuthenticationTypeURL = "type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication"
+	httpRBACTypeURL          = "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC"
+	networkRBACTypeURL       = "type.googleapis.com/envoy.extensions.filters.network.rbac.v3.RBAC"
+)
+
+// defaultListenerFiltersTimeout is how long Envoy waits for the listener filters of a listener by default
+const defaultListenerFiltersTimeout = 15 * time.Second
+
+// Ports of the listeners a sidecar adds for outbound traffic capture, Prometheus scraping and health checks
+const (
+	virtualOutboundListenerPort = 15001
+	prometheusListenerPort      = 15090
+	healthListenerPort          = 15021
+)
+
+// Keys listeners can be sorted by
+const (
+	sortByPort    = "port"
+	sortByAddress = "address"
+	sortByName    = "name"
+)
+
+// TLS modes of listener filter chains
+const (
+	tlsModeMutual     = "mTLS"
+	tlsModeSimple     = "TLS"
+	tlsModePermissive = "PERMISSIVE"
+	tlsModeDisable    = "DISABLE"
+)
+
+// Destinations of filter chains forwarding to the clusters Istio uses to drop traffic or to send it on to
+// its original destination
+const (
+	destinationBlackHole   = "blackhole"
+	destinationPassthrough = "passthrough"
+)
+
+// maxDestinationsWidth is the widest the destinations of a listener are printed in the summary
+const maxDestinationsWidth = 60
+
+const (
+	listenerStateActive   = "ACTIVE"
+	listenerStateWarming  = "WARMING"
+	listenerStateDraining = "DRAINING"
+	listenerStateStatic   = "STATIC"
+)
+
+// Origins of listeners, either the bootstrap config or LDS
+const (
+	listenerOriginStatic  = "STATIC"
+	listenerOriginDynamic = "DYNAMIC"
+)
+
+// listenerWithState is a listener along with the config dump state it was found in
+type listenerWithState struct {
+	*listener.Listener
+	state       string
+	versionInfo string
+	lastUpdated *timestamp.Timestamp
+	// additionalAddresses are the addresses the listener is bound to besides its primary address, see
+	// parseListenerAdditionalAddresses
+	additionalAddresses []*core.Address
+}
+
+// PortRange is an inclusive range of listener ports
+type PortRange struct {
+	Min uint32
+	Max uint32
+}
+
+// ListenerFilter is used to pass filter information into listener based config writer print functions
+type ListenerFilter struct {
+	// Name matches the listener name exactly, or as a regular expression when prefixed with ~
+	Name string
+	// NameContains selects listeners with a name containing the value, such as the service host in
+	// outbound|8080||foo.bar.svc.cluster.local
+	NameContains string
+	// Address matches the listener address or pipe path exactly, or any address inside the range when given in CIDR notation
+	Address string
+	// AddressRegex matches the listener address against a regular expression
+	AddressRegex string
+	// CIDR selects listeners with a socket address inside the prefix, IPv4 or IPv6
+	CIDR string
+	Port uint32
+	// Ports selects listeners with any of the ports, it cannot be combined with Port
+	Ports []uint32
+	// PortRange selects listeners with a port inside the range, it cannot be combined with Port or Ports
+	PortRange *PortRange
+	Type      string
+	Direction model.TrafficDirection
+	// SNI selects listeners with a filter chain matching the server name, wildcards are supported
+	SNI string
+	// TLSMode selects listeners by the TLS mode of their filter chains: mTLS, TLS, PERMISSIVE or DISABLE
+	TLSMode string
+	// FilterName selects listeners with a network filter, or an HTTP filter of an HTTP connection manager,
+	// with a name containing the value
+	FilterName string
+	// Verbose prints one summary row per filter chain instead of one per listener
+	Verbose bool
+	// Wide adds the name, protocol, origin, TLS mode and route configs of each listener, and the match and
+	// destination of each of its filter chains, to the default summary columns
+	Wide bool
+	// Chains adds the number of filter chains of each listener to the summary
+	Chains bool
+	// Origin selects listeners from the bootstrap config when STATIC, or received over LDS when DYNAMIC
+	Origin string
+	// LastUpdated adds how long ago each dynamic listener was last updated to the summary
+	LastUpdated bool
+	// UpdatedSince selects dynamic listeners last updated within the duration, static listeners are never selected
+	UpdatedSince time.Duration
+	// ExpandInbound summarizes each filter chain of the virtual inbound listener on its own row
+	ExpandInbound bool
+	// SortBy orders listeners by port, address or name, port being the default
+	SortBy string
+	// Columns restricts the summary to the named columns in the passed order, in place of the columns added by
+	// Verbose, Wide and the other summary options. See listenerSummaryColumns for the valid names.
+	Columns []string
+	// SkipVirtual drops the traffic capture, Prometheus and health check listeners Istio adds to every proxy
+	SkipVirtual bool
+	// Internal selects the internal listeners Envoy uses for tunneling, which have no socket or pipe address
+	Internal bool
+	// SkipInternal drops the internal listeners
+	SkipInternal bool
+	// Offset skips the first listeners, after filtering and sorting, in the summary and dump
+	Offset int
+	// Limit shows at most that many listeners after the offset in the summary and dump, all of them when 0.
+	// The summary table ends with the number of listeners left out.
+	Limit int
+	// Strict makes the dump warn about the typed configs of the listeners with unknown or deprecated types,
+	// and fail if there are any
+	Strict bool
+
+	nameRegex    *regexp.Regexp
+	addressRegex *regexp.Regexp
+	addressCIDR  *net.IPNet
+	cidr         *net.IPNet
+}
+
+// Validate returns an error if the filter fields are malformed
+func (l *ListenerFilter) Validate() error {
+	switch l.SortBy {
+	case "", sortByPort, sortByAddress, sortByName:
+	default:
+		return fmt.Errorf("cannot sort listeners by %q, expected one of %s, %s or %s", l.SortBy, sortByPort, sortByAddress, sortByName)
+	}
+	for _, column := range l.Columns {
+		if retrieveListenerSummaryColumn(column) == nil {
+			return fmt.Errorf("unknown listener summary column %q, expected one of %s", column, strings.Join(listenerSummaryColumnNames(), ", "))
+		}
+	}
+	if l.Origin != "" && !strings.EqualFold(l.Origin, listenerOriginStatic) && !strings.EqualFold(l.Origin, listenerOriginDynamic) {
+		return fmt.Errorf("invalid listener origin %q, expected %s or %s", l.Origin, listenerOriginStatic, listenerOriginDynamic)
+	}
+	if l.UpdatedSince < 0 {
+		return fmt.Errorf("invalid listener update age %v", l.UpdatedSince)
+	}
+	if l.Limit < 0 || l.Offset < 0 {
+		return fmt.Errorf("invalid listener limit %d or offset %d, they cannot be negative", l.Limit, l.Offset)
+	}
+	if l.Port != 0 && len(l.Ports) > 0 {
+		return fmt.Errorf("listener port %d and ports %v cannot both be set", l.Port, l.Ports)
+	}
+	if l.PortRange != nil {
+		if l.Port != 0 {
+			return fmt.Errorf("listener port %d and port range %d-%d cannot both be set", l.Port, l.PortRange.Min, l.PortRange.Max)
+		}
+		if len(l.Ports) > 0 {
+			return fmt.Errorf("listener ports %v and port range %d-%d cannot both be set", l.Ports, l.PortRange.Min, l.PortRange.Max)
+		}
+		if l.PortRange.Min > l.PortRange.Max {
+			return fmt.Errorf("invalid listener port range %d-%d", l.PortRange.Min, l.PortRange.Max)
+		}
+	}
+	if strings.HasPrefix(l.Name, "~") {
+		nameRegex, err := compileAnchoredPattern(l.Name[1:])
+		if err != nil {
+			return fmt.Errorf("invalid listener name pattern %q: %v", l.Name[1:], err)
+		}
+		l.nameRegex = nameRegex
+	}
+	if l.AddressRegex != "" {
+		addressRegex, err := compileAnchoredPattern(l.AddressRegex)
+		if err != nil {
+			return fmt.Errorf("invalid listener address pattern %q: %v", l.AddressRegex, err)
+		}
+		l.addressRegex = addressRegex
+	}
+	if isAddressRange(l.Address) {
+		_, addressCIDR, err := net.ParseCIDR(l.Address)
+		if err != nil {
+			return fmt.Errorf("invalid listener address range %q: %v", l.Address, err)
+		}
+		l.addressCIDR = addressCIDR
+	}
+	if l.CIDR != "" {
+		_, cidr, err := net.ParseCIDR(l.CIDR)
+		if err != nil {
+			return fmt.Errorf("invalid listener CIDR %q: %v", l.CIDR, err)
+		}
+		l.cidr = cidr
+	}
+	return nil
+}
+
+// Verify returns true if the passed listener matches the filter fields
+func (l *ListenerFilter) Verify(listener *listener.Listener) bool {
+	return l.verify(listener, nil)
+}
+
+// verifyType returns true if the listener is of the type of the filter, see retrieveListenerType. The INTERNAL type
+// selects the internal listeners whatever their protocol.
+func (l *ListenerFilter) verifyType(listener *listener.Listener) bool {
+	if strings.EqualFold(l.Type, listenerTypeInternal) {
+		return isInternalListener(listener)
+	}
+	return strings.EqualFold(retrieveListenerType(listener), l.Type)
+}
+
+// verify returns true if the passed listener, bound to the additional addresses besides its primary address,
+// matches the filter fields
+func (l *ListenerFilter) verify(listener *listener.Listener, additionalAddresses []*core.Address) bool {
+	if !l.hasHeaderFields() && l.Type == "" && l.Direction == "" && l.SNI == "" && l.TLSMode == "" && l.FilterName == "" {
+		return true
+	}
+	if !l.verifyHeader(listener, additionalAddresses) {
+		return false
+	}
+	if l.Type != "" && !l.verifyType(listener) {
+		return false
+	}
+	// The shared virtual inbound listener serves traffic in both directions, so any direction selects it
+	if l.Direction != "" && !isVirtualInboundListener(listener) &&
+		!strings.EqualFold(retrieveListenerDirection(listener), string(l.Direction)) {
+		return false
+	}
+	if l.SNI != "" && len(retrieveMatchingServerNames(listener, l.SNI)) == 0 {
+		return false
+	}
+	if l.TLSMode != "" && !strings.EqualFold(retrieveListenerTLSMode(listener), l.TLSMode) {
+		return false
+	}
+	if l.FilterName != "" && len(retrieveMatchingFilterNames(listener, l.FilterName)) == 0 {
+		return false
+	}
+	return true
+}
+
+// hasHeaderFields returns true if the filter has fields verifyHeader checks
+func (l *ListenerFilter) hasHeaderFields() bool {
+	return l.Name != "" || l.NameContains != "" || l.Address != "" || l.AddressRegex != "" || l.CIDR != "" ||
+		l.Port != 0 || len(l.Ports) > 0 || l.PortRange != nil || l.SkipVirtual || l.Internal || l.SkipInternal
+}
+
+// verifyHeader returns true if the name and addresses of the passed listener match the filter fields, so a
+// listener of which only those were unmarshalled can be left out before its filter chains are unmarshalled
+func (l *ListenerFilter) verifyHeader(listener *listener.Listener, additionalAddresses []*core.Address) bool {
+	if l.SkipVirtual && isVirtualListener(listener) {
+		return false
+	}
+	if l.Internal && !isInternalListener(listener) {
+		return false
+	}
+	if l.SkipInternal && isInternalListener(listener) {
+		return false
+	}
+	if l.Name != "" && !l.verifyName(listener.Name) {
+		return false
+	}
+	if l.NameContains != "" && !strings.Contains(listener.Name, l.NameContains) {
+		return false
+	}
+	if l.Address != "" && !matchesAnyAddress(listener, additionalAddresses, l.verifyAddress) {
+		return false
+	}
+	if l.AddressRegex != "" && !matchesAnyAddress(listener, additionalAddresses, l.verifyAddressRegex) {
+		return false
+	}
+	if l.CIDR != "" && !matchesAnyAddress(listener, additionalAddresses, l.verifyCIDR) {
+		return false
+	}
+	if l.Port != 0 && retrieveListenerPort(listener) != l.Port {
+		return false
+	}
+	if len(l.Ports) > 0 && !containsPort(l.Ports, retrieveListenerPort(listener)) {
+		return false
+	}
+	if l.PortRange != nil {
+		if port := retrieveListenerPort(listener); port < l.PortRange.Min || port > l.PortRange.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// retrieveListenerOrigin returns whether a listener comes from the bootstrap config or from LDS
+func retrieveListenerOrigin(l *listenerWithState) string {
+	if l.state == listenerStateStatic {
+		return listenerOriginStatic
+	}
+	return listenerOriginDynamic
+}
+
+// verifyUpdatedSince returns true if the dynamic listener was last updated within UpdatedSince of now
+func (l *ListenerFilter) verifyUpdatedSince(listener *listenerWithState, now time.Time) bool {
+	lastUpdated := retrieveListenerLastUpdated(listener)
+	return lastUpdated != nil && now.Sub(*lastUpdated) <= l.UpdatedSince
+}
+
+// matchesAnyAddress returns true if any of the addresses a listener is bound to matches
+func matchesAnyAddress(listener *listener.Listener, additionalAddresses []*core.Address, match func(address string) bool) bool {
+	for _, address := range retrieveListenerAddresses(listener, additionalAddresses) {
+		if match(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPort(ports []uint32, port uint32) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *ListenerFilter) verifyName(name string) bool {
+	if !strings.HasPrefix(l.Name, "~") {
+		return name == l.Name
+	}
+	if l.nameRegex == nil {
+		nameRegex, err := compileAnchoredPattern(l.Name[1:])
+		if err != nil {
+			return false
+		}
+		l.nameRegex = nameRegex
+	}
+	return l.nameRegex.MatchString(name)
+}
+
+// verifyAddress matches an address or pipe path exactly, or an address by CIDR range. Wildcard listener
+// addresses get no special treatment, so 0.0.0.0 is only selected by a range that contains it.
+func (l *ListenerFilter) verifyAddress(address string) bool {
+	if !isAddressRange(l.Address) {
+		return equalAddresses(address, l.Address)
+	}
+	if l.addressCIDR == nil {
+		_, addressCIDR, err := net.ParseCIDR(l.Address)
+		if err != nil {
+			return false
+		}
+		l.addressCIDR = addressCIDR
+	}
+	return containsAddress(l.addressCIDR, address)
+}
+
+// equalAddresses compares IP addresses canonically, so ::1 equals 0:0:0:0:0:0:0:1, and anything else as text
+func equalAddresses(a, b string) bool {
+	if aIP, bIP := net.ParseIP(a), net.ParseIP(b); aIP != nil && bIP != nil {
+		return aIP.Equal(bIP)
+	}
+	return strings.EqualFold(a, b)
+}
+
+// isAddressRange returns true if the address filter is a CIDR range rather than an address or the path of a
+// pipe listener, which is absolute or, for abstract sockets, starts with @
+func isAddressRange(address string) bool {
+	return strings.Contains(address, "/") && !strings.HasPrefix(address, "/") && !strings.HasPrefix(address, "@")
+}
+
+func (l *ListenerFilter) verifyAddressRegex(address string) bool {
+	if l.addressRegex == nil {
+		addressRegex, err := compileAnchoredPattern(l.AddressRegex)
+		if err != nil {
+			return false
+		}
+		l.addressRegex = addressRegex
+	}
+	return l.addressRegex.MatchString(address)
+}
+
+func (l *ListenerFilter) verifyCIDR(address string) bool {
+	if l.cidr == nil {
+		_, cidr, err := net.ParseCIDR(l.CIDR)
+		if err != nil {
+			return false
+		}
+		l.cidr = cidr
+	}
+	return containsAddress(l.cidr, address)
+}
+
+// containsAddress returns true if the address is an IP inside the CIDR, pipe paths are never contained
+func containsAddress(cidr *net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && cidr.Contains(ip)
+}
+
+// compileAnchoredPattern compiles a regular expression that must match the whole value
+func compileAnchoredPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// retrieveMatchingServerNames returns the filter chain server names of a listener matching the passed SNI
+func retrieveMatchingServerNames(l *listener.Listener, sni string) []string {
+	matched := make([]string, 0)
+	for _, filterChain := range l.GetFilterChains() {
+		for _, serverName := range filterChain.GetFilterChainMatch().GetServerNames() {
+			if host.Name(strings.ToLower(serverName)).Matches(host.Name(strings.ToLower(sni))) {
+				matched = append(matched, serverName)
+			}
+		}
+	}
+	return matched
+}
+
+// retrieveMatchingFilterNames returns the distinct names of the network filters and HTTP filters of a listener
+// containing the passed name
+func retrieveMatchingFilterNames(l *listener.Listener, name string) []string {
+	seen := map[string]bool{}
+	matched := make([]string, 0)
+	match := func(filterName string) {
+		if strings.Contains(filterName, name) && !seen[filterName] {
+			seen[filterName] = true
+			matched = append(matched, filterName)
+		}
+	}
+	for _, filterChain := range l.GetFilterChains() {
+		for _, filter := range filterChain.GetFilters() {
+			match(filter.Name)
+			if !isHTTPConnectionManager(filter) {
+				continue
+			}
+			httpConnectionManager, err := retrieveHTTPConnectionManager(filter)
+			if err != nil {
+				continue
+			}
+			for _, httpFilter := range httpConnectionManager.GetHttpFilters() {
+				match(httpFilter.GetName())
+			}
+		}
+	}
+	return matched
+}
+
+// retrieveListenerType classifies a Listener as HTTP|HTTP3|TCP|UDP or a combination such as HTTP+TCP, or UNKNOWN.
+// HTTP connection managers of QUIC listeners serve HTTP/3, other UDP listeners are UDP. TCP proxies forwarding to
+// the blackhole or passthrough clusters are not counted as TCP, so a listener with nothing else is PASSTHROUGH
+// or TCP (blackhole).
+func retrieveListenerType(l *listener.Listener) string {
+	nHTTP := 0
+	nTCP := 0
+	nUDP := 0
+	nBlackHole := 0
+	nPassthrough := 0
+	for _, listenerFilter := range l.GetListenerFilters() {
+		if listenerFilter.Name == UDPListener {
+			nUDP++
+		}
+	}
+	for _, filterChain := range l.GetFilterChains() {
+		for _, filter := range filterChain.GetFilters() {
+			if isHTTPConnectionManager(filter) {
+				nHTTP++
+			} else if isTCPProxy(filter) {
+				switch describeCatchAllDestination(retrieveTCPProxyCluster(filter)) {
+				case destinationBlackHole:
+					nBlackHole++
+				case destinationPassthrough:
+					nPassthrough++
+				default:
+					nTCP++
+				}
+			}
+		}
+	}
+
+	quic := isQUICListener(l)
+	types := make([]string, 0, 3)
+	if nHTTP > 0 {
+		if quic {
+			types = append(types, "HTTP3")
+		} else {
+			types = append(types, "HTTP")
+		}
+	}
+	if nTCP > 0 {
+		types = append(types, "TCP")
+	}
+	if nUDP > 0 || (!quic && nHTTP == 0 && nTCP == 0 && isUDPListener(l)) {
+		types = append(types, "UDP")
+	}
+	if len(types) == 0 {
+		switch {
+		case nPassthrough > 0:
+			return "PASSTHROUGH"
+		case nBlackHole > 0:
+			return "TCP (" + destinationBlackHole + ")"
+		}
+		return "UNKNOWN"
+	}
+	return strings.Join(types, "+")
+}
+
+// retrieveTCPProxyCluster returns the cluster a TCP proxy forwards to, or "" for weighted clusters
+func retrieveTCPProxyCluster(filter *listener.Filter) string {
+	tcpProxy, err := retrieveTCPProxy(filter)
+	if err != nil {
+		return ""
+	}
+	return tcpProxy.GetCluster()
+}
+
+// describeCatchAllDestination replaces the clusters dropping traffic or passing it through to its original
+// destination with blackhole and passthrough, so they stand out from the clusters of services
+func describeCatchAllDestination(destination string) string {
+	switch destination {
+	case util.BlackHoleCluster:
+		return destinationBlackHole
+	case util.PassthroughCluster, util.InboundPassthroughClusterIpv4, util.InboundPassthroughClusterIpv6:
+		return destinationPassthrough
+	}
+	return destination
+}
+
+// isUDPListener returns true if the listener is bound to a UDP socket, or configures a UDP listener
+func isUDPListener(l *listener.Listener) bool {
+	return l.Address.GetSocketAddress().GetProtocol() == core.SocketAddress_UDP || l.GetUdpListenerConfig() != nil
+}
+
+// isQUICListener returns true if the listener terminates QUIC, recognized by its UDP listener
+// implementation or by the transport socket of its filter chains
+func isQUICListener(l *listener.Listener) bool {
+	if l.GetUdpListenerConfig().GetUdpListenerName() == quicListener {
+		return true
+	}
+	for _, filterChain := range l.GetFilterChains() {
+		if filterChain.GetTransportSocket().GetName() == quicTransportSocket {
+			return true
+		}
+	}
+	return false
+}
+
+// retrieveListenerProtocol returns the transport protocol a listener accepts, TCP or UDP, or "-" for pipes
+func retrieveListenerProtocol(l *listener.Listener) string {
+	if l.Address.GetPipe() != nil {
+		return "-"
+	}
+	if isUDPListener(l) {
+		return core.SocketAddress_UDP.String()
+	}
+	return core.SocketAddress_TCP.String()
+}
+
+// isHTTPConnectionManager returns true if the network filter is an HTTP connection manager, recognized
+// by its legacy or canonical name, or by the type of its config when a custom name is used
+func isHTTPConnectionManager(filter *listener.Filter) bool {
+	switch filter.Name {
+	case HTTPListener, HTTPListenerCanonical:
+		return true
+	}
+	switch filter.GetTypedConfig().GetTypeUrl() {
+	case httpConnectionManagerTypeURL, httpConnectionManagerV2TypeURL:
+		return true
+	}
+	return false
+}
+
+// isTCPProxy returns true if the network filter is a TCP proxy, recognized by its legacy or canonical
+// name, or by the type of its config when a custom name is used
+func isTCPProxy(filter *listener.Filter) bool {
+	switch filter.Name {
+	case TCPListener, TCPListenerCanonical:
+		return true
+	}
+	switch filter.GetTypedConfig().GetTypeUrl() {
+	case tcpProxyTypeURL, tcpProxyV2TypeURL:
+		return true
+	}
+	return false
+}
+
+// usesOriginalDst returns true if the listener hands connections off by their original destination
+func usesOriginalDst(l *listener.Listener) bool {
+	for _, listenerFilter := range l.GetListenerFilters() {
+		if listenerFilter.Name == originalDstListenerFilter || listenerFilter.Name == originalDstListenerFilterCanonical {
+			return true
+		}
+	}
+	return false
+}
+
+// retrieveListenerAddress returns the socket address of a listener, the path for pipe (UDS) listeners,
+// or the name of internal listeners, which are addressed by name
+func retrieveListenerAddress(l *listener.Listener) string {
+	if pipe := l.Address.GetPipe(); pipe != nil {
+		return pipe.Path
+	}
+	if isInternalListener(l) {
+		return l.Name
+	}
+	return l.Address.GetSocketAddress().GetAddress()
+}
+
+// isInternalListener returns true if the listener has neither a socket nor a pipe address, as internal listeners
+// for tunneling like HBONE do. The internal_listener field is dropped with the unknown fields of the listener, see
+// splitConfigDumpSections.
+func isInternalListener(l *listener.Listener) bool {
+	return l.GetAddress().GetSocketAddress() == nil && l.GetAddress().GetPipe() == nil
+}
+
+// retrieveListenerAddresses returns all addresses a listener is bound to, the primary address first and then the
+// additional addresses of a multi-address listener
+func retrieveListenerAddresses(l *listener.Listener, additionalAddresses []*core.Address) []string {
+	addresses := []string{retrieveListenerAddress(l)}
+	for _, address := range additionalAddresses {
+		addresses = append(addresses, retrieveAddress(address))
+	}
+	return addresses
+}
+
+// retrieveAddress returns the socket address, or the path of pipe addresses
+func retrieveAddress(address *core.Address) string {
+	if pipe := address.GetPipe(); pipe != nil {
+		return pipe.Path
+	}
+	return address.GetSocketAddress().GetAddress()
+}
+
+// formatListenerAddress renders a listener address for display, bracketing IPv6 addresses
+// (including IPv4-mapped IPv6 addresses) so they cannot be confused with a port suffix.
+// IPv6 addresses are shown in their canonical form, so the same address is always rendered the same way.
+func formatListenerAddress(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil || !strings.Contains(address, ":") {
+		return address
+	}
+	// The canonical form of an IPv4-mapped address drops the mapping, so those are kept as written
+	if ip.To4() == nil {
+		address = ip.String()
+	}
+	return "[" + strings.ToLower(address) + "]"
+}
+
+func retrieveListenerPort(l *listener.Listener) uint32 {
+	return l.Address.GetSocketAddress().GetPortValue()
+}
+
+// formatListenerPort renders a listener port for display; pipe and internal listeners have no port
+func formatListenerPort(l *listener.Listener) string {
+	if l.Address.GetPipe() != nil || isInternalListener(l) {
+		return "-"
+	}
+	return strconv.Itoa(int(retrieveListenerPort(l)))
+}
+
+// retrieveListenerDirection classifies a Listener as inbound|outbound, or "" when the direction is unknown.
+// The traffic_direction field is preferred, falling back to the Istio listener naming conventions.
+func retrieveListenerDirection(l *listener.Listener) string {
+	switch l.GetTrafficDirection() {
+	case core.TrafficDirection_INBOUND:
+		return string(model.TrafficDirectionInbound)
+	case core.TrafficDirection_OUTBOUND:
+		return string(model.TrafficDirectionOutbound)
+	}
+	if isVirtualInboundListener(l) {
+		return string(model.TrafficDirectionInbound)
+	}
+	if l.Name == v1alpha3.VirtualOutboundListenerName {
+		return string(model.TrafficDirectionOutbound)
+	}
+	name := strings.ToLower(l.Name)
+	if strings.HasPrefix(name, string(model.TrafficDirectionInbound)) {
+		return string(model.TrafficDirectionInbound)
+	}
+	if strings.HasPrefix(name, string(model.TrafficDirectionOutbound)) {
+		return string(model.TrafficDirectionOutbound)
+	}
+	return ""
+}
+
+// isVirtualListener returns true for the listeners Istio adds to every proxy rather than for a service,
+// recognized by their well known ports as well as their names, which differ between proxies
+func isVirtualListener(l *listener.Listener) bool {
+	if isVirtualInboundListener(l) || l.Name == v1alpha3.VirtualOutboundListenerName {
+		return true
+	}
+	switch retrieveListenerPort(l) {
+	case virtualOutboundListenerPort, prometheusListenerPort, healthListenerPort:
+		return true
+	}
+	return false
+}
+
+// isVirtualInboundListener returns true for the listener capturing all inbound traffic, which is
+// named virtualInbound or 0.0.0.0_15006 depending on the Istio version
+func isVirtualInboundListener(l *listener.Listener) bool {
+	return l.Name == v1alpha3.VirtualInboundListenerName ||
+		l.Name == fmt.Sprintf("0.0.0.0_%d", v1alpha3.ProxyInboundListenPort) ||
+		retrieveListenerPort(l) == v1alpha3.ProxyInboundListenPort
+}
+
+// FilterChainSummary describes what a listener filter chain matches and where it sends traffic
+type FilterChainSummary struct {
+	Match       string `json:"match"`
+	TLS         string `json:"tls"`
+	Filter      string `json:"filter"`
+	Destination string `json:"destination"`
+	AccessLog   string `json:"accessLog"`
+	Policies    string `json:"policies"`
+	// Config is the Istio config resource that generated the filter chain, see describeIstioConfig
+	Config string `json:"config"`
+}
+
+func retrieveFilterChainSummaries(l *listener.Listener) []FilterChainSummary {
+	summaries := make([]FilterChainSummary, 0, len(l.GetFilterChains()))
+	for _, filterChain := range l.GetFilterChains() {
+		filterName, destination := describeFilterChainFilters(filterChain.GetFilters())
+		summaries = append(summaries, FilterChainSummary{
+			Match:       describeFilterChainMatch(filterChain.GetFilterChainMatch()),
+			TLS:         describeTransportSocket(filterChain.GetTransportSocket()),
+			Filter:      filterName,
+			Destination: destination,
+			AccessLog:   describeAccessLogs(filterChain.GetFilters()),
+			Policies:    describeFilterChainPolicies(filterChain.GetFilters()),
+			Config:      describeIstioConfig(filterChain.GetMetadata(), l.GetMetadata()),
+		})
+	}
+	return summaries
+}
+
+// describeFilterChainMatch renders the match criteria of a filter chain, or ALL when it matches everything
+func describeFilterChainMatch(match *listener.FilterChainMatch) string {
+	descrs := []string{}
+	if len(match.GetServerNames()) > 0 {
+		descrs = append(descrs, fmt.Sprintf("SNI: %s", strings.Join(match.GetServerNames(), ",")))
+	}
+	port := ""
+	if match.GetDestinationPort() != nil {
+		port = fmt.Sprintf(":%d", match.GetDestinationPort().GetValue())
+	}
+	if len(match.GetPrefixRanges()) > 0 {
+		descrs = append(descrs, fmt.Sprintf("Addr: %s%s", formatPrefixRanges(match.GetPrefixRanges()), port))
+	} else if port != "" {
+		descrs = append(descrs, fmt.Sprintf("Addr: *%s", port))
+	}
+	if match.GetTransportProtocol() != "" {
+		descrs = append(descrs, fmt.Sprintf("Trans: %s", match.GetTransportProtocol()))
+	}
+	if len(match.GetApplicationProtocols()) > 0 {
+		descrs = append(descrs, fmt.Sprintf("App: %s", strings.Join(match.GetApplicationProtocols(), ",")))
+	}
+	if len(descrs) == 0 {
+		return "ALL"
+	}
+	return strings.Join(descrs, "; ")
+}
+
+// describeTransportSocket reports whether a filter chain accepts plaintext, TLS or mutual TLS connections,
+// along with the ALPN protocols it negotiates. An explicit raw_buffer socket is told apart from none at all.
+func describeTransportSocket(transportSocket *core.TransportSocket) string {
+	if transportSocket == nil {
+		return "NONE"
+	}
+	if name := transportSocket.GetName(); name == util.EnvoyRawBufferSocketName || name == "raw_buffer" {
+		return "RAW_BUFFER"
+	}
+	if !isTLSTransportSocket(transportSocket) {
+		return transportSocket.GetName()
+	}
+	tlsContext, err := retrieveDownstreamTLSContext(transportSocket)
+	if err != nil {
+		return tlsModeSimple
+	}
+	mode := tlsModeSimple
+	if tlsContext.GetRequireClientCertificate().GetValue() {
+		mode = tlsModeMutual
+	}
+	if alpn := tlsContext.GetCommonTlsContext().GetAlpnProtocols(); len(alpn) > 0 {
+		mode += fmt.Sprintf(" (ALPN: %s)", strings.Join(alpn, ","))
+	}
+	return mode
+}
+
+// isTLSTransportSocket returns true if the transport socket terminates TLS, recognized by its name or config type
+func isTLSTransportSocket(transportSocket *core.TransportSocket) bool {
+	switch transportSocket.GetName() {
+	case util.EnvoyTLSSocketName, "tls":
+		return true
+	}
+	switch transportSocket.GetTypedConfig().GetTypeUrl() {
+	case downstreamTLSContextTypeURL, downstreamTLSContextV2TypeURL:
+		return true
+	}
+	return false
+}
+
+func retrieveDownstreamTLSContext(transportSocket *core.TransportSocket) (*tls.DownstreamTlsContext, error) {
+	tlsContext := &tls.DownstreamTlsContext{}
+	if err := unmarshalAnyAs(downstreamTLSContextTypeURL, transportSocket.GetTypedConfig(), tlsContext); err != nil {
+		return nil, err
+	}
+	return tlsContext, nil
+}
+
+// retrieveListenerTLSMode summarizes the TLS modes of the filter chains of a listener. A listener mixing
+// chains terminating TLS with plaintext ones, like the virtual inbound listener in permissive mode, is PERMISSIVE.
+func retrieveListenerTLSMode(l *listener.Listener) string {
+	nPlaintext, nSimple, nMutual := 0, 0, 0
+	for _, filterChain := range l.GetFilterChains() {
+		if !isTLSTransportSocket(filterChain.GetTransportSocket()) {
+			nPlaintext++
+			continue
+		}
+		tlsContext, err := retrieveDownstreamTLSContext(filterChain.GetTransportSocket())
+		if err == nil && tlsContext.GetRequireClientCertificate().GetValue() {
+			nMutual++
+		} else {
+			nSimple++
+		}
+	}
+	switch {
+	case nSimple+nMutual == 0:
+		return tlsModeDisable
+	case nPlaintext > 0:
+		return tlsModePermissive
+	case nSimple == 0:
+		return tlsModeMutual
+	default:
+		return tlsModeSimple
+	}
+}
+
+func formatPrefixRanges(prefixRanges []*core.CidrRange) string {
+	prefixes := make([]string, 0, len(prefixRanges))
+	for _, prefix := range prefixRanges {
+		prefixes = append(prefixes, fmt.Sprintf("%s/%d", prefix.GetAddressPrefix(), prefix.GetPrefixLen().GetValue()))
+	}
+	return strings.Join(prefixes, ",")
+}
+
+// describeFilterChainFilters returns the name of the terminating network filter of a filter chain
+// along with the route config or cluster it forwards to
+func describeFilterChainFilters(filters []*listener.Filter) (string, string) {
+	if len(filters) == 0 {
+		return "-", "-"
+	}
+	filter := filters[len(filters)-1]
+	switch {
+	case isHTTPConnectionManager(filter):
+		httpConnectionManager, err := retrieveHTTPConnectionManager(filter)
+		if err != nil {
+			return filter.Name, "-"
+		}
+		return filter.Name, describeRouteConfig(httpConnectionManager)
+	case isTCPProxy(filter):
+		tcpProxy, err := retrieveTCPProxy(filter)
+		if err != nil {
+			return filter.Name, "-"
+		}
+		if cluster := tcpProxy.GetCluster(); cluster != "" {
+			return filter.Name, cluster
+		}
+		if clusters := tcpProxy.GetWeightedClusters().GetClusters(); len(clusters) > 0 {
+			return filter.Name, fmt.Sprintf("weighted(%d)", len(clusters))
+		}
+	}
+	return filter.Name, "-"
+}
+
+// describeListenerFilters renders the listener filters of a listener in order by their short names, like
+// original_dst,tls_inspector,http_inspector, adding the timeout of the protocol detection they do when it
+// differs from the Envoy default. Listeners without listener filters are shown as -.
+func describeListenerFilters(l *listener.Listener) string {
+	names := make([]string, 0, len(l.GetListenerFilters()))
+	for _, listenerFilter := range l.GetListenerFilters() {
+		name := strings.TrimPrefix(listenerFilter.GetName(), "envoy.filters.listener.")
+		names = append(names, strings.TrimPrefix(name, "envoy.listener."))
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	description := strings.Join(names, ",")
+	if timeout, err := ptypes.Duration(l.GetListenerFiltersTimeout()); err == nil && timeout != defaultListenerFiltersTimeout {
+		description += fmt.Sprintf(" (timeout: %v)", timeout)
+	}
+	return description
+}
+
+// describeAccessLogs renders the access log sinks of the HTTP connection managers and TCP proxies of a filter
+// chain, or none when the chain does not log
+func describeAccessLogs(filters []*listener.Filter) string {
+	sinks := make([]string, 0)
+	for _, filter := range filters {
+		var accessLogs []*accesslog.AccessLog
+		switch {
+		case isHTTPConnectionManager(filter):
+			if httpConnectionManager, err := retrieveHTTPConnectionManager(filter); err == nil {
+				accessLogs = httpConnectionManager.GetAccessLog()
+			}
+		case isTCPProxy(filter):
+			if tcpProxy, err := retrieveTCPProxy(filter); err == nil {
+				accessLogs = tcpProxy.GetAccessLog()
+			}
+		}
+		for _, accessLog := range accessLogs {
+			sinks = append(sinks, describeAccessLog(accessLog))
+		}
+	}
+	if len(sinks) == 0 {
+		return "none"
+	}
+	return strings.Join(sinks, ",")
+}
+
+// describeAccessLog renders file sinks by their path like file:/dev/stdout, gRPC sinks by their log name like
+// grpc:envoy_accesslog and other sinks by the short name of their config type
+func describeAccessLog(accessLog *accesslog.AccessLog) string {
+	typedConfig := accessLog.GetTypedConfig()
+	if typedConfig == nil {
+		return accessLog.GetName()
+	}
+	typeName := typedConfig.GetTypeUrl()[strings.LastIndex(typedConfig.GetTypeUrl(), ".")+1:]
+	switch typeName {
+	case "FileAccessLog":
+		fileAccessLog := &fileaccesslog.FileAccessLog{}
+		if err := unmarshalAnyAs(fileAccessLogTypeURL, typedConfig, fileAccessLog); err == nil {
+			return "file:" + fileAccessLog.GetPath()
+		}
+	case "HttpGrpcAccessLogConfig":
+		grpcAccessLog := &grpcaccesslog.HttpGrpcAccessLogConfig{}
+		if err := unmarshalAnyAs(httpGrpcAccessLogTypeURL, typedConfig, grpcAccessLog); err == nil {
+			return "grpc:" + grpcAccessLog.GetCommonConfig().GetLogName()
+		}
+	case "TcpGrpcAccessLogConfig":
+		grpcAccessLog := &grpcaccesslog.TcpGrpcAccessLogConfig{}
+		if err := unmarshalAnyAs(tcpGrpcAccessLogTypeURL, typedConfig, grpcAccessLog); err == nil {
+			return "grpc:" + grpcAccessLog.GetCommonConfig().GetLogName()
+		}
+	}
+	return typeName
+}
 
-// ListenerFilter is used to pass filter information into listener based config writer print functions
-type ListenerFilter struct {
-	Address string
-	Port    uint32
-	Type    string
+// describeIstioConfig renders the Istio config resource recorded in the istio filter metadata of a filter chain,
+// or else of its listener, as kind/namespace/name like Gateway/istio-system/ingress, or - when there is none.
+// Istio does not record EnvoyFilter patches, so "(patched)" is only appended when one of the two records an
+// EnvoyFilter and the other the resource it patched.
+func describeIstioConfig(chainMetadata, listenerMetadata *core.Metadata) string {
+	configs := make([]string, 0, 2)
+	for _, metadata := range []*core.Metadata{chainMetadata, listenerMetadata} {
+		if config := retrieveIstioConfig(metadata); config != "" && (len(configs) == 0 || configs[0] != config) {
+			configs = append(configs, config)
+		}
+	}
+	switch {
+	case len(configs) == 0:
+		return "-"
+	case len(configs) == 2 && isEnvoyFilterConfig(configs[0]) != isEnvoyFilterConfig(configs[1]):
+		if isEnvoyFilterConfig(configs[0]) {
+			return configs[1] + " (patched)"
+		}
+		return configs[0] + " (patched)"
+	}
+	return configs[0]
 }
 
-// Verify returns true if the passed listener matches the filter fields
-func (l *ListenerFilter) Verify(listener *listener.Listener) bool {
-	if l.Address == "" && l.Port == 0 && l.Type == "" {
-		return true
+// retrieveIstioConfig returns the kind/namespace/name of the config path Istio records in filter metadata,
+// like /apis/networking.istio.io/v1alpha3/namespaces/istio-system/gateway/ingress, or "" when there is none
+func retrieveIstioConfig(metadata *core.Metadata) string {
+	config := metadata.GetFilterMetadata()[util.IstioMetadataKey].GetFields()["config"].GetStringValue()
+	parts := strings.Split(config, "/")
+	if len(parts) != 8 || parts[1] != "apis" || parts[4] != "namespaces" {
+		return ""
 	}
-	if l.Address != "" && !strings.EqualFold(retrieveListenerAddress(listener), l.Address) {
-		return false
+	return strings.Join([]string{strcase.CamelCase(parts[6]), parts[5], parts[7]}, "/")
+}
+
+func isEnvoyFilterConfig(config string) bool {
+	return strings.HasPrefix(config, "EnvoyFilter/")
+}
+
+// describeFilterChainPolicies renders the JWT authentication and RBAC authorization filters of a filter chain, which
+// enforce RequestAuthentication and AuthorizationPolicy, like jwt(issuer=foo),rbac(3 policies), or - when there are none
+func describeFilterChainPolicies(filters []*listener.Filter) string {
+	policies := make([]string, 0)
+	for _, filter := range filters {
+		switch {
+		case filter.GetName() == authz_model.RBACTCPFilterName:
+			rbac := &rbactcp.RBAC{}
+			if err := unmarshalAnyAs(networkRBACTypeURL, filter.GetTypedConfig(), rbac); err == nil {
+				policies = append(policies, describeRBAC(rbac.GetRules(), rbac.GetShadowRules()))
+			}
+		case isHTTPConnectionManager(filter):
+			httpConnectionManager, err := retrieveHTTPConnectionManager(filter)
+			if err != nil {
+				continue
+			}
+			for _, httpFilter := range httpConnectionManager.GetHttpFilters() {
+				typedConfig := httpFilter.GetTypedConfig()
+				switch httpFilter.GetName() {
+				case authn_model.EnvoyJwtFilterName:
+					jwtAuthentication := &jwtauthn.JwtAuthentication{}
+					if err := unmarshalAnyAs(jwtAuthenticationTypeURL, typedConfig, jwtAuthentication); err == nil {
+						policies = append(policies, describeJwtAuthentication(jwtAuthentication))
+					}
+				case authz_model.RBACHTTPFilterName:
+					rbac := &rbachttp.RBAC{}
+					if err := unmarshalAnyAs(httpRBACTypeURL, typedConfig, rbac); err == nil {
+						policies = append(policies, describeRBAC(rbac.GetRules(), rbac.GetShadowRules()))
+					}
+				}
+			}
+		}
 	}
-	if l.Port != 0 && retrieveListenerPort(listener) != l.Port {
-		return false
+	if len(policies) == 0 {
+		return "-"
 	}
-	if l.Type != "" && !strings.EqualFold(retrieveListenerType(listener), l.Type) {
-		return false
+	return strings.Join(policies, ",")
+}
+
+// describeJwtAuthentication renders the issuers of the JWT providers, like jwt(issuer=foo|bar)
+func describeJwtAuthentication(jwtAuthentication *jwtauthn.JwtAuthentication) string {
+	issuers := make([]string, 0, len(jwtAuthentication.GetProviders()))
+	for _, provider := range jwtAuthentication.GetProviders() {
+		issuers = append(issuers, provider.GetIssuer())
 	}
-	return true
+	sort.Strings(issuers)
+	return fmt.Sprintf("jwt(issuer=%s)", strings.Join(issuers, "|"))
 }
 
-// retrieveListenerType classifies a Listener as HTTP|TCP|HTTP+TCP|UNKNOWN
-func retrieveListenerType(l *listener.Listener) string {
-	nHTTP := 0
-	nTCP := 0
+// describeRBAC renders the number of policies of the enforced RBAC rules, noting DENY rules, or of the shadow
+// rules when the filter only logs its decisions
+func describeRBAC(rules, shadowRules *rbacconfig.RBAC) string {
+	switch {
+	case rules == nil:
+		return fmt.Sprintf("rbac(shadow, %s)", formatPolicyCount(len(shadowRules.GetPolicies())))
+	case rules.GetAction() == rbacconfig.RBAC_DENY:
+		return fmt.Sprintf("rbac(deny, %s)", formatPolicyCount(len(rules.GetPolicies())))
+	default:
+		return fmt.Sprintf("rbac(%s)", formatPolicyCount(len(rules.GetPolicies())))
+	}
+}
+
+func formatPolicyCount(count int) string {
+	if count == 1 {
+		return "1 policy"
+	}
+	return fmt.Sprintf("%d policies", count)
+}
+
+// retrieveListenerDestinations returns the deduplicated destinations of all filter chains of a listener,
+// truncated to keep the summary readable
+func retrieveListenerDestinations(l *listener.Listener) string {
+	seen := map[string]bool{}
+	destinations := make([]string, 0)
+	for _, chain := range retrieveFilterChainSummaries(l) {
+		if chain.Destination == "-" || seen[chain.Destination] {
+			continue
+		}
+		seen[chain.Destination] = true
+		destinations = append(destinations, chain.Destination)
+	}
+	if len(destinations) == 0 {
+		return "-"
+	}
+	joined := strings.Join(destinations, ",")
+	if len(joined) > maxDestinationsWidth {
+		joined = joined[:maxDestinationsWidth-3] + "..."
+	}
+	return joined
+}
+
+// describeRouteConfig returns the name of the RDS route config an HTTP connection manager uses,
+// inline when the routes are part of the listener, or "-" otherwise
+func describeRouteConfig(httpConnectionManager *hcm.HttpConnectionManager) string {
+	if httpConnectionManager.GetRouteConfig() != nil {
+		return "inline"
+	}
+	if name := httpConnectionManager.GetRds().GetRouteConfigName(); name != "" {
+		return name
+	}
+	return "-"
+}
+
+// retrieveListenerRoutes returns the deduplicated route configs of the HTTP filter chains of a listener,
+// matching the route names printed by proxy-config route, or "-" for listeners without HTTP chains
+func retrieveListenerRoutes(l *listener.Listener) string {
+	seen := map[string]bool{}
+	routes := make([]string, 0)
 	for _, filterChain := range l.GetFilterChains() {
 		for _, filter := range filterChain.GetFilters() {
-			if filter.Name == HTTPListener {
-				nHTTP++
-			} else if filter.Name == TCPListener {
-				if !strings.Contains(string(filter.GetTypedConfig().GetValue()), util.BlackHoleCluster) {
-					nTCP++
-				}
+			if !isHTTPConnectionManager(filter) {
+				continue
+			}
+			httpConnectionManager, err := retrieveHTTPConnectionManager(filter)
+			if err != nil {
+				continue
+			}
+			route := describeRouteConfig(httpConnectionManager)
+			if route == "-" || seen[route] {
+				continue
 			}
+			seen[route] = true
+			routes = append(routes, route)
 		}
 	}
+	if len(routes) == 0 {
+		return "-"
+	}
+	return strings.Join(routes, ",")
+}
+
+func retrieveHTTPConnectionManager(filter *listener.Filter) (*hcm.HttpConnectionManager, error) {
+	httpConnectionManager := &hcm.HttpConnectionManager{}
+	if err := unmarshalAnyAs(httpConnectionManagerTypeURL, filter.GetTypedConfig(), httpConnectionManager); err != nil {
+		return nil, fmt.Errorf("unmarshal http connection manager: %v", err)
+	}
+	return httpConnectionManager, nil
+}
 
-	if nHTTP > 0 {
-		if nTCP == 0 {
-			return "HTTP"
+func retrieveTCPProxy(filter *listener.Filter) (*tcp.TcpProxy, error) {
+	tcpProxy := &tcp.TcpProxy{}
+	if err := unmarshalAnyAs(tcpProxyTypeURL, filter.GetTypedConfig(), tcpProxy); err != nil {
+		return nil, fmt.Errorf("unmarshal tcp proxy: %v", err)
+	}
+	return tcpProxy, nil
+}
+
+// listenerSummaryColumn is a column the listener summary can be restricted to
+type listenerSummaryColumn struct {
+	name  string
+	value func(l listenerSummaryEntry, now time.Time) string
+}
+
+// listenerSummaryColumns are the columns of the listener summary that can be selected by name
+var listenerSummaryColumns = []listenerSummaryColumn{
+	{"NAME", func(l listenerSummaryEntry, _ time.Time) string { return l.Name }},
+	{"ADDRESS", func(l listenerSummaryEntry, _ time.Time) string { return l.address }},
+	{"PORT", func(l listenerSummaryEntry, _ time.Time) string { return l.port }},
+	{"PROTOCOL", func(l listenerSummaryEntry, _ time.Time) string { return retrieveListenerProtocol(l.Listener) }},
+	{"TYPE", func(l listenerSummaryEntry, _ time.Time) string { return formatListenerType(l.Listener) }},
+	{"DIRECTION", func(l listenerSummaryEntry, _ time.Time) string { return formatListenerDirection(l.Listener) }},
+	{"ORIGIN", func(l listenerSummaryEntry, _ time.Time) string { return retrieveListenerOrigin(l.listenerWithState) }},
+	{"STATE", func(l listenerSummaryEntry, _ time.Time) string { return l.state }},
+	{"LAST_UPDATED", func(l listenerSummaryEntry, now time.Time) string {
+		return formatListenerLastUpdated(l.listenerWithState, now)
+	}},
+	{"TLS", func(l listenerSummaryEntry, _ time.Time) string { return retrieveListenerTLSMode(l.Listener) }},
+	{"ROUTE", func(l listenerSummaryEntry, _ time.Time) string { return retrieveListenerRoutes(l.Listener) }},
+	{"CHAINS", func(l listenerSummaryEntry, _ time.Time) string { return strconv.Itoa(len(l.GetFilterChains())) }},
+	{"DESTINATION", func(l listenerSummaryEntry, _ time.Time) string { return retrieveListenerDestinations(l.Listener) }},
+}
+
+func retrieveListenerSummaryColumn(name string) *listenerSummaryColumn {
+	for i := range listenerSummaryColumns {
+		if strings.EqualFold(listenerSummaryColumns[i].name, name) {
+			return &listenerSummaryColumns[i]
+		}
+	}
+	return nil
+}
+
+func listenerSummaryColumnNames() []string {
+	names := make([]string, 0, len(listenerSummaryColumns))
+	for _, column := range listenerSummaryColumns {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+// printListenerSummaryColumns prints one row per summary entry with just the selected columns
+func printListenerSummaryColumns(w *tabwriter.Writer, listeners []*listenerWithState, filter ListenerFilter) error {
+	columns := make([]*listenerSummaryColumn, 0, len(filter.Columns))
+	header := make([]string, 0, len(filter.Columns))
+	for _, name := range filter.Columns {
+		column := retrieveListenerSummaryColumn(name)
+		columns = append(columns, column)
+		header = append(header, strings.Replace(column.name, "_", " ", -1))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	now := time.Now()
+	for _, l := range listeners {
+		for _, entry := range retrieveListenerSummaryEntries(l, filter.ExpandInbound) {
+			row := make([]string, 0, len(columns))
+			for _, column := range columns {
+				row = append(row, column.value(entry, now))
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
 		}
-		return "HTTP+TCP"
-	} else if nTCP > 0 {
-		return "TCP"
 	}
+	return w.Flush()
+}
 
-	return "UNKNOWN"
+// formatListenerType renders the type of a listener, noting when it hands connections off by their
+// original destination
+func formatListenerType(l *listener.Listener) string {
+	listenerType := retrieveListenerType(l)
+	if usesOriginalDst(l) {
+		listenerType += " (original_dst)"
+	}
+	return listenerType
 }
 
-func retrieveListenerAddress(l *listener.Listener) string {
-	return l.Address.GetSocketAddress().Address
+// formatListenerDirection renders the direction of a listener, or "-" when it is unknown
+func formatListenerDirection(l *listener.Listener) string {
+	if direction := retrieveListenerDirection(l); direction != "" {
+		return direction
+	}
+	return "-"
 }
 
-func retrieveListenerPort(l *listener.Listener) uint32 {
-	return l.Address.GetSocketAddress().GetPortValue()
+// ListenerSummary is a listener summarized as a row of the listener summary, for tooling reading the
+// summary as JSON or YAML. Columns of the summary that are not requested are left out.
+type ListenerSummary struct {
+	Name            string               `json:"name"`
+	Address         string               `json:"address"`
+	Port            uint32               `json:"port,omitempty"`
+	Protocol        string               `json:"protocol"`
+	Type            string               `json:"type"`
+	Direction       string               `json:"direction,omitempty"`
+	Origin          string               `json:"origin"`
+	State           string               `json:"state"`
+	LastUpdated     *time.Time           `json:"lastUpdated,omitempty"`
+	TLS             string               `json:"tls,omitempty"`
+	Route           string               `json:"route,omitempty"`
+	Chains          *int                 `json:"chains,omitempty"`
+	Filters         []string             `json:"filters,omitempty"`
+	ServerNames     []string             `json:"serverNames,omitempty"`
+	Destination     string               `json:"destination"`
+	FilterChains    []FilterChainSummary `json:"filterChains,omitempty"`
+	ListenerFilters string               `json:"listenerFilters,omitempty"`
 }
 
-// PrintListenerSummary prints a summary of the relevant listeners in the config dump to the ConfigWriter stdout
+// retrieveListenerSummaries summarizes each listener, or each filter chain of the virtual inbound listener
+// when expanded, with the columns the filter asks for
+func retrieveListenerSummaries(listeners []*listenerWithState, filter ListenerFilter) []ListenerSummary {
+	summaries := make([]ListenerSummary, 0, len(listeners))
+	for _, l := range listeners {
+		for _, entry := range retrieveListenerSummaryEntries(l, filter.ExpandInbound) {
+			summary := ListenerSummary{
+				Name:        entry.Name,
+				Address:     entry.address,
+				Port:        entry.portValue,
+				Protocol:    retrieveListenerProtocol(entry.Listener),
+				Type:        formatListenerType(entry.Listener),
+				Direction:   retrieveListenerDirection(entry.Listener),
+				Origin:      retrieveListenerOrigin(entry.listenerWithState),
+				State:       entry.state,
+				Destination: retrieveListenerDestinations(entry.Listener),
+			}
+			if filter.LastUpdated {
+				summary.LastUpdated = retrieveListenerLastUpdated(entry.listenerWithState)
+			}
+			if filter.Wide {
+				summary.TLS = retrieveListenerTLSMode(entry.Listener)
+				summary.Route = retrieveListenerRoutes(entry.Listener)
+			}
+			if filter.Wide || filter.Verbose {
+				summary.FilterChains = retrieveFilterChainSummaries(entry.Listener)
+			}
+			if filter.Verbose {
+				summary.ListenerFilters = describeListenerFilters(entry.Listener)
+			}
+			if filter.Chains {
+				chains := len(entry.GetFilterChains())
+				summary.Chains = &chains
+			}
+			if filter.FilterName != "" {
+				summary.Filters = retrieveMatchingFilterNames(entry.Listener, filter.FilterName)
+			}
+			if filter.SNI != "" {
+				summary.ServerNames = retrieveMatchingServerNames(entry.Listener, filter.SNI)
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
+
+// PrintListenerSummary prints a summary of the relevant listeners in the config dump to the ConfigWriter stdout,
+// as a table or, when the output format of the ConfigWriter is JSON or YAML, as a list of ListenerSummary
 func (c *ConfigWriter) PrintListenerSummary(filter ListenerFilter) error {
-	w, listeners, err := c.setupListenerConfigWriter()
+	w, listeners, err := c.setupListenerConfigWriter(filter)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(w, "ADDRESS\tPORT\tTYPE")
-	for _, listener := range listeners {
-		if filter.Verify(listener) {
-			address := retrieveListenerAddress(listener)
-			port := retrieveListenerPort(listener)
-			listenerType := retrieveListenerType(listener)
-			fmt.Fprintf(w, "%v\t%v\t%v\n", address, port, listenerType)
+	listeners, more := limitListeners(listeners, filter)
+	if c.OutputFormat != Table {
+		out, err := json.MarshalIndent(retrieveListenerSummaries(listeners, filter), "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal listener summary: %v", err)
+		}
+		return c.printJSON(out)
+	}
+	if err := printListenerSummaryTable(w, listeners, filter); err != nil {
+		return err
+	}
+	if more > 0 {
+		fmt.Fprintf(c.Stdout, "... and %s more (use --limit 0 for all)\n", formatThousands(more))
+	}
+	return nil
+}
+
+// limitListeners returns the listeners inside the offset and limit of the filter, along with the number of
+// listeners left after them
+func limitListeners(listeners []*listenerWithState, filter ListenerFilter) ([]*listenerWithState, int) {
+	if filter.Offset >= len(listeners) {
+		return nil, 0
+	}
+	listeners = listeners[filter.Offset:]
+	if filter.Limit == 0 || filter.Limit >= len(listeners) {
+		return listeners, 0
+	}
+	return listeners[:filter.Limit], len(listeners) - filter.Limit
+}
+
+// formatThousands renders a count with comma separated thousands, like 4,321
+func formatThousands(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+func printListenerSummaryTable(w *tabwriter.Writer, listeners []*listenerWithState, filter ListenerFilter) error {
+	if len(filter.Columns) > 0 {
+		return printListenerSummaryColumns(w, listeners, filter)
+	}
+	if filter.Verbose {
+		fmt.Fprintln(w, "ADDRESS\tPORT\tLISTENER FILTERS\tMATCH\tTLS\tFILTER\tDESTINATION\tACCESS LOG\tPOLICIES\tCONFIG")
+		for _, l := range listeners {
+			address := formatListenerAddress(retrieveListenerAddress(l.Listener))
+			port := formatListenerPort(l.Listener)
+			listenerFilters := describeListenerFilters(l.Listener)
+			chains := retrieveFilterChainSummaries(l.Listener)
+			if len(chains) == 0 {
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", address, port, listenerFilters, "-", "-", "-", "-", "none", "-",
+					describeIstioConfig(nil, l.GetMetadata()))
+			}
+			for i, chain := range chains {
+				// Only show the address, port and listener filters once for all chains of a listener
+				if i > 0 {
+					address, port, listenerFilters = "", "", ""
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", address, port, listenerFilters, chain.Match, chain.TLS,
+					chain.Filter, describeCatchAllDestination(chain.Destination), chain.AccessLog, chain.Policies, chain.Config)
+			}
+		}
+		return w.Flush()
+	}
+
+	header := []string{"ADDRESS", "PORT"}
+	if filter.Wide {
+		header = append([]string{"NAME"}, header...)
+		header = append(header, "PROTOCOL")
+	}
+	header = append(header, "TYPE", "DIRECTION")
+	if filter.Wide {
+		header = append(header, "ORIGIN")
+	}
+	header = append(header, "STATE")
+	if filter.LastUpdated {
+		header = append(header, "LAST UPDATED")
+	}
+	if filter.Wide {
+		header = append(header, "TLS", "ROUTE")
+	}
+	if filter.Chains {
+		header = append(header, "CHAINS")
+	}
+	if filter.FilterName != "" {
+		header = append(header, "FILTERS")
+	}
+	if filter.SNI != "" {
+		header = append(header, "DESTINATION", "SERVER NAMES")
+	} else if filter.Wide {
+		header = append(header, "MATCH", "DESTINATION")
+	} else {
+		header = append(header, "DESTINATION")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	entries := make([]listenerSummaryEntry, 0, len(listeners))
+	for _, l := range listeners {
+		entries = append(entries, retrieveListenerSummaryEntries(l, filter.ExpandInbound)...)
+	}
+	now := time.Now()
+	for _, l := range entries {
+		row := []string{l.address, l.port}
+		if filter.Wide {
+			row = append([]string{l.Name}, row...)
+			row = append(row, retrieveListenerProtocol(l.Listener))
+		}
+		row = append(row, formatListenerType(l.Listener), formatListenerDirection(l.Listener))
+		if filter.Wide {
+			row = append(row, retrieveListenerOrigin(l.listenerWithState))
+		}
+		row = append(row, l.state)
+		if filter.LastUpdated {
+			row = append(row, formatListenerLastUpdated(l.listenerWithState, now))
+		}
+		if filter.Wide {
+			row = append(row, retrieveListenerTLSMode(l.Listener), retrieveListenerRoutes(l.Listener))
+		}
+		if filter.Chains {
+			// TODO: count the default filter chain once it is part of the listener API
+			row = append(row, strconv.Itoa(len(l.GetFilterChains())))
+		}
+		if filter.FilterName != "" {
+			row = append(row, strings.Join(retrieveMatchingFilterNames(l.Listener, filter.FilterName), ","))
+		}
+		if filter.SNI != "" {
+			serverNames := strings.Join(retrieveMatchingServerNames(l.Listener, filter.SNI), ",")
+			row = append(row, retrieveListenerDestinations(l.Listener), serverNames)
+		} else if filter.Wide {
+			chains := retrieveFilterChainSummaries(l.Listener)
+			if len(chains) == 0 {
+				chains = []FilterChainSummary{{Match: "-", Filter: "-", Destination: "-"}}
+			}
+			for i, chain := range chains {
+				// Only show the listener columns once for all chains of a listener
+				if i > 0 {
+					row = make([]string, len(row))
+				}
+				fmt.Fprintln(w, strings.Join(append(row, chain.Match, chain.Destination), "\t"))
+			}
+			continue
+		} else {
+			row = append(row, retrieveListenerDestinations(l.Listener))
 		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
 	}
 	return w.Flush()
 }
 
-// PrintListenerDump prints the relevant listeners in the config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintListenerDump(filter ListenerFilter) error {
-	_, listeners, err := c.setupListenerConfigWriter()
+// retrieveListenerLastUpdated returns when a dynamic listener was last updated. Static listeners are
+// never updated, so they have no update time.
+func retrieveListenerLastUpdated(l *listenerWithState) *time.Time {
+	if l.state == listenerStateStatic || l.lastUpdated == nil {
+		return nil
+	}
+	lastUpdated, err := ptypes.Timestamp(l.lastUpdated)
+	if err != nil {
+		return nil
+	}
+	return &lastUpdated
+}
+
+// formatListenerLastUpdated renders how long before now a dynamic listener was last updated, like 3m ago
+func formatListenerLastUpdated(l *listenerWithState, now time.Time) string {
+	return formatLastUpdated(retrieveListenerLastUpdated(l), now)
+}
+
+// formatLastUpdated renders how long before now a resource was last updated, like 3m ago, or - when unknown
+func formatLastUpdated(lastUpdated *time.Time, now time.Time) string {
+	if lastUpdated == nil {
+		return "-"
+	}
+	return duration.HumanDuration(now.Sub(*lastUpdated)) + " ago"
+}
+
+// PrintListenerCount prints the number of listeners in the config dump matching the filter to the ConfigWriter
+// stdout, counting a listener once for each state it is found in, like the summary does
+func (c *ConfigWriter) PrintListenerCount(filter ListenerFilter) error {
+	listeners, err := c.retrieveFilteredListenerSlice(filter)
 	if err != nil {
 		return err
 	}
-	filteredListeners := protio.MessageSlice{}
-	for _, listener := range listeners {
-		if filter.Verify(listener) {
-			filteredListeners = append(filteredListeners, listener)
+	fmt.Fprintln(c.Stdout, len(listeners))
+	return nil
+}
+
+// listenerSummaryEntry is a listener, or a filter chain of one, summarized on its own row
+type listenerSummaryEntry struct {
+	*listenerWithState
+	address   string
+	port      string
+	portValue uint32
+}
+
+// retrieveListenerSummaryEntries returns an entry for a listener and one more for each additional address of a
+// multi-address listener, unless the virtual inbound listener is expanded into one entry per filter chain with the
+// chain's destination port and prefix ranges
+func retrieveListenerSummaryEntries(l *listenerWithState, expandInbound bool) []listenerSummaryEntry {
+	address := formatListenerAddress(retrieveListenerAddress(l.Listener))
+	if !expandInbound || !isVirtualInboundListener(l.Listener) || len(l.GetFilterChains()) == 0 {
+		entries := []listenerSummaryEntry{{
+			listenerWithState: l,
+			address:           address,
+			port:              formatListenerPort(l.Listener),
+			portValue:         retrieveListenerPort(l.Listener),
+		}}
+		for _, additionalAddress := range l.additionalAddresses {
+			port := additionalAddress.GetSocketAddress().GetPortValue()
+			formattedPort := strconv.Itoa(int(port))
+			if additionalAddress.GetPipe() != nil {
+				formattedPort = "-"
+			}
+			entries = append(entries, listenerSummaryEntry{
+				listenerWithState: l,
+				address:           formatListenerAddress(retrieveAddress(additionalAddress)),
+				port:              formattedPort,
+				portValue:         port,
+			})
+		}
+		return entries
+	}
+	entries := make([]listenerSummaryEntry, 0, len(l.GetFilterChains()))
+	for _, filterChain := range l.GetFilterChains() {
+		match := filterChain.GetFilterChainMatch()
+		chainPort, chainPortValue := "any", uint32(0)
+		if match.GetDestinationPort() != nil {
+			chainPortValue = match.GetDestinationPort().GetValue()
+			chainPort = strconv.FormatUint(uint64(chainPortValue), 10)
+		}
+		chainAddress := address
+		if len(match.GetPrefixRanges()) > 0 {
+			chainAddress = formatPrefixRanges(match.GetPrefixRanges())
 		}
+		// A shallow copy keeps everything else of the listener, like its metadata and listener filters timeout
+		chain := *l.Listener
+		chain.FilterChains = []*listener.FilterChain{filterChain}
+		chainListener := *l
+		chainListener.Listener = &chain
+		entries = append(entries, listenerSummaryEntry{
+			listenerWithState: &chainListener,
+			address:           chainAddress,
+			port:              chainPort,
+			portValue:         chainPortValue,
+		})
 	}
-	out, err := json.MarshalIndent(filteredListeners, "", "    ")
+	return entries
+}
+
+// PrintListenerDump prints the relevant listeners in the config dump to the ConfigWriter stdout.
+// Listeners are grouped into static and dynamic listeners, and dynamic listeners into their
+// active, warming and draining states, following the layout of the Envoy config dump.
+func (c *ConfigWriter) PrintListenerDump(filter ListenerFilter) error {
+	_, listeners, err := c.setupListenerConfigWriter(filter)
 	if err != nil {
+		return err
+	}
+	listeners, _ = limitListeners(listeners, filter)
+	filteredDump := &adminapi.ListenersConfigDump{}
+	dynamicListeners := map[string]*adminapi.ListenersConfigDump_DynamicListener{}
+	for _, l := range listeners {
+		listenerAny, err := ptypes.MarshalAny(l.Listener)
+		if err != nil {
+			return fmt.Errorf("failed to marshal listeners: %v", err)
+		}
+		if l.state == listenerStateStatic {
+			filteredDump.StaticListeners = append(filteredDump.StaticListeners, &adminapi.ListenersConfigDump_StaticListener{
+				Listener:    listenerAny,
+				LastUpdated: l.lastUpdated,
+			})
+			continue
+		}
+		dynamicListener, ok := dynamicListeners[l.Name]
+		if !ok {
+			dynamicListener = &adminapi.ListenersConfigDump_DynamicListener{Name: l.Name}
+			dynamicListeners[l.Name] = dynamicListener
+			filteredDump.DynamicListeners = append(filteredDump.DynamicListeners, dynamicListener)
+		}
+		listenerState := &adminapi.ListenersConfigDump_DynamicListenerState{
+			VersionInfo: l.versionInfo,
+			Listener:    listenerAny,
+			LastUpdated: l.lastUpdated,
+		}
+		switch l.state {
+		case listenerStateActive:
+			dynamicListener.ActiveState = listenerState
+		case listenerStateWarming:
+			dynamicListener.WarmingState = listenerState
+		case listenerStateDraining:
+			dynamicListener.DrainingState = listenerState
+		}
+	}
+	var strictErr error
+	if filter.Strict {
+		// Warn before printing the dump, which cannot be marshaled when some typed configs are of unknown types
+		strictErr = c.printTypedConfigWarnings(listeners)
+	}
+	if err := c.printMessage(filteredDump); err != nil {
 		return fmt.Errorf("failed to marshal listeners: %v", err)
 	}
-	fmt.Fprintln(c.Stdout, string(out))
-	return nil
+	return strictErr
+}
+
+// GetListeners returns the listeners in the config dump matching the filter, sorted as the filter asks or
+// by port, address and name by default.
+// A listener found in more than one state, such as active and warming, is returned once in its first state.
+func (c *ConfigWriter) GetListeners(filter ListenerFilter) ([]*listener.Listener, error) {
+	listeners, err := c.retrieveFilteredListenerSlice(filter)
+	if err != nil {
+		return nil, err
+	}
+	typed := make([]*listener.Listener, 0, len(listeners))
+	seen := make(map[string]bool, len(listeners))
+	for _, l := range listeners {
+		if seen[l.Name] {
+			continue
+		}
+		seen[l.Name] = true
+		typed = append(typed, l.Listener)
+	}
+	return typed, nil
+}
+
+// DiffListeners prints how the listeners matching the filter changed from the config dump of the ConfigWriter to
+// the config dump of the other one: listeners only found in the other config dump are added, listeners missing
+// from it are removed, and the listeners with a different config are changed. Listeners are compared by name,
+// using their first state, and the update times of the config dumps are ignored.
+func (c *ConfigWriter) DiffListeners(other *ConfigWriter, filter ListenerFilter) error {
+	from, err := c.retrieveListenersByName(filter)
+	if err != nil {
+		return err
+	}
+	to, err := other.retrieveListenersByName(filter)
+	if err != nil {
+		return err
+	}
+	diffs, err := diffResources(from, to)
+	if err != nil {
+		return err
+	}
+	return c.printResourceDiffs("listener", diffs)
+}
+
+func (c *ConfigWriter) retrieveListenersByName(filter ListenerFilter) (map[string]proto.Message, error) {
+	listeners, err := c.retrieveFilteredListenerSlice(filter)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]proto.Message, len(listeners))
+	for _, l := range listeners {
+		if _, ok := byName[l.Name]; !ok {
+			byName[l.Name] = l.Listener
+		}
+	}
+	return byName, nil
 }
 
-func (c *ConfigWriter) setupListenerConfigWriter() (*tabwriter.Writer, []*listener.Listener, error) {
-	listeners, err := c.retrieveSortedListenerSlice()
+func (c *ConfigWriter) setupListenerConfigWriter(filter ListenerFilter) (*tabwriter.Writer, []*listenerWithState, error) {
+	listeners, err := c.retrieveFilteredListenerSlice(filter)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -143,7 +1759,50 @@ func (c *ConfigWriter) setupListenerConfigWriter() (*tabwriter.Writer, []*listen
 	return w, listeners, nil
 }
 
-func (c *ConfigWriter) retrieveSortedListenerSlice() ([]*listener.Listener, error) {
+func (c *ConfigWriter) retrieveFilteredListenerSlice(filter ListenerFilter) ([]*listenerWithState, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var match func(*listener.Listener, []*core.Address) bool
+	if filter.hasHeaderFields() {
+		match = filter.verifyHeader
+	}
+	listeners, err := c.retrieveSortedListenerSliceMatching(match)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	filtered := make([]*listenerWithState, 0, len(listeners))
+	for _, l := range listeners {
+		if !filter.verify(l.Listener, l.additionalAddresses) {
+			continue
+		}
+		if filter.Origin != "" && !strings.EqualFold(retrieveListenerOrigin(l), filter.Origin) {
+			continue
+		}
+		if filter.UpdatedSince != 0 && !filter.verifyUpdatedSince(l, now) {
+			continue
+		}
+		filtered = append(filtered, l)
+	}
+	if filter.SortBy != "" && filter.SortBy != sortByPort {
+		sortListeners(filtered, filter.SortBy)
+	}
+	return filtered, nil
+}
+
+// errNoListeners is returned when the listener section of the config dump has no listeners
+var errNoListeners = errors.New("no listeners found")
+
+func (c *ConfigWriter) retrieveSortedListenerSlice() ([]*listenerWithState, error) {
+	return c.retrieveSortedListenerSliceMatching(nil)
+}
+
+// retrieveSortedListenerSliceMatching returns the listeners of the config dump sorted by port. When match is set,
+// the listeners it returns false for are left out; it is passed the additional addresses of a listener too.
+// Only the name and address of a listener are unmarshalled to match it, so on dumps with thousands of listeners
+// the filter chains of the listeners left out are never unmarshalled.
+func (c *ConfigWriter) retrieveSortedListenerSliceMatching(match func(*listener.Listener, []*core.Address) bool) ([]*listenerWithState, error) {
 	if c.configDump == nil {
 		return nil, fmt.Errorf("config writer has not been primed")
 	}
@@ -151,34 +1810,176 @@ func (c *ConfigWriter) retrieveSortedListenerSlice() ([]*listener.Listener, erro
 	if err != nil {
 		return nil, fmt.Errorf("listener dump: %v", err)
 	}
-	listeners := make([]*listener.Listener, 0)
-	for _, l := range listenerDump.DynamicListeners {
-		if l.ActiveState != nil && l.ActiveState.Listener != nil {
-			listenerTyped := &listener.Listener{}
-			// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
-			l.ActiveState.Listener.TypeUrl = v3.ListenerType
-			err = ptypes.UnmarshalAny(l.ActiveState.Listener, listenerTyped)
+	listeners := make([]*listenerWithState, 0)
+	found := 0
+	add := func(listenerAny *any.Any, l *listenerWithState) error {
+		found++
+		if match != nil {
+			header, err := unmarshalListenerHeader(listenerAny)
 			if err != nil {
-				return nil, fmt.Errorf("unmarshal listener: %v", err)
+				return err
+			}
+			if !match(header, c.listenerAdditionalAddresses[header.Name]) {
+				return nil
+			}
+		}
+		listenerTyped, err := unmarshalListener(listenerAny)
+		if err != nil {
+			return err
+		}
+		l.Listener = listenerTyped
+		l.additionalAddresses = c.listenerAdditionalAddresses[listenerTyped.Name]
+		listeners = append(listeners, l)
+		return nil
+	}
+	for _, l := range listenerDump.DynamicListeners {
+		states := []struct {
+			name  string
+			state *adminapi.ListenersConfigDump_DynamicListenerState
+		}{
+			{listenerStateActive, l.ActiveState},
+			{listenerStateWarming, l.WarmingState},
+			{listenerStateDraining, l.DrainingState},
+		}
+		for _, s := range states {
+			if s.state == nil || s.state.Listener == nil {
+				continue
+			}
+			if err := add(s.state.Listener, &listenerWithState{
+				state:       s.name,
+				versionInfo: s.state.VersionInfo,
+				lastUpdated: s.state.LastUpdated,
+			}); err != nil {
+				return nil, err
 			}
-			listeners = append(listeners, listenerTyped)
 		}
 	}
 
 	for _, l := range listenerDump.StaticListeners {
 		if l.Listener != nil {
-			listenerTyped := &listener.Listener{}
-			// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
-			l.Listener.TypeUrl = v3.ListenerType
-			err = ptypes.UnmarshalAny(l.Listener, listenerTyped)
-			if err != nil {
-				return nil, fmt.Errorf("unmarshal listener: %v", err)
+			if err := add(l.Listener, &listenerWithState{
+				state:       listenerStateStatic,
+				lastUpdated: l.LastUpdated,
+			}); err != nil {
+				return nil, err
 			}
-			listeners = append(listeners, listenerTyped)
 		}
 	}
-	if len(listeners) == 0 {
-		return nil, fmt.Errorf("no listeners found")
+	if found == 0 {
+		return nil, errNoListeners
 	}
+	sortListeners(listeners, sortByPort)
 	return listeners, nil
 }
+
+// sortListeners orders listeners by the passed key first, then by port, address and name
+func sortListeners(listeners []*listenerWithState, sortBy string) {
+	comparePorts := func(i, j int) int {
+		iPort, jPort := retrieveListenerPort(listeners[i].Listener), retrieveListenerPort(listeners[j].Listener)
+		switch {
+		case iPort < jPort:
+			return -1
+		case iPort > jPort:
+			return 1
+		}
+		return 0
+	}
+	compareAddresses := func(i, j int) int {
+		return compareListenerAddresses(retrieveListenerAddress(listeners[i].Listener), retrieveListenerAddress(listeners[j].Listener))
+	}
+	compareNames := func(i, j int) int {
+		return strings.Compare(listeners[i].Name, listeners[j].Name)
+	}
+	keys := []func(i, j int) int{comparePorts, compareAddresses, compareNames}
+	switch sortBy {
+	case sortByAddress:
+		keys = []func(i, j int) int{compareAddresses, comparePorts, compareNames}
+	case sortByName:
+		keys = []func(i, j int) int{compareNames, comparePorts, compareAddresses}
+	}
+	// Stable so that the states of a listener keep the active, warming, draining order they were added in
+	sort.SliceStable(listeners, func(i, j int) bool {
+		for _, key := range keys {
+			if c := key(i, j); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// compareListenerAddresses orders IP addresses numerically, so 10.0.0.2 comes before 10.0.0.10,
+// ahead of pipe paths which are ordered as strings
+func compareListenerAddresses(a, b string) int {
+	aIP, bIP := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case aIP != nil && bIP != nil:
+		return bytes.Compare(aIP.To16(), bIP.To16())
+	case aIP != nil:
+		return -1
+	case bIP != nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Field numbers of the envoy.config.listener.v3.Listener fields unmarshalListenerHeader decodes
+const (
+	listenerNameField    = 1
+	listenerAddressField = 2
+)
+
+// unmarshalListenerHeader unmarshals just the name and address of a listener, skipping the filter chains and the
+// other fields in its wire format, which is enough to match a listener by name, address or port
+func unmarshalListenerHeader(listenerAny *any.Any) (*listener.Listener, error) {
+	header := &listener.Listener{}
+	b := listenerAny.GetValue()
+	for len(b) > 0 {
+		key, n := proto.DecodeVarint(b)
+		if n == 0 {
+			return nil, fmt.Errorf("unmarshal listener: invalid field key")
+		}
+		b = b[n:]
+		var value []byte
+		switch key & 7 {
+		case proto.WireVarint:
+			_, n = proto.DecodeVarint(b)
+		case proto.WireFixed64:
+			n = 8
+		case proto.WireFixed32:
+			n = 4
+		case proto.WireBytes:
+			length, m := proto.DecodeVarint(b)
+			if m == 0 || length > uint64(len(b)-m) {
+				return nil, fmt.Errorf("unmarshal listener: invalid field length")
+			}
+			n = m + int(length)
+			value = b[m:n]
+		default:
+			return nil, fmt.Errorf("unmarshal listener: unsupported wire type %d", key&7)
+		}
+		if n == 0 || n > len(b) {
+			return nil, fmt.Errorf("unmarshal listener: truncated field %d", key>>3)
+		}
+		b = b[n:]
+		switch key >> 3 {
+		case listenerNameField:
+			header.Name = string(value)
+		case listenerAddressField:
+			address := &core.Address{}
+			if err := proto.Unmarshal(value, address); err != nil {
+				return nil, fmt.Errorf("unmarshal listener address: %v", err)
+			}
+			header.Address = address
+		}
+	}
+	return header, nil
+}
+
+func unmarshalListener(listenerAny *any.Any) (*listener.Listener, error) {
+	listenerTyped := &listener.Listener{}
+	if err := unmarshalAnyAs(v3.ListenerType, listenerAny, listenerTyped); err != nil {
+		return nil, fmt.Errorf("unmarshal listener: %v", err)
+	}
+	return listenerTyped, nil
+}
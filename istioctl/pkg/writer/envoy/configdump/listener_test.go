@@ -15,10 +15,40 @@
 package configdump
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacconfig "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	fileaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	grpcaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	jwtauthn "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/jwt_authn/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	rbactcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/test/util"
 )
 
 func TestListenerFilter_Verify(t *testing.T) {
@@ -70,6 +100,52 @@ func TestListenerFilter_Verify(t *testing.T) {
 			},
 			expect: false,
 		},
+		{
+			desc:       "ipv6-addrs-match-canonically",
+			inFilter:   &ListenerFilter{Address: "0:0:0:0:0:0:0:1"},
+			inListener: newSocketListener("::1", 15006),
+			expect:     true,
+		},
+		{
+			desc:       "ipv6-addrs-match-case-insensitively",
+			inFilter:   &ListenerFilter{Address: "FD00::A"},
+			inListener: newSocketListener("fd00::a", 15006),
+			expect:     true,
+		},
+		{
+			desc:       "ipv6-addrs-mismatch",
+			inFilter:   &ListenerFilter{Address: "::1"},
+			inListener: newSocketListener("::", 15006),
+			expect:     false,
+		},
+		{
+			desc: "pipe-addrs-match",
+			inFilter: &ListenerFilter{
+				Address: "/etc/istio/proxy/XDS",
+			},
+			inListener: &listener.Listener{
+				Address: &v3.Address{
+					Address: &v3.Address_Pipe{
+						Pipe: &v3.Pipe{Path: "/etc/istio/proxy/XDS"},
+					},
+				},
+			},
+			expect: true,
+		},
+		{
+			desc: "pipe-has-no-port",
+			inFilter: &ListenerFilter{
+				Port: 15090,
+			},
+			inListener: &listener.Listener{
+				Address: &v3.Address{
+					Address: &v3.Address_Pipe{
+						Pipe: &v3.Pipe{Path: "/etc/istio/proxy/XDS"},
+					},
+				},
+			},
+			expect: false,
+		},
 		{
 			desc: "http-type-match",
 			inFilter: &ListenerFilter{
@@ -121,23 +197,2348 @@ func TestListenerFilter_Verify(t *testing.T) {
 			expect: true,
 		},
 		{
-			desc: "unknown-type",
+			desc: "direction-match",
 			inFilter: &ListenerFilter{
-				Type: "UNKNOWN",
+				Direction: "inbound",
+			},
+			inListener: &listener.Listener{
+				TrafficDirection: v3.TrafficDirection_INBOUND,
+			},
+			expect: true,
+		},
+		{
+			desc: "direction-dont-match",
+			inFilter: &ListenerFilter{
+				Direction: "inbound",
+			},
+			inListener: &listener.Listener{
+				TrafficDirection: v3.TrafficDirection_OUTBOUND,
+			},
+			expect: false,
+		},
+		{
+			desc: "direction-unset",
+			inFilter: &ListenerFilter{
+				Direction: "outbound",
+			},
+			inListener: &listener.Listener{},
+			expect:     false,
+		},
+		{
+			desc: "direction-and-port-match",
+			inFilter: &ListenerFilter{
+				Port:      15006,
+				Direction: "inbound",
+			},
+			inListener: &listener.Listener{
+				Address: &v3.Address{
+					Address: &v3.Address_SocketAddress{
+						SocketAddress: &v3.SocketAddress{
+							PortSpecifier: &v3.SocketAddress_PortValue{
+								PortValue: 15006,
+							},
+						},
+					},
+				},
+			},
+			expect: true,
+		},
+		{
+			desc: "direction-from-name",
+			inFilter: &ListenerFilter{
+				Direction: "outbound",
+			},
+			inListener: &listener.Listener{
+				Name: "outbound_0.0.0.0_8080",
+			},
+			expect: true,
+		},
+		{
+			desc: "virtual-inbound-any-direction",
+			inFilter: &ListenerFilter{
+				Direction: "outbound",
+			},
+			inListener: &listener.Listener{
+				Name:             "virtualInbound",
+				TrafficDirection: v3.TrafficDirection_INBOUND,
+			},
+			expect: true,
+		},
+		{
+			desc: "sni-wildcard-match",
+			inFilter: &ListenerFilter{
+				SNI: "foo.example.com",
+			},
+			inListener: &listener.Listener{
+				FilterChains: []*listener.FilterChain{
+					{FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{"bar.com"}}},
+					{FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{"*.Example.com"}}},
+				},
+			},
+			expect: true,
+		},
+		{
+			desc: "sni-dont-match",
+			inFilter: &ListenerFilter{
+				SNI: "foo.example.com",
+			},
+			inListener: &listener.Listener{
+				FilterChains: []*listener.FilterChain{
+					{FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{"example.com"}}},
+					{},
+				},
+			},
+			expect: false,
+		},
+		{
+			desc: "name-match",
+			inFilter: &ListenerFilter{
+				Name: "virtualOutbound",
+				Port: 15001,
+			},
+			inListener: &listener.Listener{
+				Name: "virtualOutbound",
+				Address: &v3.Address{
+					Address: &v3.Address_SocketAddress{
+						SocketAddress: &v3.SocketAddress{
+							PortSpecifier: &v3.SocketAddress_PortValue{
+								PortValue: 15001,
+							},
+						},
+					},
+				},
+			},
+			expect: true,
+		},
+		{
+			desc: "name-is-exact",
+			inFilter: &ListenerFilter{
+				Name: "virtual",
+			},
+			inListener: &listener.Listener{
+				Name: "virtualOutbound",
+			},
+			expect: false,
+		},
+		{
+			desc: "name-contains-match",
+			inFilter: &ListenerFilter{
+				NameContains: "foo.bar.svc",
+			},
+			inListener: &listener.Listener{
+				Name: "outbound|8080||foo.bar.svc.cluster.local",
+			},
+			expect: true,
+		},
+		{
+			desc: "name-contains-dont-match",
+			inFilter: &ListenerFilter{
+				NameContains: "foo.baz.svc",
+			},
+			inListener: &listener.Listener{
+				Name: "outbound|8080||foo.bar.svc.cluster.local",
+			},
+			expect: false,
+		},
+		{
+			desc: "name-regex-match",
+			inFilter: &ListenerFilter{
+				Name: "~0\\.0\\.0\\.0_.*",
+			},
+			inListener: &listener.Listener{
+				Name: "0.0.0.0_15006",
+			},
+			expect: true,
+		},
+		{
+			desc: "name-empty-listener",
+			inFilter: &ListenerFilter{
+				Name: "virtualOutbound",
+			},
+			inListener: &listener.Listener{},
+			expect:     false,
+		},
+		{
+			desc: "name-regex-empty-listener",
+			inFilter: &ListenerFilter{
+				Name: "~.*",
+			},
+			inListener: &listener.Listener{},
+			expect:     true,
+		},
+		{
+			desc: "name-regex-invalid",
+			inFilter: &ListenerFilter{
+				Name: "~(",
+			},
+			inListener: &listener.Listener{
+				Name: "(",
+			},
+			expect: false,
+		},
+		{
+			desc: "udp-type-match",
+			inFilter: &ListenerFilter{
+				Type: "UDP",
+			},
+			inListener: &listener.Listener{
+				ListenerFilters: []*listener.ListenerFilter{{
+					Name: "envoy.filters.udp_listener.udp_proxy",
+				}},
+			},
+			expect: true,
+		},
+		{
+			desc: "original-dst-keeps-type",
+			inFilter: &ListenerFilter{
+				Type: "TCP",
 			},
 			inListener: &listener.Listener{
+				ListenerFilters: []*listener.ListenerFilter{{
+					Name: "envoy.listener.original_dst",
+				}},
 				FilterChains: []*listener.FilterChain{{
-					Filters: []*listener.Filter{},
+					Filters: []*listener.Filter{{
+						Name: "envoy.tcp_proxy",
+					}},
 				}},
 			},
 			expect: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.desc, func(t *testing.T) {
-			if got := tt.inFilter.Verify(tt.inListener); got != tt.expect {
-				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+		{
+			desc:       "ports-match",
+			inFilter:   &ListenerFilter{Ports: []uint32{80, 443, 15443}},
+			inListener: newSocketListener("0.0.0.0", 443),
+			expect:     true,
+		},
+		{
+			desc:       "ports-dont-match",
+			inFilter:   &ListenerFilter{Ports: []uint32{80, 443, 15443}},
+			inListener: newSocketListener("0.0.0.0", 8443),
+			expect:     false,
+		},
+		{
+			desc:       "port-range-match",
+			inFilter:   &ListenerFilter{PortRange: &PortRange{Min: 15000, Max: 15100}},
+			inListener: newSocketListener("0.0.0.0", 15001),
+			expect:     true,
+		},
+		{
+			desc:       "port-range-inclusive",
+			inFilter:   &ListenerFilter{PortRange: &PortRange{Min: 15000, Max: 15100}},
+			inListener: newSocketListener("0.0.0.0", 15100),
+			expect:     true,
+		},
+		{
+			desc:       "port-range-dont-match",
+			inFilter:   &ListenerFilter{PortRange: &PortRange{Min: 15000, Max: 15100}},
+			inListener: newSocketListener("0.0.0.0", 9080),
+			expect:     false,
+		},
+		{
+			desc:       "skip-virtual-by-name",
+			inFilter:   &ListenerFilter{SkipVirtual: true},
+			inListener: &listener.Listener{Name: "virtualOutbound"},
+			expect:     false,
+		},
+		{
+			desc:       "skip-virtual-by-port",
+			inFilter:   &ListenerFilter{SkipVirtual: true},
+			inListener: newSocketListener("0.0.0.0", 15021),
+			expect:     false,
+		},
+		{
+			desc:       "skip-virtual-keeps-services",
+			inFilter:   &ListenerFilter{SkipVirtual: true},
+			inListener: newSocketListener("10.96.0.1", 9080),
+			expect:     true,
+		},
+		{
+			desc:       "address-regex-match",
+			inFilter:   &ListenerFilter{AddressRegex: `10\.0\..*`},
+			inListener: newSocketListener("10.0.3.4", 9080),
+			expect:     true,
+		},
+		{
+			desc:       "address-regex-is-anchored",
+			inFilter:   &ListenerFilter{AddressRegex: `10\.0\..*`},
+			inListener: newSocketListener("110.0.3.4", 9080),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-match",
+			inFilter:   &ListenerFilter{Address: "10.96.0.0/16"},
+			inListener: newSocketListener("10.96.12.7", 0),
+			expect:     true,
+		},
+		{
+			desc:       "cidr-dont-match",
+			inFilter:   &ListenerFilter{Address: "10.96.0.0/16"},
+			inListener: newSocketListener("10.97.0.1", 0),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-32-match",
+			inFilter:   &ListenerFilter{Address: "10.96.12.7/32"},
+			inListener: newSocketListener("10.96.12.7", 0),
+			expect:     true,
+		},
+		{
+			desc:       "cidr-32-dont-match",
+			inFilter:   &ListenerFilter{Address: "10.96.12.7/32"},
+			inListener: newSocketListener("10.96.12.8", 0),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-0-matches-wildcard",
+			inFilter:   &ListenerFilter{Address: "0.0.0.0/0"},
+			inListener: newSocketListener("0.0.0.0", 0),
+			expect:     true,
+		},
+		{
+			desc:       "cidr-excludes-wildcard",
+			inFilter:   &ListenerFilter{Address: "10.0.0.0/8"},
+			inListener: newSocketListener("0.0.0.0", 0),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-ipv6-match",
+			inFilter:   &ListenerFilter{Address: "fd00::/8"},
+			inListener: newSocketListener("fd00::a", 0),
+			expect:     true,
+		},
+		{
+			desc:       "cidr-ipv4-excludes-ipv6",
+			inFilter:   &ListenerFilter{Address: "0.0.0.0/0"},
+			inListener: newSocketListener("::", 0),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-field-match",
+			inFilter:   &ListenerFilter{CIDR: "10.0.0.0/8"},
+			inListener: newSocketListener("10.1.2.3", 9080),
+			expect:     true,
+		},
+		{
+			desc:       "cidr-field-dont-match",
+			inFilter:   &ListenerFilter{CIDR: "10.0.0.0/8"},
+			inListener: newSocketListener("192.168.0.1", 9080),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-field-ipv6-match",
+			inFilter:   &ListenerFilter{CIDR: "2001:db8::/32"},
+			inListener: newSocketListener("2001:db8::1", 9080),
+			expect:     true,
+		},
+		{
+			desc:       "cidr-field-and-port",
+			inFilter:   &ListenerFilter{CIDR: "10.0.0.0/8", Port: 9080},
+			inListener: newSocketListener("10.1.2.3", 9090),
+			expect:     false,
+		},
+		{
+			desc:       "cidr-field-and-address",
+			inFilter:   &ListenerFilter{CIDR: "10.0.0.0/8", Address: "10.1.2.4"},
+			inListener: newSocketListener("10.1.2.3", 9080),
+			expect:     false,
+		},
+		{
+			desc:     "cidr-field-pipe",
+			inFilter: &ListenerFilter{CIDR: "0.0.0.0/0"},
+			inListener: &listener.Listener{
+				Address: &v3.Address{Address: &v3.Address_Pipe{Pipe: &v3.Pipe{Path: "/var/run/envoy.sock"}}},
+			},
+			expect: false,
+		},
+		{
+			desc:       "cidr-malformed",
+			inFilter:   &ListenerFilter{Address: "10.96.0.0/33"},
+			inListener: newSocketListener("10.96.0.1", 0),
+			expect:     false,
+		},
+		{
+			desc: "blackhole-type",
+			inFilter: &ListenerFilter{
+				Type: "TCP (blackhole)",
+			},
+			inListener: &listener.Listener{
+				FilterChains: []*listener.FilterChain{{
+					Filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+						ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "BlackHoleCluster"},
+					})},
+				}},
+			},
+			expect: true,
+		},
+		{
+			desc: "passthrough-type",
+			inFilter: &ListenerFilter{
+				Type: "TCP",
+			},
+			inListener: &listener.Listener{
+				FilterChains: []*listener.FilterChain{{
+					Filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+						ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "InboundPassthroughClusterIpv6"},
+					})},
+				}},
+			},
+			expect: false,
+		},
+		{
+			desc: "internal-type",
+			inFilter: &ListenerFilter{
+				Type: "internal",
+			},
+			inListener: &listener.Listener{
+				Name: "connect_originate",
+				FilterChains: []*listener.FilterChain{{
+					Filters: []*listener.Filter{{Name: "envoy.tcp_proxy"}},
+				}},
+			},
+			expect: true,
+		},
+		{
+			desc: "socket-not-internal-type",
+			inFilter: &ListenerFilter{
+				Type: "internal",
+			},
+			inListener: newSocketListener("0.0.0.0", 8080),
+			expect:     false,
+		},
+		{
+			desc: "unknown-type",
+			inFilter: &ListenerFilter{
+				Type: "UNKNOWN",
+			},
+			inListener: &listener.Listener{
+				FilterChains: []*listener.FilterChain{{
+					Filters: []*listener.Filter{},
+				}},
+			},
+			expect: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := tt.inFilter.Verify(tt.inListener); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestFormatListenerAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "0.0.0.0", want: "0.0.0.0"},
+		{in: "10.1.2.3", want: "10.1.2.3"},
+		{in: "::", want: "[::]"},
+		{in: "fe80::1", want: "[fe80::1]"},
+		{in: "FE80:0:0:0:0:0:0:1", want: "[fe80::1]"},
+		{in: "::ffff:10.1.2.3", want: "[::ffff:10.1.2.3]"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := formatListenerAddress(tt.in); got != tt.want {
+				t.Errorf("formatListenerAddress(%q): expect %v got %v", tt.in, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDescribeFilterChainMatch(t *testing.T) {
+	tests := []struct {
+		desc  string
+		match *listener.FilterChainMatch
+		want  string
+	}{
+		{
+			desc: "nil-match",
+			want: "ALL",
+		},
+		{
+			desc: "sni",
+			match: &listener.FilterChainMatch{
+				ServerNames: []string{"foo.com", "*.bar.com"},
+			},
+			want: "SNI: foo.com,*.bar.com",
+		},
+		{
+			desc: "cidr-port-alpn",
+			match: &listener.FilterChainMatch{
+				DestinationPort:      &wrappers.UInt32Value{Value: 8080},
+				PrefixRanges:         []*v3.CidrRange{{AddressPrefix: "10.0.0.0", PrefixLen: &wrappers.UInt32Value{Value: 8}}},
+				TransportProtocol:    "tls",
+				ApplicationProtocols: []string{"istio-peer-exchange", "istio"},
+			},
+			want: "Addr: 10.0.0.0/8:8080; Trans: tls; App: istio-peer-exchange,istio",
+		},
+		{
+			desc: "port-only",
+			match: &listener.FilterChainMatch{
+				DestinationPort: &wrappers.UInt32Value{Value: 9080},
+			},
+			want: "Addr: *:9080",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := describeFilterChainMatch(tt.match); got != tt.want {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetrieveMatchingFilterNames(t *testing.T) {
+	newHCM := func(httpFilters ...string) *listener.Filter {
+		httpConnectionManager := &hcm.HttpConnectionManager{}
+		for _, name := range httpFilters {
+			httpConnectionManager.HttpFilters = append(httpConnectionManager.HttpFilters, &hcm.HttpFilter{Name: name})
+		}
+		return newTypedFilter(t, HTTPListener, httpConnectionManager)
+	}
+	l := &listener.Listener{
+		FilterChains: []*listener.FilterChain{
+			{Filters: []*listener.Filter{{Name: "envoy.filters.network.ext_authz"}, {Name: TCPListener}}},
+			{Filters: []*listener.Filter{newHCM("envoy.filters.http.ext_authz", "envoy.router")}},
+			{Filters: []*listener.Filter{newHCM("envoy.filters.http.ext_authz", "envoy.filters.http.wasm", "envoy.router")}},
+		},
+	}
+	tests := []struct {
+		desc string
+		name string
+		want []string
+	}{
+		{
+			desc: "network-and-http-filters",
+			name: "ext_authz",
+			want: []string{"envoy.filters.network.ext_authz", "envoy.filters.http.ext_authz"},
+		},
+		{
+			desc: "http-filter-in-one-chain",
+			name: "wasm",
+			want: []string{"envoy.filters.http.wasm"},
+		},
+		{
+			desc: "no-match",
+			name: "lua",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveMatchingFilterNames(l, tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDescribeTransportSocket(t *testing.T) {
+	newTLSSocket := func(tlsContext *tls.DownstreamTlsContext) *v3.TransportSocket {
+		return &v3.TransportSocket{
+			Name:       "envoy.transport_sockets.tls",
+			ConfigType: &v3.TransportSocket_TypedConfig{TypedConfig: mustMarshalAny(t, tlsContext)},
+		}
+	}
+	tests := []struct {
+		desc            string
+		transportSocket *v3.TransportSocket
+		want            string
+	}{
+		{
+			desc: "none",
+			want: "NONE",
+		},
+		{
+			desc:            "raw-buffer",
+			transportSocket: &v3.TransportSocket{Name: "envoy.transport_sockets.raw_buffer"},
+			want:            "RAW_BUFFER",
+		},
+		{
+			desc:            "tls",
+			transportSocket: newTLSSocket(&tls.DownstreamTlsContext{}),
+			want:            "TLS",
+		},
+		{
+			desc: "mtls-alpn",
+			transportSocket: newTLSSocket(&tls.DownstreamTlsContext{
+				RequireClientCertificate: &wrappers.BoolValue{Value: true},
+				CommonTlsContext: &tls.CommonTlsContext{
+					AlpnProtocols: []string{"istio-peer-exchange", "istio"},
+				},
+			}),
+			want: "mTLS (ALPN: istio-peer-exchange,istio)",
+		},
+		{
+			desc:            "custom",
+			transportSocket: &v3.TransportSocket{Name: "envoy.transport_sockets.alts"},
+			want:            "envoy.transport_sockets.alts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := describeTransportSocket(tt.transportSocket); got != tt.want {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetrieveListenerTLSMode(t *testing.T) {
+	plaintext := &listener.FilterChain{}
+	simple := &listener.FilterChain{TransportSocket: &v3.TransportSocket{
+		Name:       "envoy.transport_sockets.tls",
+		ConfigType: &v3.TransportSocket_TypedConfig{TypedConfig: mustMarshalAny(t, &tls.DownstreamTlsContext{})},
+	}}
+	mutual := &listener.FilterChain{TransportSocket: &v3.TransportSocket{
+		Name: "envoy.transport_sockets.tls",
+		ConfigType: &v3.TransportSocket_TypedConfig{TypedConfig: mustMarshalAny(t, &tls.DownstreamTlsContext{
+			RequireClientCertificate: &wrappers.BoolValue{Value: true},
+		})},
+	}}
+	tests := []struct {
+		desc   string
+		chains []*listener.FilterChain
+		want   string
+	}{
+		{
+			desc: "no-chains",
+			want: "DISABLE",
+		},
+		{
+			desc:   "plaintext",
+			chains: []*listener.FilterChain{plaintext},
+			want:   "DISABLE",
+		},
+		{
+			desc:   "strict",
+			chains: []*listener.FilterChain{mutual, mutual},
+			want:   "mTLS",
+		},
+		{
+			desc:   "permissive",
+			chains: []*listener.FilterChain{mutual, plaintext},
+			want:   "PERMISSIVE",
+		},
+		{
+			desc:   "simple",
+			chains: []*listener.FilterChain{simple, mutual},
+			want:   "TLS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveListenerTLSMode(&listener.Listener{FilterChains: tt.chains}); got != tt.want {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+
+	filter := &ListenerFilter{TLSMode: "permissive"}
+	if !filter.Verify(&listener.Listener{FilterChains: []*listener.FilterChain{mutual, plaintext}}) {
+		t.Errorf("expected a permissive listener to match the TLS mode case insensitively")
+	}
+	if filter.Verify(&listener.Listener{FilterChains: []*listener.FilterChain{mutual}}) {
+		t.Errorf("expected a strict listener not to match the permissive TLS mode")
+	}
+}
+
+func TestDescribeFilterChainFilters(t *testing.T) {
+	tests := []struct {
+		desc            string
+		filters         []*listener.Filter
+		wantFilter      string
+		wantDestination string
+	}{
+		{
+			desc:            "no-filters",
+			wantFilter:      "-",
+			wantDestination: "-",
+		},
+		{
+			desc: "rds",
+			filters: []*listener.Filter{
+				newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+					RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: "9080"}},
+				}),
+			},
+			wantFilter:      HTTPListener,
+			wantDestination: "9080",
+		},
+		{
+			desc: "inline-route",
+			filters: []*listener.Filter{
+				newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+					RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{RouteConfig: &route.RouteConfiguration{}},
+				}),
+			},
+			wantFilter:      HTTPListener,
+			wantDestination: "inline",
+		},
+		{
+			desc: "tcp-proxy",
+			filters: []*listener.Filter{
+				{Name: "envoy.filters.network.rbac"},
+				newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+					ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "outbound|3306||mysql.default.svc.cluster.local"},
+				}),
+			},
+			wantFilter:      TCPListener,
+			wantDestination: "outbound|3306||mysql.default.svc.cluster.local",
+		},
+		{
+			desc: "canonical-tcp-proxy",
+			filters: []*listener.Filter{
+				newTypedFilter(t, TCPListenerCanonical, &tcp.TcpProxy{
+					ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "PassthroughCluster"},
+				}),
+			},
+			wantFilter:      TCPListenerCanonical,
+			wantDestination: "PassthroughCluster",
+		},
+		{
+			desc: "weighted-tcp-proxy",
+			filters: []*listener.Filter{
+				newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+					ClusterSpecifier: &tcp.TcpProxy_WeightedClusters{WeightedClusters: &tcp.TcpProxy_WeightedCluster{
+						Clusters: []*tcp.TcpProxy_WeightedCluster_ClusterWeight{
+							{Name: "outbound|3306|v1|mysql.default.svc.cluster.local", Weight: 80},
+							{Name: "outbound|3306|v2|mysql.default.svc.cluster.local", Weight: 20},
+						},
+					}},
+				}),
+			},
+			wantFilter:      TCPListener,
+			wantDestination: "weighted(2)",
+		},
+		{
+			desc: "custom-name-rds",
+			filters: []*listener.Filter{
+				newTypedFilter(t, "acme.http", &hcm.HttpConnectionManager{
+					RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: "9090"}},
+				}),
+			},
+			wantFilter:      "acme.http",
+			wantDestination: "9090",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotFilter, gotDestination := describeFilterChainFilters(tt.filters)
+			if gotFilter != tt.wantFilter || gotDestination != tt.wantDestination {
+				t.Errorf("%s: expect %v/%v got %v/%v", tt.desc, tt.wantFilter, tt.wantDestination, gotFilter, gotDestination)
+			}
+		})
+	}
+}
+
+func TestDescribeListenerFilters(t *testing.T) {
+	tests := []struct {
+		desc       string
+		inListener *listener.Listener
+		expect     string
+	}{
+		{
+			desc:       "no-listener-filters",
+			inListener: &listener.Listener{},
+			expect:     "-",
+		},
+		{
+			desc: "sniffing-with-timeout",
+			inListener: &listener.Listener{
+				ListenerFilters: []*listener.ListenerFilter{
+					{Name: "envoy.filters.listener.original_dst"},
+					{Name: "envoy.filters.listener.tls_inspector"},
+					{Name: "envoy.filters.listener.http_inspector"},
+				},
+				ListenerFiltersTimeout: ptypes.DurationProto(time.Second),
+			},
+			expect: "original_dst,tls_inspector,http_inspector (timeout: 1s)",
+		},
+		{
+			desc: "legacy-names-default-timeout",
+			inListener: &listener.Listener{
+				ListenerFilters:        []*listener.ListenerFilter{{Name: "envoy.listener.tls_inspector"}},
+				ListenerFiltersTimeout: ptypes.DurationProto(15 * time.Second),
+			},
+			expect: "tls_inspector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := describeListenerFilters(tt.inListener); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDescribeAccessLogs(t *testing.T) {
+	fileAccessLog, err := ptypes.MarshalAny(&fileaccesslog.FileAccessLog{Path: "/dev/stdout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcAccessLog, err := ptypes.MarshalAny(&grpcaccesslog.HttpGrpcAccessLogConfig{
+		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{LogName: "otel"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownAccessLog := &any.Any{TypeUrl: "type.googleapis.com/envoy.extensions.access_loggers.wasm.v3.WasmAccessLog"}
+	tests := []struct {
+		desc    string
+		filters []*listener.Filter
+		expect  string
+	}{
+		{
+			desc:   "no-filters",
+			expect: "none",
+		},
+		{
+			desc:    "no-access-log",
+			filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{})},
+			expect:  "none",
+		},
+		{
+			desc: "http-sinks",
+			filters: []*listener.Filter{
+				newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+					AccessLog: []*accesslog.AccessLog{
+						{Name: "envoy.access_loggers.file", ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: fileAccessLog}},
+						{Name: "envoy.access_loggers.http_grpc", ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: grpcAccessLog}},
+					},
+				}),
+			},
+			expect: "file:/dev/stdout,grpc:otel",
+		},
+		{
+			desc: "unknown-sink",
+			filters: []*listener.Filter{
+				newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+					AccessLog: []*accesslog.AccessLog{
+						{Name: "envoy.access_loggers.wasm", ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: unknownAccessLog}},
+					},
+				}),
+			},
+			expect: "WasmAccessLog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := describeAccessLogs(tt.filters); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func newIstioConfigMetadata(config string) *v3.Metadata {
+	return &v3.Metadata{FilterMetadata: map[string]*structpb.Struct{
+		"istio": {Fields: map[string]*structpb.Value{"config": {Kind: &structpb.Value_StringValue{StringValue: config}}}},
+	}}
+}
+
+func TestDescribeIstioConfig(t *testing.T) {
+	gateway := newIstioConfigMetadata("/apis/networking.istio.io/v1alpha3/namespaces/istio-system/gateway/ingress")
+	envoyFilter := newIstioConfigMetadata("/apis/networking.istio.io/v1alpha3/namespaces/istio-system/envoy-filter/xff")
+	tests := []struct {
+		desc             string
+		chainMetadata    *v3.Metadata
+		listenerMetadata *v3.Metadata
+		expect           string
+	}{
+		{
+			desc:   "no-metadata",
+			expect: "-",
+		},
+		{
+			desc:          "filter-chain",
+			chainMetadata: gateway,
+			expect:        "Gateway/istio-system/ingress",
+		},
+		{
+			desc:             "listener",
+			listenerMetadata: newIstioConfigMetadata("/apis/networking.istio.io/v1alpha3/namespaces/default/virtual-service/reviews"),
+			expect:           "VirtualService/default/reviews",
+		},
+		{
+			desc:             "same-config",
+			chainMetadata:    gateway,
+			listenerMetadata: gateway,
+			expect:           "Gateway/istio-system/ingress",
+		},
+		{
+			desc:             "patched-filter-chain",
+			chainMetadata:    envoyFilter,
+			listenerMetadata: gateway,
+			expect:           "Gateway/istio-system/ingress (patched)",
+		},
+		{
+			desc:             "patched-listener",
+			chainMetadata:    gateway,
+			listenerMetadata: envoyFilter,
+			expect:           "Gateway/istio-system/ingress (patched)",
+		},
+		{
+			desc:          "created-by-envoy-filter",
+			chainMetadata: envoyFilter,
+			expect:        "EnvoyFilter/istio-system/xff",
+		},
+		{
+			desc:          "malformed-config",
+			chainMetadata: newIstioConfigMetadata("ingress"),
+			expect:        "-",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := describeIstioConfig(tt.chainMetadata, tt.listenerMetadata); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDescribeFilterChainPolicies(t *testing.T) {
+	newHTTPFilter := func(name string, config proto.Message) *hcm.HttpFilter {
+		typedConfig, err := ptypes.MarshalAny(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &hcm.HttpFilter{Name: name, ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: typedConfig}}
+	}
+	rules := &rbacconfig.RBAC{Policies: map[string]*rbacconfig.Policy{"ns[default]-policy[a]-rule[0]": {}, "ns[default]-policy[b]-rule[0]": {}}}
+	tests := []struct {
+		desc    string
+		filters []*listener.Filter
+		expect  string
+	}{
+		{
+			desc:    "unprotected",
+			filters: []*listener.Filter{newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{})},
+			expect:  "-",
+		},
+		{
+			desc: "jwt-and-rbac",
+			filters: []*listener.Filter{
+				newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+					HttpFilters: []*hcm.HttpFilter{
+						newHTTPFilter("envoy.filters.http.jwt_authn", &jwtauthn.JwtAuthentication{
+							Providers: map[string]*jwtauthn.JwtProvider{"origins-0": {Issuer: "foo"}, "origins-1": {Issuer: "bar"}},
+						}),
+						newHTTPFilter("envoy.filters.http.rbac", &rbachttp.RBAC{
+							Rules: &rbacconfig.RBAC{Action: rbacconfig.RBAC_DENY, Policies: map[string]*rbacconfig.Policy{"deny": {}}},
+						}),
+						newHTTPFilter("envoy.filters.http.rbac", &rbachttp.RBAC{Rules: rules}),
+						{Name: "envoy.router"},
+					},
+				}),
+			},
+			expect: "jwt(issuer=bar|foo),rbac(deny, 1 policy),rbac(2 policies)",
+		},
+		{
+			desc: "network-rbac-shadow",
+			filters: []*listener.Filter{
+				newTypedFilter(t, "envoy.filters.network.rbac", &rbactcp.RBAC{ShadowRules: rules}),
+				newTypedFilter(t, TCPListener, &tcp.TcpProxy{}),
+			},
+			expect: "rbac(shadow, 2 policies)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := describeFilterChainPolicies(tt.filters); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestRetrieveListenerDestinations(t *testing.T) {
+	newTCPProxyChain := func(cluster string) *listener.FilterChain {
+		return &listener.FilterChain{
+			Filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+				ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: cluster},
+			})},
+		}
+	}
+	tests := []struct {
+		desc        string
+		chains      []*listener.FilterChain
+		destination string
+	}{
+		{
+			desc:        "no-chains",
+			destination: "-",
+		},
+		{
+			desc:        "no-destination",
+			chains:      []*listener.FilterChain{{Filters: []*listener.Filter{{Name: "envoy.filters.network.rbac"}}}},
+			destination: "-",
+		},
+		{
+			desc:        "deduplicated",
+			chains:      []*listener.FilterChain{newTCPProxyChain("a"), newTCPProxyChain("b"), newTCPProxyChain("a")},
+			destination: "a,b",
+		},
+		{
+			desc: "truncated",
+			chains: []*listener.FilterChain{
+				newTCPProxyChain("outbound|3306||mysql.default.svc.cluster.local"),
+				newTCPProxyChain("outbound|5432||postgres.default.svc.cluster.local"),
+			},
+			destination: "outbound|3306||mysql.default.svc.cluster.local,outbound|5...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveListenerDestinations(&listener.Listener{FilterChains: tt.chains}); got != tt.destination {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.destination, got)
+			}
+		})
+	}
+}
+
+func TestRetrieveListenerRoutes(t *testing.T) {
+	newRDSChain := func(routeConfigName string) *listener.FilterChain {
+		return &listener.FilterChain{
+			Filters: []*listener.Filter{newTypedFilter(t, HTTPListenerCanonical, &hcm.HttpConnectionManager{
+				RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: routeConfigName}},
+			})},
+		}
+	}
+	inlineChain := &listener.FilterChain{
+		Filters: []*listener.Filter{newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+			RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{RouteConfig: &route.RouteConfiguration{}},
+		})},
+	}
+	tcpChain := &listener.FilterChain{
+		Filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+			ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "mysql"},
+		})},
+	}
+	tests := []struct {
+		desc   string
+		chains []*listener.FilterChain
+		routes string
+	}{
+		{
+			desc:   "tcp",
+			chains: []*listener.FilterChain{tcpChain},
+			routes: "-",
+		},
+		{
+			desc:   "rds",
+			chains: []*listener.FilterChain{tcpChain, newRDSChain("9080")},
+			routes: "9080",
+		},
+		{
+			desc:   "inline",
+			chains: []*listener.FilterChain{inlineChain},
+			routes: "inline",
+		},
+		{
+			desc:   "deduplicated",
+			chains: []*listener.FilterChain{newRDSChain("9080"), newRDSChain("8080"), newRDSChain("9080")},
+			routes: "9080,8080",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := retrieveListenerRoutes(&listener.Listener{FilterChains: tt.chains}); got != tt.routes {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.routes, got)
+			}
+		})
+	}
+}
+
+func newSocketListener(address string, port uint32) *listener.Listener {
+	return &listener.Listener{
+		Address: &v3.Address{
+			Address: &v3.Address_SocketAddress{
+				SocketAddress: &v3.SocketAddress{
+					Address:       address,
+					PortSpecifier: &v3.SocketAddress_PortValue{PortValue: port},
+				},
+			},
+		},
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryThenDump(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatalf("PrintListenerSummary produced unexpected err: %v", err)
+	}
+	gotOut.Reset()
+	if err := cw.PrintListenerDump(ListenerFilter{}); err != nil {
+		t.Fatalf("PrintListenerDump after PrintListenerSummary produced unexpected err: %v", err)
+	}
+	if !strings.Contains(gotOut.String(), "0.0.0.0_8080") {
+		t.Errorf("expected the listeners in the dump, got:\n%s", gotOut.String())
+	}
+}
+
+func TestUnmarshalListener_KeepsTypeURL(t *testing.T) {
+	const v2ListenerType = "type.googleapis.com/envoy.api.v2.Listener"
+	listenerAny := mustMarshalAny(t, &listener.Listener{Name: "0.0.0.0_8080"})
+	listenerAny.TypeUrl = v2ListenerType
+	for i := 0; i < 2; i++ {
+		l, err := unmarshalListener(listenerAny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Name != "0.0.0.0_8080" {
+			t.Errorf("wanted listener 0.0.0.0_8080, got %v", l.Name)
+		}
+	}
+	if listenerAny.TypeUrl != v2ListenerType {
+		t.Errorf("expected the type URL of the config dump to be left as %v, got %v", v2ListenerType, listenerAny.TypeUrl)
+	}
+}
+
+func TestUnmarshalListenerHeader(t *testing.T) {
+	withChains := newSocketListener("10.0.0.1", 9080)
+	withChains.Name = "10.0.0.1_9080"
+	withChains.TrafficDirection = v3.TrafficDirection_OUTBOUND
+	withChains.FilterChains = []*listener.FilterChain{{
+		Filters: []*listener.Filter{newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+			RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: "9080"}},
+		})},
+	}}
+	tests := []struct {
+		desc       string
+		inListener *listener.Listener
+		expect     *listener.Listener
+	}{
+		{
+			desc:       "socket-with-chains",
+			inListener: withChains,
+			expect:     &listener.Listener{Name: "10.0.0.1_9080", Address: withChains.Address},
+		},
+		{
+			desc: "pipe",
+			inListener: &listener.Listener{
+				Name:    "uds",
+				Address: &v3.Address{Address: &v3.Address_Pipe{Pipe: &v3.Pipe{Path: "/var/run/uds.sock"}}},
+			},
+			expect: &listener.Listener{
+				Name:    "uds",
+				Address: &v3.Address{Address: &v3.Address_Pipe{Pipe: &v3.Pipe{Path: "/var/run/uds.sock"}}},
+			},
+		},
+		{
+			desc:       "internal",
+			inListener: &listener.Listener{Name: "internal", UseOriginalDst: &wrappers.BoolValue{Value: true}},
+			expect:     &listener.Listener{Name: "internal"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := unmarshalListenerHeader(mustMarshalAny(t, tt.inListener))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tt.expect) {
+				t.Errorf("wanted %v, got %v", tt.expect, got)
+			}
+		})
+	}
+
+	truncated := mustMarshalAny(t, withChains)
+	truncated.Value = truncated.Value[:len(truncated.Value)-1]
+	if _, err := unmarshalListenerHeader(truncated); err == nil {
+		t.Errorf("expected an error unmarshalling a truncated listener")
+	}
+}
+
+func TestUnmarshalListenerHeaderMalformed(t *testing.T) {
+	tests := []struct {
+		desc    string
+		inValue []byte
+		wantErr string
+	}{
+		{
+			desc:    "truncated-key",
+			inValue: []byte{0x80},
+			wantErr: "invalid field key",
+		},
+		{
+			desc:    "length-past-end",
+			inValue: []byte{0x0a, 0x05, 'a', 'b'},
+			wantErr: "invalid field length",
+		},
+		{
+			desc:    "truncated-length",
+			inValue: []byte{0x0a, 0x80},
+			wantErr: "invalid field length",
+		},
+		{
+			desc:    "truncated-varint",
+			inValue: []byte{0x18, 0x80},
+			wantErr: "truncated field 3",
+		},
+		{
+			desc:    "truncated-fixed64",
+			inValue: []byte{0x19, 0x01, 0x02},
+			wantErr: "truncated field 3",
+		},
+		{
+			desc:    "truncated-fixed32",
+			inValue: []byte{0x1d, 0x01},
+			wantErr: "truncated field 3",
+		},
+		{
+			desc:    "group",
+			inValue: []byte{0x1b},
+			wantErr: "unsupported wire type 3",
+		},
+		{
+			desc:    "malformed-address",
+			inValue: []byte{0x12, 0x02, 0x0a, 0x05},
+			wantErr: "unmarshal listener address",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := unmarshalListenerHeader(&any.Any{Value: tt.inValue})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected an error containing %q, got %v", tt.desc, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_retrieveFilteredListenerSliceHeader(t *testing.T) {
+	dump := &adminapi.ListenersConfigDump{}
+	for _, port := range []uint32{9080, 9090, 15001} {
+		l := newSocketListener("0.0.0.0", port)
+		l.Name = fmt.Sprintf("0.0.0.0_%d", port)
+		dump.DynamicListeners = append(dump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, l)},
+		})
+	}
+	cw := newConfigWriter(t, &bytes.Buffer{}, dump)
+	listeners, err := cw.retrieveFilteredListenerSlice(ListenerFilter{PortRange: &PortRange{Min: 9000, Max: 10000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 || listeners[0].Name != "0.0.0.0_9080" || listeners[1].Name != "0.0.0.0_9090" {
+		t.Errorf("expected the listeners on ports 9080 and 9090, got %v", listeners)
+	}
+	// Listeners left out by their address still count to tell an empty dump from one without matches
+	listeners, err = cw.retrieveFilteredListenerSlice(ListenerFilter{Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 0 {
+		t.Errorf("expected no listeners, got %v", listeners)
+	}
+}
+
+// newBenchmarkListenerDump returns a dump of count HTTP listeners on their own port, each with a filter chain
+// per destination port, like the outbound listeners of a sidecar in a large mesh
+func newBenchmarkListenerDump(b *testing.B, count int) *adminapi.ListenersConfigDump {
+	b.Helper()
+	dump := &adminapi.ListenersConfigDump{}
+	for i := 0; i < count; i++ {
+		port := uint32(10000 + i)
+		l := newSocketListener(fmt.Sprintf("10.0.%d.%d", i/256, i%256), port)
+		l.Name = fmt.Sprintf("%s_%d", retrieveListenerAddress(l), port)
+		for _, server := range []string{"a", "b", "c"} {
+			l.FilterChains = append(l.FilterChains, &listener.FilterChain{
+				FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{server + ".example.com"}},
+				Filters: []*listener.Filter{newTypedFilter(b, HTTPListener, &hcm.HttpConnectionManager{
+					StatPrefix:     l.Name,
+					RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: strconv.Itoa(int(port))}},
+					HttpFilters:    []*hcm.HttpFilter{{Name: "envoy.filters.http.cors"}, {Name: "envoy.filters.http.router"}},
+				})},
+			})
+		}
+		dump.DynamicListeners = append(dump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(b, l)},
+		})
+	}
+	return dump
+}
+
+func BenchmarkConfigWriter_PrintListenerSummary(b *testing.B) {
+	cw := newConfigWriter(b, ioutil.Discard, newBenchmarkListenerDump(b, 5000))
+	benchmarks := []struct {
+		desc     string
+		inFilter ListenerFilter
+	}{
+		{
+			desc:     "all",
+			inFilter: ListenerFilter{},
+		},
+		{
+			desc:     "port",
+			inFilter: ListenerFilter{Port: 12345},
+		},
+		{
+			desc:     "name-contains",
+			inFilter: ListenerFilter{NameContains: "10.0.1."},
+		},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.desc, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := cw.PrintListenerSummary(bm.inFilter); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	// The baseline of port, unmarshalling every listener before verifying the filter
+	b.Run("port-full-unmarshal", func(b *testing.B) {
+		filter := ListenerFilter{Port: 12345}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			listeners, err := cw.retrieveSortedListenerSlice()
+			if err != nil {
+				b.Fatal(err)
+			}
+			matched := 0
+			for _, l := range listeners {
+				if filter.Verify(l.Listener) {
+					matched++
+				}
+			}
+			if matched != 1 {
+				b.Fatalf("expected one listener on port 12345, got %d", matched)
+			}
+		}
+	})
+}
+
+func newTypedFilter(t testing.TB, name string, config proto.Message) *listener.Filter {
+	t.Helper()
+	typedConfig, err := ptypes.MarshalAny(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &listener.Filter{
+		Name:       name,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: typedConfig},
+	}
+}
+
+func TestRetrieveListenerType_ConfigDump(t *testing.T) {
+	want := map[string]string{
+		"0.0.0.0_8080":    "HTTP",
+		"10.0.0.1_3306":   "TCP",
+		"10.0.0.2_8443":   "HTTP+TCP",
+		"0.0.0.0_9090":    "HTTP",
+		"0.0.0.0_9091":    "TCP",
+		"virtualOutbound": "PASSTHROUGH",
+		"0.0.0.0_15090":   "HTTP",
+	}
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	listeners, err := cw.retrieveSortedListenerSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != len(want) {
+		t.Fatalf("wanted %v listeners, got %v", len(want), len(listeners))
+	}
+	for _, l := range listeners {
+		if got := retrieveListenerType(l.Listener); got != want[l.Name] {
+			t.Errorf("listener %v: wanted type %v, got %v", l.Name, want[l.Name], got)
+		}
+	}
+}
+
+func TestRetrieveListenerType_QUIC(t *testing.T) {
+	want := map[string]struct {
+		listenerType string
+		protocol     string
+	}{
+		"0.0.0.0_443":     {"HTTP", "TCP"},
+		"udp_0.0.0.0_443": {"HTTP3", "UDP"},
+		"0.0.0.0_5353":    {"UDP", "UDP"},
+	}
+	cd, err := ioutil.ReadFile("testdata/listenersquic.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	listeners, err := cw.retrieveSortedListenerSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != len(want) {
+		t.Fatalf("wanted %v listeners, got %v", len(want), len(listeners))
+	}
+	for _, l := range listeners {
+		if got := retrieveListenerType(l.Listener); got != want[l.Name].listenerType {
+			t.Errorf("listener %v: wanted type %v, got %v", l.Name, want[l.Name].listenerType, got)
+		}
+		if got := retrieveListenerProtocol(l.Listener); got != want[l.Name].protocol {
+			t.Errorf("listener %v: wanted protocol %v, got %v", l.Name, want[l.Name].protocol, got)
+		}
+	}
+
+	http3, err := cw.GetListeners(ListenerFilter{Type: "http3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(http3) != 1 || http3[0].Name != "udp_0.0.0.0_443" {
+		t.Errorf("expected only the QUIC listener, got %v", http3)
+	}
+}
+
+func TestConfigWriter_PrintListenerSummary(t *testing.T) {
+	tests := []struct {
+		name           string
+		filter         ListenerFilter
+		wantOutputFile string
+	}{
+		{
+			name:           "narrow by default",
+			filter:         ListenerFilter{Name: "10.0.0.2_8443"},
+			wantOutputFile: "testdata/listenersummary.txt",
+		},
+		{
+			name:           "wide adds filter chain match and destination",
+			filter:         ListenerFilter{Name: "10.0.0.2_8443", Wide: true},
+			wantOutputFile: "testdata/listenersummarywide.txt",
+		},
+		{
+			name:           "verbose marks passthrough chains",
+			filter:         ListenerFilter{Name: "virtualOutbound", Verbose: true},
+			wantOutputFile: "testdata/listenersummaryverbose.txt",
+		},
+		{
+			name:           "columns selects and orders columns",
+			filter:         ListenerFilter{Columns: []string{"PORT", "name", "TYPE"}},
+			wantOutputFile: "testdata/listenersummarycolumns.txt",
+		},
+		{
+			name:           "chains adds the filter chain count",
+			filter:         ListenerFilter{Name: "10.0.0.2_8443", Chains: true},
+			wantOutputFile: "testdata/listenersummarychains.txt",
+		},
+	}
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut}
+			if err := cw.Prime(cd); err != nil {
+				t.Fatal(err)
+			}
+			if err := cw.PrintListenerSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			util.CompareContent(gotOut.Bytes(), tt.wantOutputFile, t)
+		})
+	}
+}
+
+func TestConfigWriter_GetListeners(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := &ConfigWriter{}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	listeners, err := cw.GetListeners(ListenerFilter{PortRange: &PortRange{Min: 9000, Max: 9999}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotNames := make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		gotNames = append(gotNames, l.Name)
+	}
+	if want := []string{"0.0.0.0_9090", "0.0.0.0_9091"}; !reflect.DeepEqual(gotNames, want) {
+		t.Errorf("expect %v got %v", want, gotNames)
+	}
+
+	listeners, err = cw.GetListeners(ListenerFilter{SkipVirtual: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range listeners {
+		if l.Name == "virtualOutbound" || l.Name == "0.0.0.0_15090" {
+			t.Errorf("expected %v to be skipped", l.Name)
+		}
+	}
+	if len(listeners) != 5 {
+		t.Errorf("expected 5 listeners without the virtual ones, got %v", len(listeners))
+	}
+
+	listeners, err = cw.GetListeners(ListenerFilter{Origin: "static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].Name != "0.0.0.0_15090" {
+		t.Errorf("expected only the bootstrap listener, got %v", listeners)
+	}
+
+	if _, err := cw.GetListeners(ListenerFilter{Name: "~virtual(.*"}); err == nil {
+		t.Errorf("expected an error for an invalid filter")
+	}
+
+	// A listener being updated is returned once, in its active state
+	active, warming := newSocketListener("0.0.0.0", 9080), newSocketListener("0.0.0.0", 9080)
+	active.Name, warming.Name = "0.0.0.0_9080", "0.0.0.0_9080"
+	warming.TrafficDirection = v3.TrafficDirection_OUTBOUND
+	cw = newConfigWriter(t, &bytes.Buffer{}, &adminapi.ListenersConfigDump{
+		DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+			Name:         active.Name,
+			ActiveState:  &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, active)},
+			WarmingState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, warming)},
+		}},
+	})
+	listeners, err = cw.GetListeners(ListenerFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].TrafficDirection != v3.TrafficDirection_UNSPECIFIED {
+		t.Errorf("expected only the active listener, got %v", listeners)
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryJSON(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut, OutputFormat: JSON}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{Port: 3306, Chains: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(gotOut.String(), `"port": 3306`) {
+		t.Errorf("expected the port as a JSON number in:\n%s", gotOut.String())
+	}
+	var summaries []ListenerSummary
+	if err := json.Unmarshal(gotOut.Bytes(), &summaries); err != nil {
+		t.Fatalf("summary is not a JSON array: %v\n%s", err, gotOut.String())
+	}
+	one := 1
+	want := []ListenerSummary{{
+		Name:        "10.0.0.1_3306",
+		Address:     "10.0.0.1",
+		Port:        3306,
+		Protocol:    "TCP",
+		Type:        "TCP",
+		Direction:   "outbound",
+		Origin:      "DYNAMIC",
+		State:       "ACTIVE",
+		Chains:      &one,
+		Destination: "outbound|3306||mysql.default.svc.cluster.local",
+	}}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("expect %+v got %+v", want, summaries)
+	}
+
+	gotOut.Reset()
+	cw.OutputFormat = YAML
+	if err := cw.PrintListenerSummary(ListenerFilter{Port: 3306}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(gotOut.String(), "- address: 10.0.0.1\n") {
+		t.Errorf("expected a YAML list in:\n%s", gotOut.String())
+	}
+}
+
+func TestConfigWriter_DiffListeners(t *testing.T) {
+	newListener := func(name string, port uint32, cluster string) *listener.Listener {
+		l := newSocketListener("0.0.0.0", port)
+		l.Name = name
+		l.FilterChains = []*listener.FilterChain{{
+			Filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+				StatPrefix:       cluster,
+				ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: cluster},
+			})},
+		}}
+		return l
+	}
+	newDump := func(listeners ...*listener.Listener) *adminapi.ListenersConfigDump {
+		dump := &adminapi.ListenersConfigDump{}
+		for _, l := range listeners {
+			lastUpdated, err := ptypes.TimestampProto(time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			dump.DynamicListeners = append(dump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+				Name: l.Name,
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{
+					Listener:    mustMarshalAny(t, l),
+					LastUpdated: lastUpdated,
+				},
+			})
+		}
+		return dump
+	}
+	gotOut := &bytes.Buffer{}
+	before := newConfigWriter(t, gotOut, newDump(
+		newListener("0.0.0.0_3306", 3306, "mysql"),
+		newListener("0.0.0.0_5432", 5432, "postgres"),
+		newListener("0.0.0.0_6379", 6379, "redis"),
+	))
+	after := newConfigWriter(t, nil, newDump(
+		newListener("0.0.0.0_3306", 3306, "mysql"),
+		newListener("0.0.0.0_5432", 5432, "postgres-v2"),
+		newListener("0.0.0.0_9042", 9042, "cassandra"),
+	))
+	if err := before.DiffListeners(after, ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"~ 0.0.0.0_5432\n", "--- 0.0.0.0_5432", `"cluster": "postgres-v2"`, "+ 0.0.0.0_9042\n", "- 0.0.0.0_6379\n"} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, gotOut.String())
+		}
+	}
+	if strings.Contains(gotOut.String(), "0.0.0.0_3306") {
+		t.Errorf("expected the unchanged listener to be left out of:\n%s", gotOut.String())
+	}
+
+	gotOut.Reset()
+	before.OutputFormat = JSON
+	if err := before.DiffListeners(after, ListenerFilter{Port: 9042}); err != nil {
+		t.Fatal(err)
+	}
+	var diffs []ResourceDiff
+	if err := json.Unmarshal(gotOut.Bytes(), &diffs); err != nil {
+		t.Fatalf("diff is not a JSON array: %v\n%s", err, gotOut.String())
+	}
+	if want := []ResourceDiff{{Name: "0.0.0.0_9042", Change: "added"}}; !reflect.DeepEqual(diffs, want) {
+		t.Errorf("expect %+v got %+v", want, diffs)
+	}
+}
+
+func TestConfigWriter_PrintListenerCount(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc   string
+		filter ListenerFilter
+		want   string
+	}{
+		{
+			desc: "all",
+			want: "7\n",
+		},
+		{
+			desc:   "http",
+			filter: ListenerFilter{Type: "HTTP"},
+			want:   "3\n",
+		},
+		{
+			desc:   "none",
+			filter: ListenerFilter{Port: 1},
+			want:   "0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := &ConfigWriter{Stdout: gotOut}
+			if err := cw.Prime(cd); err != nil {
+				t.Fatal(err)
+			}
+			if err := cw.PrintListenerCount(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintListenerDumpYAML(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listeners.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: jsonOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerDump(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	yamlOut := &bytes.Buffer{}
+	cw.Stdout = yamlOut
+	cw.OutputFormat = YAML
+	if err := cw.PrintListenerDump(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	fromYAML, err := yaml.YAMLToJSON(yamlOut.Bytes())
+	if err != nil {
+		t.Fatalf("YAML dump does not parse: %v", err)
+	}
+	var want, got interface{}
+	if err := json.Unmarshal(jsonOut.Bytes(), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(fromYAML, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("YAML dump does not round trip to the JSON dump:\n%s\nvs\n%s", jsonOut.String(), fromYAML)
+	}
+}
+
+func TestConfigWriter_retrieveSortedListenerSlice(t *testing.T) {
+	newListener := func(name, address string, port uint32) *listener.Listener {
+		l := newSocketListener(address, port)
+		l.Name = name
+		return l
+	}
+	listeners := []*listener.Listener{
+		newListener("0.0.0.0_9080", "0.0.0.0", 9080),
+		newListener("10.0.0.1_80", "10.0.0.1", 80),
+		newListener("virtualOutbound", "0.0.0.0", 15001),
+		newListener("0.0.0.0_80", "0.0.0.0", 80),
+		newListener("10.0.0.2_9080", "10.0.0.2", 9080),
+		newListener("0.0.0.0_15090", "0.0.0.0", 15090),
+	}
+	want := []string{"0.0.0.0_80", "10.0.0.1_80", "0.0.0.0_9080", "10.0.0.2_9080", "virtualOutbound", "0.0.0.0_15090"}
+
+	// The same listeners should come out in the same order however they are laid out in the config dump
+	for _, offset := range []int{0, 1, 3} {
+		dump := &adminapi.ListenersConfigDump{}
+		for i := range listeners {
+			l := listeners[(i+offset)%len(listeners)]
+			if i%2 == 0 {
+				dump.StaticListeners = append(dump.StaticListeners, &adminapi.ListenersConfigDump_StaticListener{
+					Listener: mustMarshalAny(t, l),
+				})
+				continue
+			}
+			dump.DynamicListeners = append(dump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+				Name:        l.Name,
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, l)},
+			})
+		}
+		got, err := newConfigWriter(t, &bytes.Buffer{}, dump).retrieveSortedListenerSlice()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotNames := make([]string, 0, len(got))
+		for _, l := range got {
+			gotNames = append(gotNames, l.Name)
+		}
+		if !reflect.DeepEqual(gotNames, want) {
+			t.Errorf("offset %d: expect %v got %v", offset, want, gotNames)
+		}
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryExpandInbound(t *testing.T) {
+	virtualInbound := newSocketListener("0.0.0.0", 15006)
+	virtualInbound.Name = "virtualInbound"
+	virtualInbound.FilterChains = []*listener.FilterChain{
+		{
+			FilterChainMatch: &listener.FilterChainMatch{
+				DestinationPort: &wrappers.UInt32Value{Value: 9080},
+				PrefixRanges:    []*v3.CidrRange{{AddressPrefix: "10.0.0.5", PrefixLen: &wrappers.UInt32Value{Value: 32}}},
+			},
+			Filters: []*listener.Filter{newTypedFilter(t, HTTPListener, &hcm.HttpConnectionManager{
+				RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{
+					RouteConfigName: "inbound|9080|http|reviews.default.svc.cluster.local",
+				}},
+			})},
+		},
+		{
+			Filters: []*listener.Filter{newTypedFilter(t, TCPListener, &tcp.TcpProxy{
+				ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: "InboundPassthroughClusterIpv4"},
+			})},
+		},
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, &adminapi.ListenersConfigDump{
+		DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+			Name:        virtualInbound.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, virtualInbound)},
+		}},
+	})
+	if err := cw.PrintListenerSummary(ListenerFilter{ExpandInbound: true}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/listenersummaryinbound.txt", t)
+}
+
+func TestRetrieveListenerSummaryEntries_ExpandInboundKeepsListener(t *testing.T) {
+	virtualInbound := newSocketListener("0.0.0.0", 15006)
+	virtualInbound.Name = "virtualInbound"
+	virtualInbound.Metadata = newIstioConfigMetadata("/apis/networking.istio.io/v1alpha3/namespaces/default/sidecar/default")
+	virtualInbound.ListenerFiltersTimeout = ptypes.DurationProto(time.Second)
+	virtualInbound.FilterChains = []*listener.FilterChain{
+		{FilterChainMatch: &listener.FilterChainMatch{DestinationPort: &wrappers.UInt32Value{Value: 9080}}},
+		{FilterChainMatch: &listener.FilterChainMatch{DestinationPort: &wrappers.UInt32Value{Value: 8080}}},
+	}
+	entries := retrieveListenerSummaryEntries(&listenerWithState{Listener: virtualInbound, state: listenerStateActive}, true)
+	if len(entries) != 2 {
+		t.Fatalf("expected an entry per filter chain, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if len(entry.GetFilterChains()) != 1 || entry.GetFilterChains()[0] != virtualInbound.FilterChains[i] {
+			t.Errorf("entry %d: expected only filter chain %d, got %v", i, i, entry.GetFilterChains())
+		}
+		if !proto.Equal(entry.GetMetadata(), virtualInbound.Metadata) {
+			t.Errorf("entry %d: expected the listener metadata, got %v", i, entry.GetMetadata())
+		}
+		if !proto.Equal(entry.GetListenerFiltersTimeout(), virtualInbound.ListenerFiltersTimeout) {
+			t.Errorf("entry %d: expected the listener filters timeout, got %v", i, entry.GetListenerFiltersTimeout())
+		}
+		if entry.state != listenerStateActive {
+			t.Errorf("entry %d: expected state %v, got %v", i, listenerStateActive, entry.state)
+		}
+	}
+	if len(virtualInbound.FilterChains) != 2 {
+		t.Errorf("expected the filter chains of the listener to be left as they are, got %v", virtualInbound.FilterChains)
+	}
+}
+
+func TestSortListeners(t *testing.T) {
+	newListener := func(name, address string, port uint32) *listenerWithState {
+		l := newSocketListener(address, port)
+		l.Name = name
+		return &listenerWithState{Listener: l, state: listenerStateActive}
+	}
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{
+			sortBy: "port",
+			want:   []string{"d", "c", "b", "a"},
+		},
+		{
+			sortBy: "address",
+			want:   []string{"b", "c", "a", "d"},
+		},
+		{
+			sortBy: "name",
+			want:   []string{"a", "b", "c", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			listeners := []*listenerWithState{
+				newListener("d", "/var/run/envoy.sock", 0),
+				newListener("a", "10.0.0.10", 9080),
+				newListener("c", "10.0.0.10", 80),
+				newListener("b", "10.0.0.2", 9080),
+			}
+			sortListeners(listeners, tt.sortBy)
+			gotNames := make([]string, 0, len(listeners))
+			for _, l := range listeners {
+				gotNames = append(gotNames, l.Name)
+			}
+			if !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("sort by %s: expect %v got %v", tt.sortBy, tt.want, gotNames)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryUDS(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listenersuds.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/listenersummaryuds.txt", t)
+
+	listeners, err := cw.GetListeners(ListenerFilter{Address: "/var/run/istio/gateway.sock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].Name != "gateway-uds" {
+		t.Errorf("expected only the pipe listener, got %v", listeners)
+	}
+	listeners, err = cw.GetListeners(ListenerFilter{Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].Name != "0.0.0.0_8080" {
+		t.Errorf("expected only the socket listener, got %v", listeners)
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryMultiAddress(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listenersmultiaddress.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/listenersummarymultiaddress.txt", t)
+
+	tests := []struct {
+		desc   string
+		filter ListenerFilter
+		want   []string
+	}{
+		{
+			desc:   "additional-address",
+			filter: ListenerFilter{Address: "::"},
+			want:   []string{"0.0.0.0_9080"},
+		},
+		{
+			desc:   "primary-address",
+			filter: ListenerFilter{Address: "0.0.0.0"},
+			want:   []string{"0.0.0.0_9080"},
+		},
+		{
+			desc:   "additional-address-regex",
+			filter: ListenerFilter{AddressRegex: "::"},
+			want:   []string{"0.0.0.0_9080"},
+		},
+		{
+			desc:   "single-address",
+			filter: ListenerFilter{Address: "10.0.0.1"},
+			want:   []string{"10.0.0.1_3306"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			listeners, err := cw.GetListeners(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotNames := make([]string, 0, len(listeners))
+			for _, l := range listeners {
+				gotNames = append(gotNames, l.Name)
+			}
+			if !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("expect %v got %v", tt.want, gotNames)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryInternal(t *testing.T) {
+	cd, err := ioutil.ReadFile("testdata/listenersinternal.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	if err := cw.Prime(cd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	util.CompareContent(gotOut.Bytes(), "testdata/listenersummaryinternal.txt", t)
+
+	tests := []struct {
+		desc   string
+		filter ListenerFilter
+		want   []string
+	}{
+		{
+			desc:   "internal",
+			filter: ListenerFilter{Internal: true},
+			want:   []string{"connect_originate", "inbound-vip"},
+		},
+		{
+			desc:   "type-internal",
+			filter: ListenerFilter{Type: "internal"},
+			want:   []string{"connect_originate", "inbound-vip"},
+		},
+		{
+			desc:   "skip-internal",
+			filter: ListenerFilter{SkipInternal: true},
+			want:   []string{"0.0.0.0_9080"},
+		},
+		{
+			desc:   "address-is-the-name",
+			filter: ListenerFilter{Address: "connect_originate"},
+			want:   []string{"connect_originate"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			listeners, err := cw.GetListeners(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotNames := make([]string, 0, len(listeners))
+			for _, l := range listeners {
+				gotNames = append(gotNames, l.Name)
+			}
+			if !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, gotNames)
+			}
+		})
+	}
+}
+
+func TestFormatThousands(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 4321: "4,321", 1234567: "1,234,567"} {
+		if got := formatThousands(n); got != want {
+			t.Errorf("%d: expect %q got %q", n, want, got)
+		}
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryLimit(t *testing.T) {
+	dump := &adminapi.ListenersConfigDump{}
+	for port := uint32(80); port < 85; port++ {
+		l := newSocketListener("0.0.0.0", port)
+		l.Name = fmt.Sprintf("0.0.0.0_%d", port)
+		dump.DynamicListeners = append(dump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, l)},
+		})
+	}
+	tests := []struct {
+		desc      string
+		filter    ListenerFilter
+		wantPorts []string
+		wantMore  string
+	}{
+		{
+			desc:      "limit",
+			filter:    ListenerFilter{Limit: 2},
+			wantPorts: []string{"80", "81"},
+			wantMore:  "... and 3 more (use --limit 0 for all)\n",
+		},
+		{
+			desc:      "offset",
+			filter:    ListenerFilter{Offset: 3},
+			wantPorts: []string{"83", "84"},
+		},
+		{
+			desc:      "offset-and-limit",
+			filter:    ListenerFilter{Offset: 1, Limit: 1},
+			wantPorts: []string{"81"},
+			wantMore:  "... and 3 more (use --limit 0 for all)\n",
+		},
+		{
+			desc:      "filtered-before-limit",
+			filter:    ListenerFilter{Port: 83, Limit: 1},
+			wantPorts: []string{"83"},
+		},
+		{
+			desc:   "offset-past-the-end",
+			filter: ListenerFilter{Offset: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newConfigWriter(t, gotOut, dump)
+			if err := cw.PrintListenerSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(gotOut.String(), "\n")
+			var gotPorts []string
+			for _, line := range lines[1:] {
+				if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "0.0.0.0" {
+					gotPorts = append(gotPorts, fields[1])
+				}
+			}
+			if strings.Join(gotPorts, ",") != strings.Join(tt.wantPorts, ",") {
+				t.Errorf("%s: expect ports %v got %v", tt.desc, tt.wantPorts, gotPorts)
+			}
+			gotMore := ""
+			if last := lines[len(lines)-2]; strings.HasPrefix(last, "...") {
+				gotMore = last + "\n"
+			}
+			if gotMore != tt.wantMore {
+				t.Errorf("%s: expect trailer %q in:\n%s", tt.desc, tt.wantMore, gotOut.String())
+			}
+		})
+	}
+
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, dump)
+	if err := cw.PrintListenerDump(ListenerFilter{Limit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(gotOut.String(), `"name": "0.0.0.0_8`); got != 2 {
+		t.Errorf("expected the dump of only one listener, found %d names in:\n%s", got, gotOut.String())
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryStates(t *testing.T) {
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, &adminapi.ListenersConfigDump{
+		DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+			Name: "0.0.0.0_9080",
+			WarmingState: &adminapi.ListenersConfigDump_DynamicListenerState{
+				Listener: mustMarshalAny(t, &listener.Listener{Name: "0.0.0.0_9080"}),
+			},
+		}},
+	})
+	if err := cw.PrintListenerSummary(ListenerFilter{}); err != nil {
+		t.Fatalf("warming listeners should be summarized: %v", err)
+	}
+	if !strings.Contains(gotOut.String(), listenerStateWarming) {
+		t.Errorf("expected a %s listener in:\n%s", listenerStateWarming, gotOut.String())
+	}
+
+	gotOut.Reset()
+	if err := cw.PrintListenerDump(ListenerFilter{}); err != nil {
+		t.Fatalf("warming listeners should be dumped: %v", err)
+	}
+	if !strings.Contains(gotOut.String(), "warmingState") {
+		t.Errorf("expected a warmingState section in:\n%s", gotOut.String())
+	}
+}
+
+func TestConfigWriter_PrintListenerSummaryLastUpdated(t *testing.T) {
+	lastUpdated, err := ptypes.TimestampProto(time.Now().Add(-3 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staleUpdated, err := ptypes.TimestampProto(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newListener := func(name string, port uint32) *any.Any {
+		l := newSocketListener("0.0.0.0", port)
+		l.Name = name
+		return mustMarshalAny(t, l)
+	}
+	gotOut := &bytes.Buffer{}
+	cw := newConfigWriter(t, gotOut, &adminapi.ListenersConfigDump{
+		DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{
+			{
+				Name: "0.0.0.0_9080",
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{
+					Listener:    newListener("0.0.0.0_9080", 9080),
+					LastUpdated: lastUpdated,
+				},
+			},
+			{
+				Name: "0.0.0.0_9081",
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{
+					Listener:    newListener("0.0.0.0_9081", 9081),
+					LastUpdated: staleUpdated,
+				},
+			},
+		},
+		StaticListeners: []*adminapi.ListenersConfigDump_StaticListener{{
+			Listener:    newListener("0.0.0.0_15090", 15090),
+			LastUpdated: lastUpdated,
+		}},
+	})
+	if err := cw.PrintListenerSummary(ListenerFilter{LastUpdated: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"LAST UPDATED", "3m ago", "60m ago", "STATIC     -"} {
+		if !strings.Contains(gotOut.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, gotOut.String())
+		}
+	}
+
+	listeners, err := cw.GetListeners(ListenerFilter{UpdatedSince: 10 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].Name != "0.0.0.0_9080" {
+		t.Errorf("expected only the recently updated listener, got %v", listeners)
+	}
+}
+
+// newConfigWriter returns a ConfigWriter of a config dump with the sections, such as the listener, cluster and route
+// dumps, see newConfigDumpWriter to build them from the resources
+func newConfigWriter(t testing.TB, out io.Writer, sections ...proto.Message) *ConfigWriter {
+	t.Helper()
+	configs := make([]*any.Any, 0, len(sections))
+	for _, section := range sections {
+		configs = append(configs, mustMarshalAny(t, section))
+	}
+	return &ConfigWriter{
+		Stdout:     out,
+		configDump: &configdump.Wrapper{ConfigDump: &adminapi.ConfigDump{Configs: configs}},
+	}
+}
+
+func mustMarshalAny(t testing.TB, message proto.Message) *any.Any {
+	t.Helper()
+	a, err := ptypes.MarshalAny(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestListenerFilter_Validate(t *testing.T) {
+	tests := []struct {
+		desc     string
+		inFilter *ListenerFilter
+		wantErr  bool
+	}{
+		{
+			desc:     "empty",
+			inFilter: &ListenerFilter{},
+		},
+		{
+			desc:     "name-regex",
+			inFilter: &ListenerFilter{Name: "~virtual.*"},
+		},
+		{
+			desc:     "name-regex-invalid",
+			inFilter: &ListenerFilter{Name: "~virtual(.*"},
+			wantErr:  true,
+		},
+		{
+			desc:     "port-range",
+			inFilter: &ListenerFilter{PortRange: &PortRange{Min: 15000, Max: 15100}},
+		},
+		{
+			desc:     "origin",
+			inFilter: &ListenerFilter{Origin: "dynamic"},
+		},
+		{
+			desc:     "columns",
+			inFilter: &ListenerFilter{Columns: []string{"name", "LAST_UPDATED"}},
+		},
+		{
+			desc:     "unknown-column",
+			inFilter: &ListenerFilter{Columns: []string{"NAME", "CLUSTER"}},
+			wantErr:  true,
+		},
+		{
+			desc:     "unknown-origin",
+			inFilter: &ListenerFilter{Origin: "eds"},
+			wantErr:  true,
+		},
+		{
+			desc:     "negative-updated-since",
+			inFilter: &ListenerFilter{UpdatedSince: -time.Minute},
+			wantErr:  true,
+		},
+		{
+			desc:     "negative-limit",
+			inFilter: &ListenerFilter{Limit: -1},
+			wantErr:  true,
+		},
+		{
+			desc:     "sort-by-address",
+			inFilter: &ListenerFilter{SortBy: "address"},
+		},
+		{
+			desc:     "sort-by-unknown",
+			inFilter: &ListenerFilter{SortBy: "type"},
+			wantErr:  true,
+		},
+		{
+			desc:     "port-range-inverted",
+			inFilter: &ListenerFilter{PortRange: &PortRange{Min: 15100, Max: 15000}},
+			wantErr:  true,
+		},
+		{
+			desc:     "port-and-ports",
+			inFilter: &ListenerFilter{Port: 80, Ports: []uint32{80, 443}},
+			wantErr:  true,
+		},
+		{
+			desc:     "ports-and-port-range",
+			inFilter: &ListenerFilter{Ports: []uint32{80, 443}, PortRange: &PortRange{Min: 15000, Max: 15100}},
+			wantErr:  true,
+		},
+		{
+			desc:     "port-and-port-range",
+			inFilter: &ListenerFilter{Port: 15001, PortRange: &PortRange{Min: 15000, Max: 15100}},
+			wantErr:  true,
+		},
+		{
+			desc:     "address-cidr",
+			inFilter: &ListenerFilter{Address: "10.96.0.0/16"},
+		},
+		{
+			desc:     "address-pipe-path",
+			inFilter: &ListenerFilter{Address: "/var/run/istio/gateway.sock"},
+		},
+		{
+			desc:     "address-cidr-bad-prefix",
+			inFilter: &ListenerFilter{Address: "10.96.0.0/33"},
+			wantErr:  true,
+		},
+		{
+			desc:     "address-regex",
+			inFilter: &ListenerFilter{AddressRegex: `10\.0\..*`},
+		},
+		{
+			desc:     "address-regex-invalid",
+			inFilter: &ListenerFilter{AddressRegex: `10\.0\.(`},
+			wantErr:  true,
+		},
+		{
+			desc:     "cidr",
+			inFilter: &ListenerFilter{CIDR: "2001:db8::/32"},
+		},
+		{
+			desc:     "cidr-invalid",
+			inFilter: &ListenerFilter{CIDR: "10.0.0.0"},
+			wantErr:  true,
+		},
+		{
+			desc:     "address-cidr-bad-ip",
+			inFilter: &ListenerFilter{Address: "10.96.0/16"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := tt.inFilter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("%s: expect error %v got %v", tt.desc, tt.wantErr, err)
 			}
 		})
 	}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+// listenersConfigDumpTypeURL is the type of the listeners section of the config dump
+const listenersConfigDumpTypeURL = "type.googleapis.com/envoy.admin.v3.ListenersConfigDump"
+
+// parseListenerAdditionalAddresses returns the additional_addresses of the multi-address listeners of the listeners
+// section of the config dump by listener name. A listener in more than one state gets the addresses of the first of
+// active, warming, draining and static it is found in.
+func parseListenerAdditionalAddresses(section json.RawMessage) (map[string][]*core.Address, error) {
+	addresses := map[string][]*core.Address{}
+	if section == nil {
+		return addresses, nil
+	}
+	type dumpedListener struct {
+		Listener *struct {
+			Name                string `json:"name"`
+			AdditionalAddresses []struct {
+				Address json.RawMessage `json:"address"`
+			} `json:"additional_addresses"`
+		} `json:"listener"`
+	}
+	listenersDump := struct {
+		DynamicListeners []struct {
+			ActiveState   dumpedListener `json:"active_state"`
+			WarmingState  dumpedListener `json:"warming_state"`
+			DrainingState dumpedListener `json:"draining_state"`
+		} `json:"dynamic_listeners"`
+		StaticListeners []dumpedListener `json:"static_listeners"`
+	}{}
+	if err := json.Unmarshal(section, &listenersDump); err != nil {
+		return nil, err
+	}
+	listeners := make([]dumpedListener, 0, len(listenersDump.DynamicListeners)+len(listenersDump.StaticListeners))
+	for _, l := range listenersDump.DynamicListeners {
+		listeners = append(listeners, l.ActiveState, l.WarmingState, l.DrainingState)
+	}
+	listeners = append(listeners, listenersDump.StaticListeners...)
+	for _, l := range listeners {
+		if l.Listener == nil || len(l.Listener.AdditionalAddresses) == 0 || addresses[l.Listener.Name] != nil {
+			continue
+		}
+		for _, additionalAddress := range l.Listener.AdditionalAddresses {
+			address := &core.Address{}
+			if err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(additionalAddress.Address), address); err != nil {
+				return nil, err
+			}
+			addresses[l.Listener.Name] = append(addresses[l.Listener.Name], address)
+		}
+	}
+	return addresses, nil
+}
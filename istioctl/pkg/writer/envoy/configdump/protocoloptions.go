@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// clustersConfigDumpTypeURL is the type of the clusters section of the config dump
+const clustersConfigDumpTypeURL = "type.googleapis.com/envoy.admin.v3.ClustersConfigDump"
+
+// httpProtocolOptions is the HttpProtocolOptions typed extension of a cluster, selecting the upstream HTTP protocol
+// explicitly, as the protocol of the downstream request or automatically by ALPN.
+type httpProtocolOptions struct {
+	CommonHTTPProtocolOptions struct {
+		IdleTimeout string `json:"idle_timeout"`
+	} `json:"common_http_protocol_options"`
+	ExplicitHTTPConfig *struct {
+		HTTPProtocolOptions  json.RawMessage `json:"http_protocol_options"`
+		HTTP2ProtocolOptions json.RawMessage `json:"http2_protocol_options"`
+		HTTP3ProtocolOptions json.RawMessage `json:"http3_protocol_options"`
+	} `json:"explicit_http_config"`
+	UseDownstreamProtocolConfig json.RawMessage `json:"use_downstream_protocol_config"`
+	AutoConfig                  json.RawMessage `json:"auto_config"`
+}
+
+// protocol returns the upstream HTTP protocol the options select, or "" when they select none
+func (o *httpProtocolOptions) protocol() string {
+	if o == nil {
+		return ""
+	}
+	switch {
+	case o.AutoConfig != nil:
+		return upstreamProtocolAuto
+	case o.UseDownstreamProtocolConfig != nil:
+		return upstreamProtocolDownstream
+	case o.ExplicitHTTPConfig == nil:
+		return ""
+	case o.ExplicitHTTPConfig.HTTP2ProtocolOptions != nil:
+		return upstreamProtocolExplicitHTTP2
+	case o.ExplicitHTTPConfig.HTTP3ProtocolOptions != nil:
+		return upstreamProtocolExplicitHTTP3
+	case o.ExplicitHTTPConfig.HTTPProtocolOptions != nil:
+		return upstreamProtocolExplicitHTTP1
+	}
+	return ""
+}
+
+// idleTimeout returns the idle timeout of the upstream connections the options set, and false when they set none
+func (o *httpProtocolOptions) idleTimeout() (time.Duration, bool) {
+	if o == nil || o.CommonHTTPProtocolOptions.IdleTimeout == "" {
+		return 0, false
+	}
+	timeout, err := time.ParseDuration(o.CommonHTTPProtocolOptions.IdleTimeout)
+	return timeout, err == nil
+}
+
+// parseHTTPProtocolOptions returns the HttpProtocolOptions typed extensions of the clusters of the clusters section of
+// the config dump by cluster name. A cluster both active and warming gets the options of the active one.
+func parseHTTPProtocolOptions(section json.RawMessage) (map[string]*httpProtocolOptions, error) {
+	options := map[string]*httpProtocolOptions{}
+	if section == nil {
+		return options, nil
+	}
+	type dumpedCluster struct {
+		Cluster struct {
+			Name                          string                     `json:"name"`
+			TypedExtensionProtocolOptions map[string]json.RawMessage `json:"typed_extension_protocol_options"`
+		} `json:"cluster"`
+	}
+	clustersDump := struct {
+		StaticClusters         []dumpedCluster `json:"static_clusters"`
+		DynamicActiveClusters  []dumpedCluster `json:"dynamic_active_clusters"`
+		DynamicWarmingClusters []dumpedCluster `json:"dynamic_warming_clusters"`
+	}{}
+	if err := json.Unmarshal(section, &clustersDump); err != nil {
+		return nil, err
+	}
+	for _, clusters := range [][]dumpedCluster{clustersDump.DynamicActiveClusters, clustersDump.DynamicWarmingClusters, clustersDump.StaticClusters} {
+		for _, c := range clusters {
+			raw, ok := c.Cluster.TypedExtensionProtocolOptions[httpProtocolOptionsExtension]
+			if !ok || options[c.Cluster.Name] != nil {
+				continue
+			}
+			clusterOptions := &httpProtocolOptions{}
+			if err := json.Unmarshal(raw, clusterOptions); err != nil {
+				return nil, err
+			}
+			options[c.Cluster.Name] = clusterOptions
+		}
+	}
+	return options, nil
+}
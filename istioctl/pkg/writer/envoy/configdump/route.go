@@ -16,13 +16,17 @@ package configdump
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
-	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/proto"
 
 	protio "istio.io/istio/istioctl/pkg/util/proto"
 	v3 "istio.io/istio/pilot/pkg/proxy/envoy/v3"
@@ -31,27 +35,482 @@ import (
 // RouteFilter is used to pass filter information into route based config writer print functions
 type RouteFilter struct {
 	Name string
+	// NameContains selects route configs with a name containing the value, such as foo.bar.svc
+	NameContains string
+	// VirtualHostDomain selects route configs with a virtual host serving the domain, see matchesVirtualHostDomain
+	VirtualHostDomain string
+	// Retries selects route configs with routes that retry failed requests
+	Retries bool
+	// Method selects route configs with routes matching the HTTP method, by their :method header matcher
+	Method string
+	// Path selects route configs with routes whose prefix, exact path or regex matches the path. Catch-all
+	// routes matching any path are left out unless IncludeCatchAll is set.
+	Path            string
+	IncludeCatchAll bool
+	// ManipulatesHeaders selects route configs with routes that add or remove request or response headers
+	ManipulatesHeaders bool
 }
 
+// Kinds of route path matches
+const (
+	routeMatchPrefix = "prefix"
+	routeMatchPath   = "path"
+	routeMatchRegex  = "regex"
+)
+
+// Envoy defaults of the timeout and retries of a route
+const (
+	defaultRouteTimeout = 15 * time.Second
+	defaultNumRetries   = 1
+)
+
 // Verify returns true if the passed route matches the filter fields
 func (r *RouteFilter) Verify(route *route.RouteConfiguration) bool {
 	if r.Name != "" && r.Name != route.Name {
 		return false
 	}
+	if r.NameContains != "" && !strings.Contains(route.Name, r.NameContains) {
+		return false
+	}
+	if r.VirtualHostDomain != "" && len(r.retrieveMatchingVirtualHosts(route)) == 0 {
+		return false
+	}
+	if (r.Retries || r.Method != "" || r.Path != "" || r.ManipulatesHeaders) && !r.hasMatchingRoutes(route) {
+		return false
+	}
+	return true
+}
+
+func (r *RouteFilter) hasMatchingRoutes(routeConfig *route.RouteConfiguration) bool {
+	for _, virtualHost := range r.retrieveMatchingVirtualHosts(routeConfig) {
+		if len(r.retrieveMatchingRoutes(virtualHost)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// retrieveMatchingRoutes returns the routes of a virtual host matching the retries, method, path and header
+// manipulation of the filter
+func (r *RouteFilter) retrieveMatchingRoutes(virtualHost *route.VirtualHost) []*route.Route {
+	routes := make([]*route.Route, 0, len(virtualHost.GetRoutes()))
+	for _, rt := range virtualHost.GetRoutes() {
+		if r.Retries && retrieveRouteNumRetries(virtualHost, rt) == 0 {
+			continue
+		}
+		if r.ManipulatesHeaders && len(retrieveRouteHeaderOperations(rt)) == 0 {
+			continue
+		}
+		if r.Method != "" && !matchesRouteMethod(rt.GetMatch(), r.Method) {
+			continue
+		}
+		if r.Path != "" {
+			if matched, _ := matchesRoutePath(rt.GetMatch(), r.Path); !matched ||
+				(!r.IncludeCatchAll && isCatchAllRouteMatch(rt.GetMatch())) {
+				continue
+			}
+		}
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
+// matchesRouteMethod returns true if the :method header matchers of a route match the method, routes without
+// one match every method
+func matchesRouteMethod(match *route.RouteMatch, method string) bool {
+	for _, header := range match.GetHeaders() {
+		if header.GetName() != ":method" {
+			continue
+		}
+		var matched bool
+		switch {
+		case header.GetExactMatch() != "":
+			matched = header.GetExactMatch() == method
+		case header.GetSafeRegexMatch() != nil:
+			methodRegex, err := compileAnchoredPattern(header.GetSafeRegexMatch().GetRegex())
+			matched = err == nil && methodRegex.MatchString(method)
+		case header.GetPrefixMatch() != "":
+			matched = strings.HasPrefix(method, header.GetPrefixMatch())
+		case header.GetSuffixMatch() != "":
+			matched = strings.HasSuffix(method, header.GetSuffixMatch())
+		default:
+			matched = true
+		}
+		if matched == header.GetInvertMatch() {
+			return false
+		}
+	}
 	return true
 }
 
-// PrintRouteSummary prints a summary of the relevant routes in the config dump to the ConfigWriter stdout
+// matchesRoutePath returns true if the path specifier of a route matches the path, along with the kind of the
+// path specifier: a prefix, an exact path, or a regex that must match the whole path
+func matchesRoutePath(match *route.RouteMatch, path string) (bool, string) {
+	caseSensitive := match.GetCaseSensitive() == nil || match.GetCaseSensitive().GetValue()
+	normalize := func(s string) string {
+		if caseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+	switch match.GetPathSpecifier().(type) {
+	case *route.RouteMatch_Prefix:
+		return strings.HasPrefix(normalize(path), normalize(match.GetPrefix())), routeMatchPrefix
+	case *route.RouteMatch_Path:
+		return normalize(path) == normalize(match.GetPath()), routeMatchPath
+	case *route.RouteMatch_SafeRegex:
+		pathRegex, err := compileAnchoredPattern(match.GetSafeRegex().GetRegex())
+		return err == nil && pathRegex.MatchString(path), routeMatchRegex
+	}
+	return false, ""
+}
+
+// isCatchAllRouteMatch returns true if a route matches every path
+func isCatchAllRouteMatch(match *route.RouteMatch) bool {
+	_, isPrefix := match.GetPathSpecifier().(*route.RouteMatch_Prefix)
+	return isPrefix && (match.GetPrefix() == "" || match.GetPrefix() == "/")
+}
+
+// formatRouteName renders the name of a route, which Istio sets to the name of the VirtualService HTTP route
+func formatRouteName(r *route.Route) string {
+	if r.Name == "" {
+		return "-"
+	}
+	return r.Name
+}
+
+// describeRouteMatch renders the path specifier of a route as its kind and value, like prefix /api
+func describeRouteMatch(match *route.RouteMatch) string {
+	switch match.GetPathSpecifier().(type) {
+	case *route.RouteMatch_Prefix:
+		return routeMatchPrefix + " " + match.GetPrefix()
+	case *route.RouteMatch_Path:
+		return routeMatchPath + " " + match.GetPath()
+	case *route.RouteMatch_SafeRegex:
+		return routeMatchRegex + " " + match.GetSafeRegex().GetRegex()
+	}
+	return "-"
+}
+
+// describeRouteMethod renders the :method header matchers of a route, or * when it matches every method
+func describeRouteMethod(match *route.RouteMatch) string {
+	methods := make([]string, 0)
+	for _, header := range match.GetHeaders() {
+		if header.GetName() != ":method" {
+			continue
+		}
+		method := header.GetExactMatch()
+		switch {
+		case header.GetSafeRegexMatch() != nil:
+			method = "~" + header.GetSafeRegexMatch().GetRegex()
+		case header.GetPrefixMatch() != "":
+			method = header.GetPrefixMatch() + "*"
+		case header.GetSuffixMatch() != "":
+			method = "*" + header.GetSuffixMatch()
+		}
+		if header.GetInvertMatch() {
+			method = "!" + method
+		}
+		methods = append(methods, method)
+	}
+	if len(methods) == 0 {
+		return "*"
+	}
+	return strings.Join(methods, ",")
+}
+
+// retrieveMatchingVirtualHosts returns the virtual hosts of a route config serving the domain of the filter,
+// or all of them when the filter has no domain
+func (r *RouteFilter) retrieveMatchingVirtualHosts(routeConfig *route.RouteConfiguration) []*route.VirtualHost {
+	if r.VirtualHostDomain == "" {
+		return routeConfig.GetVirtualHosts()
+	}
+	virtualHosts := make([]*route.VirtualHost, 0)
+	for _, virtualHost := range routeConfig.GetVirtualHosts() {
+		for _, domain := range virtualHost.GetDomains() {
+			if matchesVirtualHostDomain(domain, r.VirtualHostDomain) {
+				virtualHosts = append(virtualHosts, virtualHost)
+				break
+			}
+		}
+	}
+	return virtualHosts
+}
+
+// matchesVirtualHostDomain returns true if a virtual host domain serves the requested domain, ignoring case.
+// Wildcard virtual host domains match like Envoy matches the Host header: * matches everything, and a leading
+// or trailing * matches at least one character. A requested domain with a leading *, like *.example.com, matches
+// the virtual host domains ending with the rest of it.
+func matchesVirtualHostDomain(domain, requested string) bool {
+	domain, requested = strings.ToLower(domain), strings.ToLower(requested)
+	switch {
+	case domain == requested || domain == "*":
+		return true
+	case strings.HasPrefix(domain, "*"):
+		return len(requested) >= len(domain) && strings.HasSuffix(requested, domain[1:])
+	case strings.HasSuffix(domain, "*"):
+		return len(requested) >= len(domain) && strings.HasPrefix(requested, domain[:len(domain)-1])
+	case strings.HasPrefix(requested, "*"):
+		return len(domain) >= len(requested) && strings.HasSuffix(domain, requested[1:])
+	}
+	return false
+}
+
+// RouteSummary is a route config summarized as a row of the route summary, for tooling reading the
+// summary as JSON or YAML
+type RouteSummary struct {
+	Name         string `json:"name"`
+	VirtualHosts int    `json:"virtualHosts"`
+}
+
+// PrintRouteSummary prints a summary of the relevant routes in the config dump to the ConfigWriter stdout,
+// as a table or, when the output format of the ConfigWriter is JSON or YAML, as a list of RouteSummary
 func (c *ConfigWriter) PrintRouteSummary(filter RouteFilter) error {
-	w, routes, err := c.setupRouteConfigWriter()
+	w, routes, err := c.setupRouteConfigWriter(filter)
 	if err != nil {
 		return err
 	}
+	if c.OutputFormat != Table {
+		summaries := make([]RouteSummary, 0, len(routes))
+		for _, route := range routes {
+			summaries = append(summaries, RouteSummary{Name: route.Name, VirtualHosts: len(route.GetVirtualHosts())})
+		}
+		out, err := json.MarshalIndent(summaries, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal route summary: %v", err)
+		}
+		return c.printJSON(out)
+	}
 	fmt.Fprintln(c.Stdout, "NOTE: This output only contains routes loaded via RDS.")
 	fmt.Fprintln(w, "NAME\tVIRTUAL HOSTS")
 	for _, route := range routes {
-		if filter.Verify(route) {
-			fmt.Fprintf(w, "%v\t%v\n", route.Name, len(route.GetVirtualHosts()))
+		fmt.Fprintf(w, "%v\t%v\n", route.Name, len(route.GetVirtualHosts()))
+	}
+	return w.Flush()
+}
+
+// PrintRouteVirtualHosts prints the virtual hosts of the relevant routes in the config dump to the ConfigWriter
+// stdout, with the domains they serve and their number of routes. Only the virtual hosts serving the domain of
+// the filter are printed.
+func (c *ConfigWriter) PrintRouteVirtualHosts(filter RouteFilter) error {
+	w, routes, err := c.setupRouteConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "NAME\tVIRTUAL HOST\tDOMAINS\tROUTES")
+	for _, route := range routes {
+		for _, virtualHost := range filter.retrieveMatchingVirtualHosts(route) {
+			domains := "-"
+			if len(virtualHost.GetDomains()) > 0 {
+				domains = strings.Join(virtualHost.GetDomains(), ",")
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", route.Name, virtualHost.Name, domains, len(virtualHost.GetRoutes()))
+		}
+	}
+	return w.Flush()
+}
+
+// routeClusterWeight is a cluster a route sends traffic to, with its share of the traffic of the route
+type routeClusterWeight struct {
+	cluster string
+	weight  string
+	percent string
+}
+
+// retrieveRouteClusterWeights returns the clusters of the route action of a route with their weights and
+// percentages. A single cluster receives 100% of the traffic, and clusters picked from a request header get
+// no percentage. Routes that redirect or respond directly have no clusters.
+func retrieveRouteClusterWeights(r *route.Route) []routeClusterWeight {
+	action := r.GetRoute()
+	switch {
+	case action.GetCluster() != "":
+		return []routeClusterWeight{{cluster: action.GetCluster(), weight: "-", percent: "100%"}}
+	case action.GetClusterHeader() != "":
+		return []routeClusterWeight{{cluster: "header:" + action.GetClusterHeader(), weight: "-", percent: "-"}}
+	}
+	var total uint32
+	for _, weightedCluster := range action.GetWeightedClusters().GetClusters() {
+		total += weightedCluster.GetWeight().GetValue()
+	}
+	weights := make([]routeClusterWeight, 0, len(action.GetWeightedClusters().GetClusters()))
+	for _, weightedCluster := range action.GetWeightedClusters().GetClusters() {
+		weight := weightedCluster.GetWeight().GetValue()
+		percent := "-"
+		if total > 0 {
+			percent = fmt.Sprintf("%.4g%%", float64(weight)*100/float64(total))
+		}
+		weights = append(weights, routeClusterWeight{cluster: weightedCluster.GetName(), weight: strconv.Itoa(int(weight)), percent: percent})
+	}
+	return weights
+}
+
+// PrintRouteWeightedClusters prints the clusters each route of the relevant routes in the config dump sends
+// traffic to, with their weights and the percentages of the traffic they receive, to the ConfigWriter stdout
+func (c *ConfigWriter) PrintRouteWeightedClusters(filter RouteFilter) error {
+	w, routes, err := c.setupRouteConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "NAME\tVIRTUAL HOST\tROUTE\tCLUSTER\tWEIGHT\tPERCENT")
+	for _, routeConfig := range routes {
+		for _, virtualHost := range filter.retrieveMatchingVirtualHosts(routeConfig) {
+			for _, r := range filter.retrieveMatchingRoutes(virtualHost) {
+				for _, weight := range retrieveRouteClusterWeights(r) {
+					fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", routeConfig.Name, virtualHost.Name, formatRouteName(r),
+						weight.cluster, weight.weight, weight.percent)
+				}
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// retrieveRouteRetryPolicy returns the retry policy of a route, which replaces the retry policy of its virtual host
+func retrieveRouteRetryPolicy(virtualHost *route.VirtualHost, r *route.Route) *route.RetryPolicy {
+	if retryPolicy := r.GetRoute().GetRetryPolicy(); retryPolicy != nil {
+		return retryPolicy
+	}
+	return virtualHost.GetRetryPolicy()
+}
+
+// retrieveRouteNumRetries returns the number of times a route retries failed requests, which is 0 without
+// a retry policy
+func retrieveRouteNumRetries(virtualHost *route.VirtualHost, r *route.Route) uint32 {
+	retryPolicy := retrieveRouteRetryPolicy(virtualHost, r)
+	switch {
+	case retryPolicy == nil:
+		return 0
+	case retryPolicy.GetNumRetries() == nil:
+		return defaultNumRetries
+	}
+	return retryPolicy.GetNumRetries().GetValue()
+}
+
+// formatRouteTimeout renders a route timeout, where 0 disables the timeout
+func formatRouteTimeout(timeout time.Duration) string {
+	if timeout == 0 {
+		return "disabled"
+	}
+	return timeout.String()
+}
+
+// PrintRouteTimeouts prints the timeout and retry policy of each route of the relevant routes in the config dump
+// to the ConfigWriter stdout. Settings a route leaves unset are shown with the values Envoy applies: routes time
+// out after 15s, inherit the retry policy of their virtual host, and tries time out with the route.
+func (c *ConfigWriter) PrintRouteTimeouts(filter RouteFilter) error {
+	w, routes, err := c.setupRouteConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "NAME\tVIRTUAL HOST\tROUTE\tTIMEOUT\tRETRIES\tRETRY ON\tPER TRY TIMEOUT")
+	for _, routeConfig := range routes {
+		for _, virtualHost := range filter.retrieveMatchingVirtualHosts(routeConfig) {
+			for _, r := range filter.retrieveMatchingRoutes(virtualHost) {
+				// Redirects and direct responses are not sent upstream
+				if r.GetRoute() == nil {
+					continue
+				}
+				numRetries := retrieveRouteNumRetries(virtualHost, r)
+				timeout := durationValue(r.GetRoute().GetTimeout(), defaultRouteTimeout)
+				retryOn, perTryTimeout := "-", "-"
+				if retryPolicy := retrieveRouteRetryPolicy(virtualHost, r); retryPolicy != nil {
+					if retryPolicy.GetRetryOn() != "" {
+						retryOn = retryPolicy.GetRetryOn()
+					}
+					perTryTimeout = formatRouteTimeout(durationValue(retryPolicy.GetPerTryTimeout(), timeout))
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%d\t%v\t%v\n", routeConfig.Name, virtualHost.Name, formatRouteName(r),
+					formatRouteTimeout(timeout), numRetries, retryOn, perTryTimeout)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// PrintRouteMatches prints the path match and methods of each route of the relevant routes in the config dump to
+// the ConfigWriter stdout, so the route serving a request can be found with the method and path of the filter.
+// Routes are printed in the order Envoy tries them, and the first route of a virtual host matching a request
+// serves it.
+func (c *ConfigWriter) PrintRouteMatches(filter RouteFilter) error {
+	w, routes, err := c.setupRouteConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "NAME\tVIRTUAL HOST\tROUTE\tMATCH\tMETHOD")
+	for _, routeConfig := range routes {
+		for _, virtualHost := range filter.retrieveMatchingVirtualHosts(routeConfig) {
+			for _, r := range filter.retrieveMatchingRoutes(virtualHost) {
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", routeConfig.Name, virtualHost.Name, formatRouteName(r),
+					describeRouteMatch(r.GetMatch()), describeRouteMethod(r.GetMatch()))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// routeHeaderOperation is a header a route adds to or removes from the requests or responses it serves
+type routeHeaderOperation struct {
+	// headers are request or response
+	headers string
+	// action is append or set for added headers, or remove
+	action string
+	name   string
+	value  string
+}
+
+// retrieveRouteHeaderOperations returns the request header operations of a route followed by its response
+// header operations. Added headers are appended to the values already in the request or response unless
+// append is false.
+func retrieveRouteHeaderOperations(r *route.Route) []routeHeaderOperation {
+	operations := make([]routeHeaderOperation, 0)
+	for _, headers := range []struct {
+		name     string
+		toAdd    []*core.HeaderValueOption
+		toRemove []string
+	}{
+		{"request", r.GetRequestHeadersToAdd(), r.GetRequestHeadersToRemove()},
+		{"response", r.GetResponseHeadersToAdd(), r.GetResponseHeadersToRemove()},
+	} {
+		for _, header := range headers.toAdd {
+			action := "append"
+			if header.GetAppend() != nil && !header.GetAppend().GetValue() {
+				action = "set"
+			}
+			operations = append(operations, routeHeaderOperation{
+				headers: headers.name,
+				action:  action,
+				name:    header.GetHeader().GetKey(),
+				value:   header.GetHeader().GetValue(),
+			})
+		}
+		for _, name := range headers.toRemove {
+			operations = append(operations, routeHeaderOperation{headers: headers.name, action: "remove", name: name, value: "-"})
+		}
+	}
+	return operations
+}
+
+// PrintRouteHeaders prints the request and response headers each route of the relevant routes in the config dump
+// adds or removes to the ConfigWriter stdout, grouped by virtual host. Routes leaving headers untouched are shown
+// with a single row of "-", and are left out when the filter selects routes manipulating headers.
+func (c *ConfigWriter) PrintRouteHeaders(filter RouteFilter) error {
+	w, routes, err := c.setupRouteConfigWriter(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "NAME\tVIRTUAL HOST\tROUTE\tHEADERS\tACTION\tHEADER\tVALUE")
+	for _, routeConfig := range routes {
+		for _, virtualHost := range filter.retrieveMatchingVirtualHosts(routeConfig) {
+			for _, r := range filter.retrieveMatchingRoutes(virtualHost) {
+				operations := retrieveRouteHeaderOperations(r)
+				if len(operations) == 0 {
+					operations = append(operations, routeHeaderOperation{headers: "-", action: "-", name: "-", value: "-"})
+				}
+				for _, operation := range operations {
+					fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", routeConfig.Name, virtualHost.Name, formatRouteName(r),
+						operation.headers, operation.action, operation.name, operation.value)
+				}
+			}
 		}
 	}
 	return w.Flush()
@@ -59,26 +518,49 @@ func (c *ConfigWriter) PrintRouteSummary(filter RouteFilter) error {
 
 // PrintRouteDump prints the relevant routes in the config dump to the ConfigWriter stdout
 func (c *ConfigWriter) PrintRouteDump(filter RouteFilter) error {
-	_, routes, err := c.setupRouteConfigWriter()
+	_, routes, err := c.setupRouteConfigWriter(filter)
 	if err != nil {
 		return err
 	}
 	filteredRoutes := protio.MessageSlice{}
+	for _, route := range routes {
+		filteredRoutes = append(filteredRoutes, route)
+	}
+	return c.printMessages(filteredRoutes)
+}
+
+// GetRoutes returns the route configs in the config dump matching the filter, sorted by name, with the ones named
+// by port in numeric order first
+func (c *ConfigWriter) GetRoutes(filter RouteFilter) ([]*route.RouteConfiguration, error) {
+	routes, err := c.retrieveSortedRouteSlice()
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]*route.RouteConfiguration, 0, len(routes))
 	for _, route := range routes {
 		if filter.Verify(route) {
-			filteredRoutes = append(filteredRoutes, route)
+			filtered = append(filtered, route)
 		}
 	}
-	out, err := json.MarshalIndent(filteredRoutes, "", "    ")
+	return filtered, nil
+}
+
+func (c *ConfigWriter) retrieveRoutesByName(filter RouteFilter) (map[string]proto.Message, error) {
+	routes, err := c.GetRoutes(filter)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	fmt.Fprintln(c.Stdout, string(out))
-	return nil
+	byName := make(map[string]proto.Message, len(routes))
+	for _, r := range routes {
+		if _, ok := byName[r.Name]; !ok {
+			byName[r.Name] = r
+		}
+	}
+	return byName, nil
 }
 
-func (c *ConfigWriter) setupRouteConfigWriter() (*tabwriter.Writer, []*route.RouteConfiguration, error) {
-	routes, err := c.retrieveSortedRouteSlice()
+func (c *ConfigWriter) setupRouteConfigWriter(filter RouteFilter) (*tabwriter.Writer, []*route.RouteConfiguration, error) {
+	routes, err := c.GetRoutes(filter)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -86,6 +568,9 @@ func (c *ConfigWriter) setupRouteConfigWriter() (*tabwriter.Writer, []*route.Rou
 	return w, routes, nil
 }
 
+// errNoRoutes is returned when the route section of the config dump has no route configs
+var errNoRoutes = errors.New("no routes found")
+
 func (c *ConfigWriter) retrieveSortedRouteSlice() ([]*route.RouteConfiguration, error) {
 	if c.configDump == nil {
 		return nil, fmt.Errorf("config writer has not been primed")
@@ -98,9 +583,7 @@ func (c *ConfigWriter) retrieveSortedRouteSlice() ([]*route.RouteConfiguration,
 	for _, r := range routeDump.DynamicRouteConfigs {
 		if r.RouteConfig != nil {
 			routeTyped := &route.RouteConfiguration{}
-			// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
-			r.RouteConfig.TypeUrl = v3.RouteType
-			err = ptypes.UnmarshalAny(r.RouteConfig, routeTyped)
+			err = unmarshalAnyAs(v3.RouteType, r.RouteConfig, routeTyped)
 			if err != nil {
 				return nil, err
 			}
@@ -110,9 +593,7 @@ func (c *ConfigWriter) retrieveSortedRouteSlice() ([]*route.RouteConfiguration,
 	for _, r := range routeDump.StaticRouteConfigs {
 		if r.RouteConfig != nil {
 			routeTyped := &route.RouteConfiguration{}
-			// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
-			r.RouteConfig.TypeUrl = v3.RouteType
-			err = ptypes.UnmarshalAny(r.RouteConfig, routeTyped)
+			err = unmarshalAnyAs(v3.RouteType, r.RouteConfig, routeTyped)
 			if err != nil {
 				return nil, err
 			}
@@ -120,18 +601,19 @@ func (c *ConfigWriter) retrieveSortedRouteSlice() ([]*route.RouteConfiguration,
 		}
 	}
 	if len(routes) == 0 {
-		return nil, fmt.Errorf("no routes found")
+		return nil, errNoRoutes
 	}
-	sort.Slice(routes, func(i, j int) bool {
-		iName, err := strconv.Atoi(routes[i].Name)
-		if err != nil {
-			return false
-		}
-		jName, err := strconv.Atoi(routes[j].Name)
-		if err != nil {
-			return false
+	// Route configs named by port, like 8080, come first in numeric order, then the other ones by name
+	sort.SliceStable(routes, func(i, j int) bool {
+		iName, iErr := strconv.Atoi(routes[i].Name)
+		jName, jErr := strconv.Atoi(routes[j].Name)
+		switch {
+		case iErr == nil && jErr == nil:
+			return iName < jName
+		case iErr == nil || jErr == nil:
+			return iErr == nil
 		}
-		return iName < jName
+		return routes[i].Name < routes[j].Name
 	})
 	return routes, nil
 }
@@ -13,3 +13,632 @@
 // limitations under the License.
 
 package configdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func newRouteConfigWriter(t *testing.T, out io.Writer, routeConfigs ...*route.RouteConfiguration) *ConfigWriter {
+	t.Helper()
+	return newConfigDumpWriter(t, out, nil, nil, routeConfigs)
+}
+
+func TestRouteFilter_Verify(t *testing.T) {
+	tests := []struct {
+		desc    string
+		filter  RouteFilter
+		inRoute *route.RouteConfiguration
+		expect  bool
+	}{
+		{
+			desc:    "empty-filter",
+			inRoute: &route.RouteConfiguration{Name: "9080"},
+			expect:  true,
+		},
+		{
+			desc:    "name-exact",
+			filter:  RouteFilter{Name: "9080"},
+			inRoute: &route.RouteConfiguration{Name: "9080"},
+			expect:  true,
+		},
+		{
+			desc:    "name-is-not-a-substring",
+			filter:  RouteFilter{Name: "foo.bar.svc"},
+			inRoute: &route.RouteConfiguration{Name: "foo.bar.svc.cluster.local:8080"},
+			expect:  false,
+		},
+		{
+			desc:    "name-contains",
+			filter:  RouteFilter{NameContains: "foo.bar.svc"},
+			inRoute: &route.RouteConfiguration{Name: "foo.bar.svc.cluster.local:8080"},
+			expect:  true,
+		},
+		{
+			desc:    "name-contains-mismatch",
+			filter:  RouteFilter{NameContains: "foo.bar.svc"},
+			inRoute: &route.RouteConfiguration{Name: "9080"},
+			expect:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := tt.filter.Verify(tt.inRoute); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestMatchesVirtualHostDomain(t *testing.T) {
+	tests := []struct {
+		desc      string
+		domain    string
+		requested string
+		expect    bool
+	}{
+		{desc: "exact", domain: "reviews.default.svc.cluster.local", requested: "reviews.default.svc.cluster.local", expect: true},
+		{desc: "case-insensitive", domain: "Bookinfo.Example.com", requested: "bookinfo.example.com", expect: true},
+		{desc: "different-port", domain: "reviews:9080", requested: "reviews:8080", expect: false},
+		{desc: "catch-all", domain: "*", requested: "bookinfo.example.com", expect: true},
+		{desc: "suffix-wildcard", domain: "*.example.com", requested: "bookinfo.example.com", expect: true},
+		{desc: "suffix-wildcard-needs-a-character", domain: "*.example.com", requested: ".example.com", expect: false},
+		{desc: "suffix-wildcard-mismatch", domain: "*.example.com", requested: "example.com", expect: false},
+		{desc: "prefix-wildcard", domain: "reviews.*", requested: "reviews.default", expect: true},
+		{desc: "requested-wildcard", domain: "bookinfo.example.com", requested: "*.example.com", expect: true},
+		{desc: "requested-wildcard-mismatch", domain: "bookinfo.example.org", requested: "*.example.com", expect: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := matchesVirtualHostDomain(tt.domain, tt.requested); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintRouteVirtualHosts(t *testing.T) {
+	routeConfigs := []*route.RouteConfiguration{
+		{
+			Name: "9080",
+			VirtualHosts: []*route.VirtualHost{
+				{
+					Name:    "reviews.default.svc.cluster.local:9080",
+					Domains: []string{"reviews", "reviews.default.svc.cluster.local"},
+					Routes:  []*route.Route{{Name: "default"}},
+				},
+				{Name: "empty"},
+			},
+		},
+		{
+			Name: "80",
+			VirtualHosts: []*route.VirtualHost{
+				{
+					Name:    "bookinfo.example.com:80",
+					Domains: []string{"bookinfo.example.com", "bookinfo.example.com:80"},
+					Routes:  []*route.Route{{Name: "productpage"}, {Name: "static"}},
+				},
+				{Name: "wildcard", Domains: []string{"*.example.com"}, Routes: []*route.Route{{}}},
+				{Name: "allow_any", Domains: []string{"*"}, Routes: []*route.Route{{}}},
+			},
+		},
+	}
+	tests := []struct {
+		desc   string
+		filter RouteFilter
+		want   string
+	}{
+		{
+			desc: "all",
+			want: "NAME     VIRTUAL HOST                               DOMAINS                                          ROUTES\n" +
+				"80       bookinfo.example.com:80                    bookinfo.example.com,bookinfo.example.com:80     2\n" +
+				"80       wildcard                                   *.example.com                                    1\n" +
+				"80       allow_any                                  *                                                1\n" +
+				"9080     reviews.default.svc.cluster.local:9080     reviews,reviews.default.svc.cluster.local        1\n" +
+				"9080     empty                                      -                                                0\n",
+		},
+		{
+			desc:   "domain",
+			filter: RouteFilter{VirtualHostDomain: "bookinfo.example.com"},
+			want: "NAME     VIRTUAL HOST                DOMAINS                                          ROUTES\n" +
+				"80       bookinfo.example.com:80     bookinfo.example.com,bookinfo.example.com:80     2\n" +
+				"80       wildcard                    *.example.com                                    1\n" +
+				"80       allow_any                   *                                                1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newRouteConfigWriter(t, gotOut, routeConfigs...)
+			if err := cw.PrintRouteVirtualHosts(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func newWeightedClustersRoute(name string, clusters ...*route.WeightedCluster_ClusterWeight) *route.Route {
+	return &route.Route{
+		Name: name,
+		Action: &route.Route_Route{Route: &route.RouteAction{
+			ClusterSpecifier: &route.RouteAction_WeightedClusters{WeightedClusters: &route.WeightedCluster{Clusters: clusters}},
+		}},
+	}
+}
+
+func newClusterWeight(name string, weight uint32) *route.WeightedCluster_ClusterWeight {
+	return &route.WeightedCluster_ClusterWeight{Name: name, Weight: &wrappers.UInt32Value{Value: weight}}
+}
+
+func TestConfigWriter_PrintRouteWeightedClusters(t *testing.T) {
+	gotOut := &bytes.Buffer{}
+	cw := newRouteConfigWriter(t, gotOut, &route.RouteConfiguration{
+		Name: "80",
+		VirtualHosts: []*route.VirtualHost{{
+			Name: "reviews:80",
+			Routes: []*route.Route{
+				newWeightedClustersRoute("reviews",
+					newClusterWeight("outbound|80|v1|reviews.default.svc.cluster.local", 90),
+					newClusterWeight("outbound|80|v2|reviews.default.svc.cluster.local", 10)),
+				newWeightedClustersRoute("thirds", newClusterWeight("a", 1), newClusterWeight("b", 1), newClusterWeight("c", 1)),
+				{
+					Action: &route.Route_Route{Route: &route.RouteAction{
+						ClusterSpecifier: &route.RouteAction_Cluster{Cluster: "outbound|80||ratings.default.svc.cluster.local"},
+					}},
+				},
+				{
+					Name: "sharded",
+					Action: &route.Route_Route{Route: &route.RouteAction{
+						ClusterSpecifier: &route.RouteAction_ClusterHeader{ClusterHeader: "x-cluster"},
+					}},
+				},
+				{
+					Name:   "https-redirect",
+					Action: &route.Route_Redirect{Redirect: &route.RedirectAction{}},
+				},
+			},
+		}},
+	})
+	if err := cw.PrintRouteWeightedClusters(RouteFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME     VIRTUAL HOST     ROUTE       CLUSTER                                              WEIGHT     PERCENT\n" +
+		"80       reviews:80       reviews     outbound|80|v1|reviews.default.svc.cluster.local     90         90%\n" +
+		"80       reviews:80       reviews     outbound|80|v2|reviews.default.svc.cluster.local     10         10%\n" +
+		"80       reviews:80       thirds      a                                                    1          33.33%\n" +
+		"80       reviews:80       thirds      b                                                    1          33.33%\n" +
+		"80       reviews:80       thirds      c                                                    1          33.33%\n" +
+		"80       reviews:80       -           outbound|80||ratings.default.svc.cluster.local       -          100%\n" +
+		"80       reviews:80       sharded     header:x-cluster                                     -          -\n"
+	if gotOut.String() != want {
+		t.Errorf("expect %q got %q", want, gotOut.String())
+	}
+}
+
+func newTimeoutRoute(name string, timeout *time.Duration, retryPolicy *route.RetryPolicy) *route.Route {
+	action := &route.RouteAction{
+		ClusterSpecifier: &route.RouteAction_Cluster{Cluster: "outbound|9080||reviews.default.svc.cluster.local"},
+		RetryPolicy:      retryPolicy,
+	}
+	if timeout != nil {
+		action.Timeout = ptypes.DurationProto(*timeout)
+	}
+	return &route.Route{Name: name, Action: &route.Route_Route{Route: action}}
+}
+
+func TestConfigWriter_PrintRouteTimeouts(t *testing.T) {
+	disabled, slow := time.Duration(0), 30*time.Second
+	routeConfigs := []*route.RouteConfiguration{
+		{
+			Name: "9080",
+			VirtualHosts: []*route.VirtualHost{
+				{
+					Name: "reviews:9080",
+					RetryPolicy: &route.RetryPolicy{
+						RetryOn:    "connect-failure,refused-stream",
+						NumRetries: &wrappers.UInt32Value{Value: 2},
+					},
+					Routes: []*route.Route{
+						newTimeoutRoute("istio-default", &disabled, nil),
+						newTimeoutRoute("slow", &slow, &route.RetryPolicy{NumRetries: &wrappers.UInt32Value{Value: 0}}),
+						newTimeoutRoute("defaults", nil, &route.RetryPolicy{RetryOn: "5xx"}),
+						{Name: "https-redirect", Action: &route.Route_Redirect{Redirect: &route.RedirectAction{}}},
+					},
+				},
+				{
+					Name:   "allow_any",
+					Routes: []*route.Route{newTimeoutRoute("allow_any", &disabled, nil)},
+				},
+			},
+		},
+		{
+			Name: "80",
+			VirtualHosts: []*route.VirtualHost{{
+				Name:   "allow_any",
+				Routes: []*route.Route{newTimeoutRoute("allow_any", &disabled, nil)},
+			}},
+		},
+	}
+	tests := []struct {
+		desc   string
+		filter RouteFilter
+		want   string
+	}{
+		{
+			desc:   "all",
+			filter: RouteFilter{Name: "9080"},
+			want: "NAME     VIRTUAL HOST     ROUTE             TIMEOUT      RETRIES     RETRY ON                           PER TRY TIMEOUT\n" +
+				"9080     reviews:9080     istio-default     disabled     2           connect-failure,refused-stream     disabled\n" +
+				"9080     reviews:9080     slow              30s          0           -                                  30s\n" +
+				"9080     reviews:9080     defaults          15s          1           5xx                                15s\n" +
+				"9080     allow_any        allow_any         disabled     0           -                                  -\n",
+		},
+		{
+			desc:   "retries",
+			filter: RouteFilter{Retries: true},
+			want: "NAME     VIRTUAL HOST     ROUTE             TIMEOUT      RETRIES     RETRY ON                           PER TRY TIMEOUT\n" +
+				"9080     reviews:9080     istio-default     disabled     2           connect-failure,refused-stream     disabled\n" +
+				"9080     reviews:9080     defaults          15s          1           5xx                                15s\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newRouteConfigWriter(t, gotOut, routeConfigs...)
+			if err := cw.PrintRouteTimeouts(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func newMethodMatcher(method string, invert bool) *route.HeaderMatcher {
+	return &route.HeaderMatcher{
+		Name:                 ":method",
+		HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: method},
+		InvertMatch:          invert,
+	}
+}
+
+func newRegexMatcher(regex string) *matcher.RegexMatcher {
+	return &matcher.RegexMatcher{
+		EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}},
+		Regex:      regex,
+	}
+}
+
+func TestMatchesRoutePath(t *testing.T) {
+	tests := []struct {
+		desc       string
+		match      *route.RouteMatch
+		path       string
+		expect     bool
+		expectKind string
+	}{
+		{
+			desc:       "prefix",
+			match:      &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/api"}},
+			path:       "/api/v1",
+			expect:     true,
+			expectKind: routeMatchPrefix,
+		},
+		{
+			desc: "prefix-case-insensitive",
+			match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/API"},
+				CaseSensitive: &wrappers.BoolValue{Value: false},
+			},
+			path:       "/api/v1",
+			expect:     true,
+			expectKind: routeMatchPrefix,
+		},
+		{
+			desc:       "prefix-case-sensitive",
+			match:      &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/API"}},
+			path:       "/api/v1",
+			expect:     false,
+			expectKind: routeMatchPrefix,
+		},
+		{
+			desc:       "exact-path-is-not-a-prefix",
+			match:      &route.RouteMatch{PathSpecifier: &route.RouteMatch_Path{Path: "/api"}},
+			path:       "/api/v1",
+			expect:     false,
+			expectKind: routeMatchPath,
+		},
+		{
+			desc:       "regex-matches-the-whole-path",
+			match:      &route.RouteMatch{PathSpecifier: &route.RouteMatch_SafeRegex{SafeRegex: newRegexMatcher("/api/v[0-9]+")}},
+			path:       "/api/v1/users",
+			expect:     false,
+			expectKind: routeMatchRegex,
+		},
+		{
+			desc:       "regex",
+			match:      &route.RouteMatch{PathSpecifier: &route.RouteMatch_SafeRegex{SafeRegex: newRegexMatcher("/api/v[0-9]+/.*")}},
+			path:       "/api/v1/users",
+			expect:     true,
+			expectKind: routeMatchRegex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, gotKind := matchesRoutePath(tt.match, tt.path)
+			if got != tt.expect || gotKind != tt.expectKind {
+				t.Errorf("%s: expect %v %q got %v %q", tt.desc, tt.expect, tt.expectKind, got, gotKind)
+			}
+		})
+	}
+}
+
+func TestMatchesRouteMethod(t *testing.T) {
+	tests := []struct {
+		desc   string
+		match  *route.RouteMatch
+		method string
+		expect bool
+	}{
+		{
+			desc:   "any-method",
+			match:  &route.RouteMatch{},
+			method: "POST",
+			expect: true,
+		},
+		{
+			desc:   "exact",
+			match:  &route.RouteMatch{Headers: []*route.HeaderMatcher{newMethodMatcher("POST", false)}},
+			method: "POST",
+			expect: true,
+		},
+		{
+			desc:   "exact-mismatch",
+			match:  &route.RouteMatch{Headers: []*route.HeaderMatcher{newMethodMatcher("GET", false)}},
+			method: "POST",
+			expect: false,
+		},
+		{
+			desc:   "inverted",
+			match:  &route.RouteMatch{Headers: []*route.HeaderMatcher{newMethodMatcher("GET", true)}},
+			method: "POST",
+			expect: true,
+		},
+		{
+			desc: "regex",
+			match: &route.RouteMatch{Headers: []*route.HeaderMatcher{{
+				Name:                 ":method",
+				HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: newRegexMatcher("GET|HEAD")},
+			}}},
+			method: "HEAD",
+			expect: true,
+		},
+		{
+			desc: "other-headers-are-ignored",
+			match: &route.RouteMatch{Headers: []*route.HeaderMatcher{{
+				Name:                 "end-user",
+				HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"},
+			}}},
+			method: "POST",
+			expect: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := matchesRouteMethod(tt.match, tt.method); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintRouteMatches(t *testing.T) {
+	routeConfig := &route.RouteConfiguration{
+		Name: "80",
+		VirtualHosts: []*route.VirtualHost{{
+			Name: "api.example.com:80",
+			Routes: []*route.Route{
+				{
+					Name: "create-user",
+					Match: &route.RouteMatch{
+						PathSpecifier: &route.RouteMatch_Path{Path: "/api/v1/users"},
+						Headers:       []*route.HeaderMatcher{newMethodMatcher("POST", false)},
+					},
+				},
+				{
+					Name: "users-regex",
+					Match: &route.RouteMatch{
+						PathSpecifier: &route.RouteMatch_SafeRegex{SafeRegex: newRegexMatcher("/api/v[0-9]+/users/.*")},
+						Headers: []*route.HeaderMatcher{{
+							Name:                 ":method",
+							HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: newRegexMatcher("GET|HEAD")},
+						}},
+					},
+				},
+				{Name: "api", Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/api/v1"}}},
+				{
+					Name: "not-get",
+					Match: &route.RouteMatch{
+						PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/api"},
+						Headers:       []*route.HeaderMatcher{newMethodMatcher("GET", true)},
+					},
+				},
+				{Name: "default", Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}}},
+			},
+		}},
+	}
+	tests := []struct {
+		desc   string
+		filter RouteFilter
+		want   string
+	}{
+		{
+			desc: "all",
+			want: "NAME     VIRTUAL HOST           ROUTE           MATCH                           METHOD\n" +
+				"80       api.example.com:80     create-user     path /api/v1/users              POST\n" +
+				"80       api.example.com:80     users-regex     regex /api/v[0-9]+/users/.*     ~GET|HEAD\n" +
+				"80       api.example.com:80     api             prefix /api/v1                  *\n" +
+				"80       api.example.com:80     not-get         prefix /api                     !GET\n" +
+				"80       api.example.com:80     default         prefix /                        *\n",
+		},
+		{
+			desc:   "method-and-path",
+			filter: RouteFilter{Method: "POST", Path: "/api/v1/users"},
+			want: "NAME     VIRTUAL HOST           ROUTE           MATCH                  METHOD\n" +
+				"80       api.example.com:80     create-user     path /api/v1/users     POST\n" +
+				"80       api.example.com:80     api             prefix /api/v1         *\n" +
+				"80       api.example.com:80     not-get         prefix /api            !GET\n",
+		},
+		{
+			desc:   "include-catch-all",
+			filter: RouteFilter{Method: "POST", Path: "/api/v1/users", IncludeCatchAll: true},
+			want: "NAME     VIRTUAL HOST           ROUTE           MATCH                  METHOD\n" +
+				"80       api.example.com:80     create-user     path /api/v1/users     POST\n" +
+				"80       api.example.com:80     api             prefix /api/v1         *\n" +
+				"80       api.example.com:80     not-get         prefix /api            !GET\n" +
+				"80       api.example.com:80     default         prefix /               *\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newRouteConfigWriter(t, gotOut, routeConfig)
+			if err := cw.PrintRouteMatches(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+
+	cw := newRouteConfigWriter(t, &bytes.Buffer{}, routeConfig)
+	if routes, err := cw.GetRoutes(RouteFilter{Method: "PUT", Path: "/static"}); err != nil || len(routes) != 0 {
+		t.Errorf("expected no route config to serve PUT /static, got %v %v", routes, err)
+	}
+}
+
+func TestConfigWriter_GetRoutesSorted(t *testing.T) {
+	routeConfigs := make([]*route.RouteConfiguration, 0)
+	for _, name := range []string{"inbound|9080||", "8080", "InboundPassthroughClusterIpv4", "15010", "80", "istio-autogenerated-k8s-ingress"} {
+		routeConfigs = append(routeConfigs, &route.RouteConfiguration{Name: name})
+	}
+	routes, err := newRouteConfigWriter(t, &bytes.Buffer{}, routeConfigs...).GetRoutes(RouteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(routes))
+	for _, r := range routes {
+		got = append(got, r.Name)
+	}
+	want := []string{"80", "8080", "15010", "InboundPassthroughClusterIpv4", "inbound|9080||", "istio-autogenerated-k8s-ingress"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expect %v got %v", want, got)
+	}
+}
+
+func TestConfigWriter_PrintRouteHeaders(t *testing.T) {
+	routeConfigs := []*route.RouteConfiguration{
+		{
+			Name: "9080",
+			VirtualHosts: []*route.VirtualHost{
+				{
+					Name: "reviews:9080",
+					Routes: []*route.Route{{
+						Name: "default",
+						RequestHeadersToAdd: []*core.HeaderValueOption{
+							{Header: &core.HeaderValue{Key: "x-team", Value: "payments"}},
+							{Header: &core.HeaderValue{Key: "x-env", Value: "prod"}, Append: &wrappers.BoolValue{Value: false}},
+						},
+						RequestHeadersToRemove:  []string{"x-debug"},
+						ResponseHeadersToRemove: []string{"server"},
+					}},
+				},
+				{
+					Name:   "allow_any",
+					Routes: []*route.Route{{Name: "allow_any"}},
+				},
+			},
+		},
+		{
+			Name:         "80",
+			VirtualHosts: []*route.VirtualHost{{Name: "allow_any", Routes: []*route.Route{{Name: "allow_any"}}}},
+		},
+	}
+	tests := []struct {
+		desc   string
+		filter RouteFilter
+		want   string
+	}{
+		{
+			desc:   "all",
+			filter: RouteFilter{Name: "9080"},
+			want: "NAME     VIRTUAL HOST     ROUTE         HEADERS      ACTION     HEADER      VALUE\n" +
+				"9080     reviews:9080     default       request      append     x-team      payments\n" +
+				"9080     reviews:9080     default       request      set        x-env       prod\n" +
+				"9080     reviews:9080     default       request      remove     x-debug     -\n" +
+				"9080     reviews:9080     default       response     remove     server      -\n" +
+				"9080     allow_any        allow_any     -            -          -           -\n",
+		},
+		{
+			desc:   "manipulates-headers",
+			filter: RouteFilter{ManipulatesHeaders: true},
+			want: "NAME     VIRTUAL HOST     ROUTE       HEADERS      ACTION     HEADER      VALUE\n" +
+				"9080     reviews:9080     default     request      append     x-team      payments\n" +
+				"9080     reviews:9080     default     request      set        x-env       prod\n" +
+				"9080     reviews:9080     default     request      remove     x-debug     -\n" +
+				"9080     reviews:9080     default     response     remove     server      -\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw := newRouteConfigWriter(t, gotOut, routeConfigs...)
+			if err := cw.PrintRouteHeaders(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintRouteSummaryJSON(t *testing.T) {
+	gotOut := &bytes.Buffer{}
+	cw := newRouteConfigWriter(t, gotOut,
+		&route.RouteConfiguration{
+			Name: "9080",
+			VirtualHosts: []*route.VirtualHost{
+				{Name: "reviews.default.svc.cluster.local:9080"},
+				{Name: "allow_any"},
+			},
+		},
+		&route.RouteConfiguration{Name: "inbound|9080|http|reviews.default.svc.cluster.local"})
+	cw.OutputFormat = JSON
+	if err := cw.PrintRouteSummary(RouteFilter{Name: "9080"}); err != nil {
+		t.Fatal(err)
+	}
+	var summaries []RouteSummary
+	if err := json.Unmarshal(gotOut.Bytes(), &summaries); err != nil {
+		t.Fatalf("summary is not a JSON array: %v\n%s", err, gotOut.String())
+	}
+	want := []RouteSummary{{Name: "9080", VirtualHosts: 2}}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("expect %+v got %+v", want, summaries)
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+
+	sdscompare "istio.io/istio/istioctl/pkg/writer/compare/sds"
+)
+
+// redactedSecret replaces the key material of the secrets in the secret dump, like Envoy redacts sensitive fields
+const redactedSecret = "[redacted]"
+
+// SecretFilter is used to pass filter information into secret based config writer print functions
+type SecretFilter struct {
+	// Name selects secrets by resource name, such as default or ROOTCA
+	Name string
+	// ExpiringWithin selects the secrets of the summary whose certificate expires within the duration or has
+	// already expired. Zero selects all secrets.
+	ExpiringWithin time.Duration
+	// VerifyChain adds whether the certificate chain of each secret verifies against the root certificates of
+	// the config dump to the summary
+	VerifyChain bool
+}
+
+// Results of verifying the certificate chain of a secret
+const (
+	secretChainValid   = "VALID"
+	secretChainInvalid = "INVALID"
+)
+
+// Verify returns true if the passed secret name matches the filter fields
+func (s *SecretFilter) Verify(name string) bool {
+	return s.Name == "" || s.Name == name
+}
+
+// verifyExpiry returns true if the certificate of the secret expires within the ExpiringWithin duration of now.
+// Secrets without a certificate Envoy can read never match.
+func (s *SecretFilter) verifyExpiry(secret sdscompare.SecretItem, now time.Time) bool {
+	if s.ExpiringWithin == 0 {
+		return true
+	}
+	notAfter, err := time.Parse(time.RFC3339, secret.NotAfter)
+	if err != nil || !secret.Valid {
+		return false
+	}
+	return notAfter.Before(now.Add(s.ExpiringWithin))
+}
+
+// isExpiredSecret returns true if the certificate of the secret expired before now
+func isExpiredSecret(secret sdscompare.SecretItem, now time.Time) bool {
+	notAfter, err := time.Parse(time.RFC3339, secret.NotAfter)
+	return err == nil && secret.Valid && notAfter.Before(now)
+}
+
+// PrintSecretDump prints the relevant secrets of the secret config dump to the ConfigWriter stdout. Private keys,
+// their passwords and session ticket keys are redacted, so the dump can be shared. A secret which cannot be read
+// fails the whole dump rather than being printed unredacted.
+func (c *ConfigWriter) PrintSecretDump(filter SecretFilter) error {
+	if c.configDump == nil {
+		return fmt.Errorf("config writer has not been primed")
+	}
+	secretDump, err := c.configDump.GetSecretConfigDump()
+	if err != nil {
+		return fmt.Errorf("sidecar doesn't support secrets: %v", err)
+	}
+	filtered := &adminapi.SecretsConfigDump{}
+	for _, s := range secretDump.StaticSecrets {
+		if !filter.Verify(s.Name) {
+			continue
+		}
+		secret, err := redactSecret(s.GetSecret())
+		if err != nil {
+			return fmt.Errorf("unable to redact secret %s: %v", s.Name, err)
+		}
+		redacted := proto.Clone(s).(*adminapi.SecretsConfigDump_StaticSecret)
+		redacted.Secret = secret
+		filtered.StaticSecrets = append(filtered.StaticSecrets, redacted)
+	}
+	if filtered.DynamicActiveSecrets, err = filterDynamicSecrets(secretDump.DynamicActiveSecrets, filter); err != nil {
+		return err
+	}
+	if filtered.DynamicWarmingSecrets, err = filterDynamicSecrets(secretDump.DynamicWarmingSecrets, filter); err != nil {
+		return err
+	}
+	if err := c.printMessage(filtered); err != nil {
+		return fmt.Errorf("unable to marshal secrets in Envoy config dump")
+	}
+	return nil
+}
+
+func filterDynamicSecrets(secrets []*adminapi.SecretsConfigDump_DynamicSecret,
+	filter SecretFilter) ([]*adminapi.SecretsConfigDump_DynamicSecret, error) {
+	filtered := make([]*adminapi.SecretsConfigDump_DynamicSecret, 0, len(secrets))
+	for _, s := range secrets {
+		if !filter.Verify(s.Name) {
+			continue
+		}
+		secret, err := redactSecret(s.GetSecret())
+		if err != nil {
+			return nil, fmt.Errorf("unable to redact secret %s: %v", s.Name, err)
+		}
+		redacted := proto.Clone(s).(*adminapi.SecretsConfigDump_DynamicSecret)
+		redacted.Secret = secret
+		filtered = append(filtered, redacted)
+	}
+	return filtered, nil
+}
+
+// redactSecret replaces the private key and password of a TLS certificate secret and the keys of a session ticket
+// keys secret with [redacted], returning an error for a secret which cannot be read.
+func redactSecret(secretAny *any.Any) (*any.Any, error) {
+	if secretAny == nil {
+		return nil, nil
+	}
+	secret := &tls.Secret{}
+	if err := unmarshalAnyAs(secretTypeURL, secretAny, secret); err != nil {
+		return nil, err
+	}
+	if tlsCertificate := secret.GetTlsCertificate(); tlsCertificate != nil {
+		if tlsCertificate.PrivateKey != nil {
+			tlsCertificate.PrivateKey = redactedDataSource()
+		}
+		if tlsCertificate.Password != nil {
+			tlsCertificate.Password = redactedDataSource()
+		}
+	}
+	keys := secret.GetSessionTicketKeys().GetKeys()
+	for i := range keys {
+		keys[i] = redactedDataSource()
+	}
+	return ptypes.MarshalAny(secret)
+}
+
+func redactedDataSource() *core.DataSource {
+	return &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: redactedSecret}}
+}
+
+// PrintSecretSummary prints a summary of the relevant dynamic active and warming secrets from the config dump,
+// with the serial number, validity, subject and SANs of the leaf certificate of each secret. Expired certificates
+// are marked as such, and highlighted in red when the ConfigWriter prints in color.
+func (c *ConfigWriter) PrintSecretSummary(filter SecretFilter) error {
+	if c.configDump == nil {
+		return fmt.Errorf("config writer has not been primed")
+	}
+	secretItems, err := sdscompare.GetEnvoySecrets(c.configDump)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	filtered := make([]sdscompare.SecretItem, 0, len(secretItems))
+	for _, s := range secretItems {
+		if filter.Verify(s.Name) && filter.verifyExpiry(s, now) {
+			filtered = append(filtered, s)
+		}
+	}
+	if len(filtered) == 0 {
+		fmt.Fprintln(c.Stdout, "No active or warming secrets found.")
+		return nil
+	}
+	// The table is colored once it is aligned, since the tabwriter counts color codes in the width of cells
+	out := &bytes.Buffer{}
+	w := new(tabwriter.Writer).Init(out, 0, 8, 5, ' ', 0)
+	var roots *x509.CertPool
+	if filter.VerifyChain {
+		roots = c.retrieveRootCertificates()
+		fmt.Fprintln(w, "RESOURCE NAME\tTYPE\tSTATUS\tVALID CERT\tSERIAL NUMBER\tNOT AFTER\tNOT BEFORE\tSUBJECT\tSANS\tCHAIN")
+	} else {
+		fmt.Fprintln(w, "RESOURCE NAME\tTYPE\tSTATUS\tVALID CERT\tSERIAL NUMBER\tNOT AFTER\tNOT BEFORE\tSUBJECT\tSANS")
+	}
+	for _, s := range filtered {
+		notAfter := s.NotAfter
+		if isExpiredSecret(s, now) {
+			notAfter += " (expired)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s", s.Name, s.Type, s.State, s.Valid, s.SerialNumber,
+			notAfter, s.NotBefore, orDash(s.Subject), orDash(strings.Join(s.SANs, ",")))
+		if filter.VerifyChain {
+			fmt.Fprintf(w, "\t%s", verifySecretChain(s, roots, now))
+		}
+		fmt.Fprintln(w)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(out.String(), "\n")
+	fmt.Fprint(c.Stdout, lines[0])
+	for i, s := range filtered {
+		if isExpiredSecret(s, now) {
+			fmt.Fprint(c.Stdout, c.colorize(colorRed, lines[i+1]))
+		} else {
+			fmt.Fprint(c.Stdout, lines[i+1])
+		}
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// retrieveRootCertificates returns the root certificates of the config dump, those of the validation context
+// secrets Envoy fetched with SDS, like ROOTCA, and those configured inline in the TLS contexts of clusters
+func (c *ConfigWriter) retrieveRootCertificates() *x509.CertPool {
+	roots := x509.NewCertPool()
+	if secretDump, err := c.configDump.GetSecretConfigDump(); err == nil {
+		secrets := make([]*any.Any, 0)
+		for _, s := range secretDump.StaticSecrets {
+			secrets = append(secrets, s.GetSecret())
+		}
+		for _, s := range append(secretDump.DynamicActiveSecrets, secretDump.DynamicWarmingSecrets...) {
+			secrets = append(secrets, s.GetSecret())
+		}
+		for _, secretAny := range secrets {
+			secret := &tls.Secret{}
+			if err := unmarshalAnyAs(secretTypeURL, secretAny, secret); err == nil {
+				appendDataSourceCerts(roots, secret.GetValidationContext().GetTrustedCa())
+			}
+		}
+	}
+	// Clusters are optional, the secret view works on config dumps with just secrets
+	clusters, _ := c.retrieveSortedClusterSlice()
+	for _, cl := range clusters {
+		transportSockets := []*core.TransportSocket{cl.GetTransportSocket()}
+		for _, match := range cl.GetTransportSocketMatches() {
+			transportSockets = append(transportSockets, match.GetTransportSocket())
+		}
+		for _, transportSocket := range transportSockets {
+			if !isUpstreamTLSTransportSocket(transportSocket) {
+				continue
+			}
+			tlsContext, err := retrieveUpstreamTLSContext(transportSocket)
+			if err != nil {
+				continue
+			}
+			commonTLSContext := tlsContext.GetCommonTlsContext()
+			appendDataSourceCerts(roots, commonTLSContext.GetValidationContext().GetTrustedCa())
+			appendDataSourceCerts(roots, commonTLSContext.GetCombinedValidationContext().GetDefaultValidationContext().GetTrustedCa())
+		}
+	}
+	return roots
+}
+
+// appendDataSourceCerts adds the PEM certificates inline in the data source to the pool. Certificates in files
+// are on the disk of the proxy, so they cannot be read from the config dump.
+func appendDataSourceCerts(pool *x509.CertPool, dataSource *core.DataSource) {
+	if inline := dataSource.GetInlineBytes(); len(inline) > 0 {
+		pool.AppendCertsFromPEM(inline)
+	} else if inline := dataSource.GetInlineString(); inline != "" {
+		pool.AppendCertsFromPEM([]byte(inline))
+	}
+}
+
+// verifySecretChain verifies the leaf certificate of a secret, with the intermediate certificates following it,
+// against the root certificates, returning VALID or INVALID with the reason. Root certificate secrets are not
+// verified and shown as -.
+func verifySecretChain(secret sdscompare.SecretItem, roots *x509.CertPool, now time.Time) string {
+	if secret.Type == "CA" {
+		return "-"
+	}
+	certs := make([]*x509.Certificate, 0)
+	for rest := []byte(secret.Data); ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Sprintf("%s: %v", secretChainInvalid, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return fmt.Sprintf("%s: no certificate", secretChainInvalid)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Sprintf("%s: %v", secretChainInvalid, err)
+	}
+	return secretChainValid
+}
@@ -0,0 +1,325 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"istio.io/istio/pilot/test/util"
+)
+
+// newSecretCertificate returns the PEM encoded self-signed certificate and private key of the template
+func newSecretCertificate(t *testing.T, template *x509.Certificate) (cert, key []byte) {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = signSecretCertificate(t, template, template, privateKey, privateKey)
+	keyDer, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// signSecretCertificate returns the PEM encoded and parsed certificate of the template for the key, signed by the issuer
+func signSecretCertificate(t *testing.T, template, issuer *x509.Certificate, key, issuerKey *ecdsa.PrivateKey) ([]byte, *x509.Certificate) {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert
+}
+
+// newCertificateChain returns a root certificate and the chain of a workload certificate issued by an intermediate
+// certificate the root issued, all valid now
+func newCertificateChain(t *testing.T) (root, chain []byte) {
+	t.Helper()
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	newCATemplate := func(serial int64, organization string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{Organization: []string{organization}},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+	}
+	root, rootCert := signSecretCertificate(t, newCATemplate(1, "root"), newCATemplate(1, "root"), keys[0], keys[0])
+	intermediate, intermediateCert := signSecretCertificate(t, newCATemplate(2, "intermediate"), rootCert, keys[1], keys[0])
+	workload, _ := signSecretCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, intermediateCert, keys[2], keys[1])
+	return root, append(workload, intermediate...)
+}
+
+func inlineBytes(b []byte) *core.DataSource {
+	return &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: b}}
+}
+
+// newSecretConfigWriter returns a ConfigWriter of the default workload certificate and the ROOTCA secrets,
+// along with the private key of the workload certificate
+func newSecretConfigWriter(t *testing.T, out *bytes.Buffer) (*ConfigWriter, []byte) {
+	t.Helper()
+	notBefore := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	spiffeID, err := url.Parse("spiffe://cluster.local/ns/default/sa/reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workloadCert, workloadKey := newSecretCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1001),
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(24 * time.Hour),
+		URIs:         []*url.URL{spiffeID},
+	})
+	rootCert, _ := newSecretCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"cluster.local"}},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(100, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	})
+	secrets := []*tls.Secret{
+		{
+			Name: "default",
+			Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{
+				CertificateChain: inlineBytes(workloadCert),
+				PrivateKey:       inlineBytes(workloadKey),
+			}},
+		},
+		{
+			Name: "ROOTCA",
+			Type: &tls.Secret_ValidationContext{ValidationContext: &tls.CertificateValidationContext{
+				TrustedCa: inlineBytes(rootCert),
+			}},
+		},
+	}
+	secretDump := &adminapi.SecretsConfigDump{}
+	for _, s := range secrets {
+		secretDump.DynamicActiveSecrets = append(secretDump.DynamicActiveSecrets, &adminapi.SecretsConfigDump_DynamicSecret{
+			Name:   s.Name,
+			Secret: mustMarshalAny(t, s),
+		})
+	}
+	return newConfigWriter(t, out, secretDump), workloadKey
+}
+
+func TestConfigWriter_PrintSecretSummary(t *testing.T) {
+	header := "RESOURCE NAME     TYPE           STATUS     VALID CERT     SERIAL NUMBER     NOT AFTER                          " +
+		"NOT BEFORE               SUBJECT     SANS\n"
+	expired := "default           Cert Chain     ACTIVE     true           1001              2020-06-02T00:00:00Z (expired)     " +
+		"2020-06-01T00:00:00Z     -           spiffe://cluster.local/ns/default/sa/reviews\n"
+	tests := []struct {
+		desc     string
+		filter   SecretFilter
+		color    bool
+		wantFile string
+		want     string
+	}{
+		{
+			desc:     "all",
+			wantFile: "testdata/secretsummary.txt",
+		},
+		{
+			desc:   "unknown-name",
+			filter: SecretFilter{Name: "file-cert:/etc/certs/cert-chain.pem"},
+			want:   "No active or warming secrets found.\n",
+		},
+		{
+			desc:   "expiring-within",
+			filter: SecretFilter{ExpiringWithin: 24 * time.Hour},
+			want:   header + expired,
+		},
+		{
+			desc:   "expired-in-color",
+			filter: SecretFilter{Name: "default"},
+			color:  true,
+			want:   header + colorRed + strings.TrimSuffix(expired, "\n") + colorReset + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotOut := &bytes.Buffer{}
+			cw, _ := newSecretConfigWriter(t, gotOut)
+			cw.Color = tt.color
+			if err := cw.PrintSecretSummary(tt.filter); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFile != "" {
+				util.CompareContent(gotOut.Bytes(), tt.wantFile, t)
+			} else if gotOut.String() != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintSecretDump(t *testing.T) {
+	gotOut := &bytes.Buffer{}
+	cw, workloadKey := newSecretConfigWriter(t, gotOut)
+	if err := cw.PrintSecretDump(SecretFilter{Name: "default"}); err != nil {
+		t.Fatal(err)
+	}
+	got := gotOut.String()
+	if strings.Contains(got, base64.StdEncoding.EncodeToString(workloadKey)) {
+		t.Errorf("expected the private key to be redacted, got:\n%s", got)
+	}
+	if !strings.Contains(got, redactedSecret) {
+		t.Errorf("expected the private key to be replaced with %s, got:\n%s", redactedSecret, got)
+	}
+	if strings.Contains(got, "ROOTCA") {
+		t.Errorf("expected only the default secret, got:\n%s", got)
+	}
+}
+
+func TestRedactSecret_V2(t *testing.T) {
+	secretAny := mustMarshalAny(t, &tls.Secret{
+		Name: "default",
+		Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{PrivateKey: inlineBytes([]byte("key"))}},
+	})
+	secretAny.TypeUrl = "type.googleapis.com/envoy.api.v2.auth.Secret"
+	redacted, err := redactSecret(secretAny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &tls.Secret{}
+	if err := ptypes.UnmarshalAny(redacted, secret); err != nil {
+		t.Fatal(err)
+	}
+	if got := secret.GetTlsCertificate().GetPrivateKey().GetInlineString(); got != redactedSecret {
+		t.Errorf("expected the private key to be replaced with %s, got %q", redactedSecret, got)
+	}
+}
+
+func TestConfigWriter_PrintSecretSummaryVerifyChain(t *testing.T) {
+	root, chain := newCertificateChain(t)
+	otherRoot, _ := newCertificateChain(t)
+	workloadSecret := &tls.Secret{
+		Name: "default",
+		Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{CertificateChain: inlineBytes(chain)}},
+	}
+	newRootSecret := func(root []byte) *tls.Secret {
+		return &tls.Secret{
+			Name: "ROOTCA",
+			Type: &tls.Secret_ValidationContext{ValidationContext: &tls.CertificateValidationContext{TrustedCa: inlineBytes(root)}},
+		}
+	}
+	newInlineRootCluster := func(root []byte) *cluster.Cluster {
+		return &cluster.Cluster{
+			Name: "outbound|443||api.example.com",
+			TransportSocket: newUpstreamTLSTransportSocket(t, &tls.CommonTlsContext{
+				ValidationContextType: &tls.CommonTlsContext_ValidationContext{
+					ValidationContext: &tls.CertificateValidationContext{TrustedCa: inlineBytes(root)},
+				},
+			}),
+		}
+	}
+	tests := []struct {
+		desc     string
+		secrets  []*tls.Secret
+		clusters []*cluster.Cluster
+		want     string
+	}{
+		{
+			desc:    "sds-root",
+			secrets: []*tls.Secret{workloadSecret, newRootSecret(root)},
+			want:    secretChainValid,
+		},
+		{
+			desc:     "inline-root",
+			secrets:  []*tls.Secret{workloadSecret},
+			clusters: []*cluster.Cluster{newInlineRootCluster(root)},
+			want:     secretChainValid,
+		},
+		{
+			desc:     "other-root",
+			secrets:  []*tls.Secret{workloadSecret, newRootSecret(otherRoot)},
+			clusters: []*cluster.Cluster{newInlineRootCluster(otherRoot)},
+			want:     secretChainInvalid + ": x509: certificate signed by unknown authority",
+		},
+		{
+			desc:    "no-root",
+			secrets: []*tls.Secret{workloadSecret},
+			want:    secretChainInvalid + ": x509: certificate signed by unknown authority",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			secretDump := &adminapi.SecretsConfigDump{}
+			for _, s := range tt.secrets {
+				secretDump.DynamicActiveSecrets = append(secretDump.DynamicActiveSecrets, &adminapi.SecretsConfigDump_DynamicSecret{
+					Name:   s.Name,
+					Secret: mustMarshalAny(t, s),
+				})
+			}
+			sections := []proto.Message{secretDump}
+			if len(tt.clusters) > 0 {
+				clusterDump := &adminapi.ClustersConfigDump{}
+				for _, c := range tt.clusters {
+					clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+						Cluster: mustMarshalAny(t, c),
+					})
+				}
+				sections = append(sections, clusterDump)
+			}
+			gotOut := &bytes.Buffer{}
+			cw := newConfigWriter(t, gotOut, sections...)
+			if err := cw.PrintSecretSummary(SecretFilter{Name: "default", VerifyChain: true}); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(gotOut.String()), "\n")
+			if len(lines) != 2 || !strings.Contains(lines[1], "     "+tt.want) {
+				t.Errorf("%s: expect the chain to be %q, got:\n%s", tt.desc, tt.want, gotOut.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+// Reasons a typed config is reported by the strict listener dump
+const (
+	typedConfigUnknown    = "unknown type"
+	typedConfigDeprecated = "deprecated type"
+)
+
+// deprecatedTypeVersion matches the package versions of the Envoy v2 API, which Envoy no longer accepts
+var deprecatedTypeVersion = regexp.MustCompile(`^v2(alpha\d*)?$`)
+
+// typedConfigWarning is a typed config of a listener whose type is not registered or is deprecated
+type typedConfigWarning struct {
+	listener string
+	// chain is the index of the filter chain, or - for listener filters
+	chain string
+	// filter is the path of names of the filters enclosing the typed config, such as an HTTP filter of
+	// an HTTP connection manager
+	filter  string
+	typeURL string
+	reason  string
+}
+
+func (w typedConfigWarning) String() string {
+	return fmt.Sprintf("listener %s, chain %s, filter %s: %s %s", w.listener, w.chain, w.filter, w.reason, w.typeURL)
+}
+
+// printTypedConfigWarnings prints the typed config warnings of the listeners to the stderr of the ConfigWriter,
+// returning an error if there are any
+func (c *ConfigWriter) printTypedConfigWarnings(listeners []*listenerWithState) error {
+	stderr := c.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	count := 0
+	seen := map[string]bool{}
+	for _, l := range listeners {
+		// A listener is checked once, in its first state
+		if seen[l.Name] {
+			continue
+		}
+		seen[l.Name] = true
+		for _, warning := range retrieveTypedConfigWarnings(l.Listener) {
+			fmt.Fprintf(stderr, "Warning: %v\n", warning)
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("found %d unknown or deprecated typed configs in listeners", count)
+	}
+	return nil
+}
+
+// retrieveTypedConfigWarnings walks the typed configs of the listener filters and of the filters and transport
+// sockets of the filter chains of a listener, including the typed configs nested in them, such as the HTTP filters
+// of an HTTP connection manager
+func retrieveTypedConfigWarnings(l *listener.Listener) []typedConfigWarning {
+	warnings := make([]typedConfigWarning, 0)
+	check := func(chain string) func(*any.Any, []string) {
+		return func(typedConfig *any.Any, names []string) {
+			if reason := checkTypedConfig(typedConfig); reason != "" {
+				warnings = append(warnings, typedConfigWarning{
+					listener: l.Name,
+					chain:    chain,
+					filter:   strings.Join(names, " > "),
+					typeURL:  typedConfig.GetTypeUrl(),
+					reason:   reason,
+				})
+			}
+		}
+	}
+	for _, listenerFilter := range l.GetListenerFilters() {
+		walkTypedConfigs(reflect.ValueOf(listenerFilter), nil, check("-"))
+	}
+	for i, filterChain := range l.GetFilterChains() {
+		for _, filter := range filterChain.GetFilters() {
+			walkTypedConfigs(reflect.ValueOf(filter), nil, check(strconv.Itoa(i)))
+		}
+		walkTypedConfigs(reflect.ValueOf(filterChain.GetTransportSocket()), nil, check(strconv.Itoa(i)))
+	}
+	return warnings
+}
+
+// checkTypedConfig returns why a typed config would be rejected, or "" when its type is current and registered
+func checkTypedConfig(typedConfig *any.Any) string {
+	name, err := ptypes.AnyMessageName(typedConfig)
+	if err != nil {
+		return typedConfigUnknown
+	}
+	for _, part := range strings.Split(name, ".") {
+		if deprecatedTypeVersion.MatchString(part) {
+			return typedConfigDeprecated
+		}
+	}
+	if proto.MessageType(name) == nil {
+		return typedConfigUnknown
+	}
+	return ""
+}
+
+// walkTypedConfigs calls visit with every Any nested in the value, along with the names of the messages enclosing
+// it, such as filters. The Anys of registered types are unpacked to walk the Anys nested in them.
+func walkTypedConfigs(v reflect.Value, names []string, visit func(*any.Any, []string)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if typedConfig, ok := v.Interface().(*any.Any); ok {
+			visit(typedConfig, names)
+			if name, err := ptypes.AnyMessageName(typedConfig); err == nil && proto.MessageType(name) != nil {
+				var unpacked ptypes.DynamicAny
+				if err := ptypes.UnmarshalAny(typedConfig, &unpacked); err == nil {
+					walkTypedConfigs(reflect.ValueOf(unpacked.Message), names, visit)
+				}
+			}
+			return
+		}
+		walkTypedConfigs(v.Elem(), names, visit)
+	case reflect.Struct:
+		if name := v.FieldByName("Name"); name.IsValid() && name.Kind() == reflect.String && name.String() != "" {
+			names = append(names[:len(names):len(names)], name.String())
+		}
+		for i := 0; i < v.NumField(); i++ {
+			// Skip the unexported fields of generated messages
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			walkTypedConfigs(v.Field(i), names, visit)
+		}
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Struct:
+			for i := 0; i < v.Len(); i++ {
+				walkTypedConfigs(v.Index(i), names, visit)
+			}
+		}
+	case reflect.Map:
+		// Walk maps like the typed per filter configs of virtual hosts in key order, so warnings are stable
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		for _, key := range keys {
+			walkTypedConfigs(v.MapIndex(key), names, visit)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+const (
+	unknownTypeURL    = "type.googleapis.com/example.filters.http.Unknown"
+	deprecatedTypeURL = "type.googleapis.com/envoy.config.filter.http.fault.v2.HTTPFault"
+)
+
+func newTypedConfigListener(t *testing.T, httpFilterTypeURLs ...string) *listener.Listener {
+	t.Helper()
+	httpConnectionManager := &hcm.HttpConnectionManager{}
+	for _, typeURL := range httpFilterTypeURLs {
+		httpConnectionManager.HttpFilters = append(httpConnectionManager.HttpFilters, &hcm.HttpFilter{
+			Name:       typeURL[strings.LastIndex(typeURL, ".")+1:],
+			ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: &any.Any{TypeUrl: typeURL}},
+		})
+	}
+	httpConnectionManager.HttpFilters = append(httpConnectionManager.HttpFilters, &hcm.HttpFilter{
+		Name:       "envoy.filters.http.rbac",
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: mustMarshalAny(t, &rbachttp.RBAC{})},
+	})
+	l := newSocketListener("0.0.0.0", 8080)
+	l.Name = "0.0.0.0_8080"
+	l.FilterChains = []*listener.FilterChain{{
+		Filters: []*listener.Filter{newTypedFilter(t, "envoy.filters.network.http_connection_manager", httpConnectionManager)},
+	}}
+	return l
+}
+
+func TestCheckTypedConfig(t *testing.T) {
+	tests := []struct {
+		desc    string
+		typeURL string
+		want    string
+	}{
+		{
+			desc:    "registered",
+			typeURL: "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC",
+		},
+		{
+			desc:    "unknown",
+			typeURL: unknownTypeURL,
+			want:    typedConfigUnknown,
+		},
+		{
+			desc:    "deprecated",
+			typeURL: deprecatedTypeURL,
+			want:    typedConfigDeprecated,
+		},
+		{
+			desc:    "deprecated-alpha",
+			typeURL: "type.googleapis.com/envoy.config.filter.http.jwt_authn.v2alpha.JwtAuthentication",
+			want:    typedConfigDeprecated,
+		},
+		{
+			desc: "missing-type-url",
+			want: typedConfigUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := checkTypedConfig(&any.Any{TypeUrl: tt.typeURL}); got != tt.want {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigWriter_PrintListenerDumpStrict(t *testing.T) {
+	tests := []struct {
+		desc       string
+		listener   *listener.Listener
+		filter     ListenerFilter
+		wantStderr string
+		wantErr    bool
+	}{
+		{
+			desc:     "nested-http-filters",
+			listener: newTypedConfigListener(t, unknownTypeURL, deprecatedTypeURL),
+			filter:   ListenerFilter{Strict: true},
+			wantStderr: "Warning: listener 0.0.0.0_8080, chain 0, filter envoy.filters.network.http_connection_manager > Unknown: " +
+				"unknown type " + unknownTypeURL + "\n" +
+				"Warning: listener 0.0.0.0_8080, chain 0, filter envoy.filters.network.http_connection_manager > HTTPFault: " +
+				"deprecated type " + deprecatedTypeURL + "\n",
+			wantErr: true,
+		},
+		{
+			desc:     "registered-types",
+			listener: newTypedConfigListener(t),
+			filter:   ListenerFilter{Strict: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			dump := &adminapi.ListenersConfigDump{
+				DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+					Name:        tt.listener.Name,
+					ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustMarshalAny(t, tt.listener)},
+				}},
+			}
+			gotOut, gotErr := &bytes.Buffer{}, &bytes.Buffer{}
+			cw := newConfigWriter(t, gotOut, dump)
+			cw.Stderr = gotErr
+			err := cw.PrintListenerDump(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: expect error %v got %v", tt.desc, tt.wantErr, err)
+			}
+			if gotErr.String() != tt.wantStderr {
+				t.Errorf("%s: expect %q got %q", tt.desc, tt.wantStderr, gotErr.String())
+			}
+			if !tt.wantErr && !strings.Contains(gotOut.String(), tt.listener.Name) {
+				t.Errorf("%s: expected the dump of listener %s, got:\n%s", tt.desc, tt.listener.Name, gotOut.String())
+			}
+		})
+	}
+}